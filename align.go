@@ -0,0 +1,108 @@
+package console
+
+import (
+	"bytes"
+	"sync"
+	"unicode/utf8"
+)
+
+// alignDecay is the number of consecutive narrower lines after which a
+// columnTracker forgets its widest observed width and shrinks back to the
+// width of the current line.
+const alignDecay = 50
+
+// columnTracker remembers the widest column offset seen recently.  It is
+// shared between a Handler and all the handlers derived from it, so records
+// logged through different child loggers line up with each other.
+type columnTracker struct {
+	mu    sync.Mutex
+	width int
+	age   int
+}
+
+// fit records a line whose column starts at offset w, and returns the
+// offset the column should be padded to.
+func (c *columnTracker) fit(w int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case w >= c.width:
+		c.width = w
+		c.age = 0
+	case c.age >= alignDecay:
+		c.width = w
+		c.age = 0
+	default:
+		c.age++
+	}
+	return c.width
+}
+
+// The header columns aligned by HandlerOptions.AutoAlign.
+const (
+	columnLevel = iota
+	columnName
+	columnSource
+	numColumns
+)
+
+// headerColumns tracks the widths of the header columns aligned by
+// HandlerOptions.AutoAlign.  Like the attrs column, it's shared between a
+// Handler and all the handlers derived from it.
+type headerColumns [numColumns]columnTracker
+
+// alignColumn pads the column written to buf at offset to the width of the
+// column, if the handler aligns columns.  Empty columns aren't padded, so they
+// are still elided.
+func (e *encoder) alignColumn(offset, column int) {
+	if e.h.columns == nil || len(e.buf) == offset {
+		return
+	}
+	w := visibleWidth(e.buf[offset:])
+	e.buf.Pad(e.h.columns[column].fit(w)-w, ' ')
+}
+
+// visibleWidth returns the number of characters which will be visible on the
+// last line of b when printed to a terminal, ignoring ANSI escape sequences.
+func visibleWidth(b []byte) int {
+	if i := bytes.LastIndexByte(b, '\n'); i >= 0 {
+		b = b[i+1:]
+	}
+	w := 0
+	for i := 0; i < len(b); {
+		if n := csiLen(b[i:]); n != 0 {
+			if n < 0 {
+				break
+			}
+			i += n
+			continue
+		}
+		_, size := utf8.DecodeRune(b[i:])
+		i += size
+		w++
+	}
+	return w
+}
+
+// visibleIndex returns the offset in b after its first n visible characters, and
+// the escape sequences between them, like visibleWidth counts them, or len(b) if
+// b has no more than n visible characters.
+func visibleIndex(b []byte, n int) int {
+	w := 0
+	for i := 0; i < len(b); {
+		if m := csiLen(b[i:]); m != 0 {
+			if m < 0 {
+				return len(b)
+			}
+			i += m
+			continue
+		}
+		if w == n {
+			return i
+		}
+		_, size := utf8.DecodeRune(b[i:])
+		i += size
+		w++
+	}
+	return len(b)
+}
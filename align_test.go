@@ -0,0 +1,112 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_AlignAttrs(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, AlignAttrs: true})
+
+	log := func(h slog.Handler, msg string, attrs ...slog.Attr) {
+		rec := slog.NewRecord(time.Time{}, slog.LevelInfo, msg, 0)
+		rec.AddAttrs(attrs...)
+		AssertNoError(t, h.Handle(context.Background(), rec))
+	}
+
+	log(h, "a longer message", slog.String("foo", "bar"))
+	log(h, "short", slog.String("foo", "bar"))
+	// derived handlers share the column
+	log(h.WithGroup("g"), "tiny", slog.String("foo", "bar"))
+	// lines without attrs are not padded
+	log(h, "none")
+
+	want := strings.Join([]string{
+		"INF a longer message foo=bar",
+		"INF short            foo=bar",
+		"INF tiny             g.foo=bar",
+		"INF none",
+		"",
+	}, "\n")
+	AssertEqual(t, want, buf.String())
+}
+
+func TestHandler_AlignAttrs_Colors(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{AlignAttrs: true, HeaderFormat: "%l %m %a"})
+	theme := NewDefaultTheme()
+
+	for _, msg := range []string{"longer", "s"} {
+		rec := slog.NewRecord(time.Time{}, slog.LevelInfo, msg, 0)
+		rec.AddAttrs(slog.Int("n", 1))
+		AssertNoError(t, h.Handle(context.Background(), rec))
+	}
+
+	attr := styled("n=", theme.AttrKey) + styled("1", theme.AttrValue)
+	want := styled("INF", theme.LevelInfo) + " " + styled("longer", theme.Message) + " " + attr + "\n" +
+		styled("INF", theme.LevelInfo) + " " + styled("s", theme.Message) + "      " + attr + "\n"
+	AssertEqual(t, want, buf.String())
+}
+
+func TestHandler_AutoAlign(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, AutoAlign: true, HeaderFormat: "%L %{[%N]%} %m"})
+
+	log := func(h slog.Handler, lvl slog.Level, msg string) {
+		AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, lvl, msg, 0)))
+	}
+
+	log(h, slog.LevelInfo, "a")
+	log(h, slog.LevelError, "b")
+	log(h, slog.LevelWarn, "c")
+	// derived handlers share the widths
+	log(h.WithName("db"), slog.LevelInfo, "d")
+	log(h.WithName("http.server"), slog.LevelInfo, "e")
+	log(h.WithName("db").WithGroup("g"), slog.LevelDebug, "f")
+	// empty columns are still elided
+	log(h, slog.LevelInfo, "g")
+
+	want := strings.Join([]string{
+		"INFO a",
+		"ERROR b",
+		"WARN  c",
+		"INFO  [db] d",
+		"INFO  [http.server] e",
+		"DEBUG [db         ] f",
+		"INFO  g",
+		"",
+	}, "\n")
+	AssertEqual(t, want, buf.String())
+}
+
+func TestColumnTracker_Decay(t *testing.T) {
+	var c columnTracker
+	AssertEqual(t, 10, c.fit(10))
+	for i := 0; i < alignDecay; i++ {
+		AssertEqual(t, 10, c.fit(4))
+	}
+	AssertEqual(t, 4, c.fit(4))
+	AssertEqual(t, 6, c.fit(6))
+}
+
+func TestVisibleWidth(t *testing.T) {
+	AssertEqual(t, 0, visibleWidth(nil))
+	AssertEqual(t, 3, visibleWidth([]byte("abc")))
+	AssertEqual(t, 3, visibleWidth([]byte(styled("abc", ToANSICode(Bold, Red)))))
+	AssertEqual(t, 2, visibleWidth([]byte("first line\nµs")))
+}
+
+func TestVisibleIndex(t *testing.T) {
+	AssertEqual(t, 0, visibleIndex(nil, 2))
+	AssertEqual(t, 2, visibleIndex([]byte("abc"), 2))
+	AssertEqual(t, 3, visibleIndex([]byte("abc"), 5))
+	AssertEqual(t, 3, visibleIndex([]byte("µsx"), 2))
+	colored := styled("abc", ToANSICode(Red))
+	AssertEqual(t, len(ToANSICode(Red))+2, visibleIndex([]byte(colored), 2))
+	AssertEqual(t, len(colored), visibleIndex([]byte(colored), 3))
+}
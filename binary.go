@@ -0,0 +1,59 @@
+package console
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+)
+
+// BytesFormat selects how []byte attribute values are printed.  See
+// HandlerOptions.BytesFormat.
+type BytesFormat int
+
+const (
+	// BytesDefault prints []byte values like fmt does, e.g. "[104 105]".
+	BytesDefault BytesFormat = iota
+	// BytesHexDump prints []byte values as a hex dump, with the bytes in hex and
+	// ASCII, like hexdump -C, which is printed below the record like other
+	// multiline values.
+	BytesHexDump
+	// BytesBase64 prints []byte values base64 encoded, followed by their length,
+	// like "aGVsbG8= (5 bytes)".
+	BytesBase64
+)
+
+// defaultMaxBytes is the default of HandlerOptions.MaxBytes.
+const defaultMaxBytes = 256
+
+// writeBytes writes b in the handler's BytesFormat, truncated to MaxBytes.
+func (e *encoder) writeBytes(buf *buffer, b []byte) {
+	n := len(b)
+	if n > e.h.opts.MaxBytes {
+		b = b[:e.h.opts.MaxBytes]
+	}
+
+	switch e.h.opts.BytesFormat {
+	case BytesHexDump:
+		d := hex.Dumper(buf)
+		_, _ = d.Write(b)
+		_ = d.Close()
+		if l := len(*buf); l > 0 && (*buf)[l-1] == '\n' {
+			*buf = (*buf)[:l-1]
+		}
+		if n > len(b) {
+			buf.AppendString("\n…(+")
+			buf.AppendInt(int64(n - len(b)))
+			buf.AppendString(" bytes)")
+		}
+	case BytesBase64:
+		l := len(*buf)
+		*buf = append(*buf, make([]byte, base64.StdEncoding.EncodedLen(len(b)))...)
+		base64.StdEncoding.Encode((*buf)[l:], b)
+		if n > len(b) {
+			buf.AppendString("…")
+		}
+		buf.AppendString(" (")
+		*buf = strconv.AppendInt(*buf, int64(n), 10)
+		buf.AppendString(" bytes)")
+	}
+}
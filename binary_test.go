@@ -0,0 +1,55 @@
+package console
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestHandler_BytesFormat(t *testing.T) {
+	data := []byte("hello, world!\x00\x01\xff and more")
+	tests := []handlerTest{
+		{
+			name:  "default",
+			attrs: []slog.Attr{slog.Any("b", []byte("hi"))},
+			want:  "msg b=[104 105]\n",
+		},
+		{
+			name:  "hex dump",
+			opts:  HandlerOptions{BytesFormat: BytesHexDump},
+			attrs: []slog.Attr{slog.Any("b", data), slog.Int("n", 1)},
+			want: "msg n=1\n=== b ===\n" +
+				"00000000  68 65 6c 6c 6f 2c 20 77  6f 72 6c 64 21 00 01 ff  |hello, world!...|\n" +
+				"00000010  20 61 6e 64 20 6d 6f 72  65                       | and more|\n",
+		},
+		{
+			name:  "hex dump truncated",
+			opts:  HandlerOptions{BytesFormat: BytesHexDump, MaxBytes: 4},
+			attrs: []slog.Attr{slog.Any("b", data)},
+			want:  "msg\n=== b ===\n00000000  68 65 6c 6c                                       |hell|\n…(+21 bytes)\n",
+		},
+		{
+			name:  "base64",
+			opts:  HandlerOptions{BytesFormat: BytesBase64},
+			attrs: []slog.Attr{slog.Any("b", []byte("hello"))},
+			want:  "msg b=aGVsbG8= (5 bytes)\n",
+		},
+		{
+			name:  "base64 truncated",
+			opts:  HandlerOptions{BytesFormat: BytesBase64, MaxBytes: 3},
+			attrs: []slog.Attr{slog.Any("b", []byte("hello"))},
+			want:  "msg b=aGVs… (5 bytes)\n",
+		},
+		{
+			name:  "empty",
+			opts:  HandlerOptions{BytesFormat: BytesBase64},
+			attrs: []slog.Attr{slog.Any("b", []byte{})},
+			want:  "msg b= (0 bytes)\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = true
+		test.opts.HeaderFormat = "%m %a"
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+}
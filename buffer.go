@@ -2,6 +2,7 @@ package console
 
 import (
 	"io"
+	"net"
 	"strconv"
 	"time"
 )
@@ -18,6 +19,8 @@ func (b *buffer) Pad(n int, c byte) {
 	}
 }
 
+// WriteTo writes the buffer to dst with a single Write.  Records are encoded as bytes,
+// so writing them with io.StringWriter would only add a conversion.
 func (b *buffer) WriteTo(dst io.Writer) (int64, error) {
 	l := len(*b)
 	if l == 0 {
@@ -34,18 +37,46 @@ func (b *buffer) WriteTo(dst io.Writer) (int64, error) {
 	return int64(n), nil
 }
 
+// writeWithTrailer writes b followed by trailer to dst, with a single write.  If dst
+// supports vectored writes, like a net.Conn, the buffers are written together without
+// copying.  Otherwise, trailer is appended to b first.
+func (b *buffer) writeWithTrailer(dst io.Writer, trailer []byte) (int64, error) {
+	if len(trailer) == 0 {
+		return b.WriteTo(dst)
+	}
+	if _, ok := dst.(net.Conn); ok {
+		bufs := net.Buffers{*b, trailer}
+		n, err := bufs.WriteTo(dst)
+		if err == nil {
+			b.Reset()
+		}
+		return n, err
+	}
+	b.Append(trailer)
+	return b.WriteTo(dst)
+}
+
 func (b *buffer) Write(bt []byte) (int, error) {
 	*b = append(*b, bt...)
 	return len(bt), nil
 }
 
+// WriteString implements io.StringWriter, for Encoder.WriteString.
+func (b *buffer) WriteString(s string) (int, error) {
+	*b = append(*b, s...)
+	return len(s), nil
+}
+
+// WriteByte implements io.ByteWriter.
+func (b *buffer) WriteByte(c byte) error {
+	*b = append(*b, c)
+	return nil
+}
+
 func (b *buffer) Reset() {
-	// To reduce peak allocation, return only smaller buffers to the pool.
-	const maxBufferSize = 16 << 10
-	if cap(*b) > maxBufferSize {
-		*b = (*b)[:0:maxBufferSize]
-		return
-	}
+	// oversized buffers are not shrunk here.  Instead, encoders holding
+	// them are dropped instead of being returned to the pool, so the
+	// memory is actually released.  See encoder.free.
 	*b = (*b)[:0]
 }
 
@@ -2,8 +2,12 @@ package console
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
+	"log/slog"
+	"net"
+	"strings"
 	"testing"
 	"time"
 )
@@ -48,6 +52,41 @@ func TestBuffer_WriteTo(t *testing.T) {
 	AssertZero(t, len(b))
 }
 
+func TestBuffer_WriteString(t *testing.T) {
+	var b buffer
+	var _ io.StringWriter = &b
+	var _ io.ByteWriter = &b
+	n, err := io.WriteString(&b, "foo")
+	AssertNoError(t, err)
+	AssertEqual(t, 3, n)
+	AssertNoError(t, b.WriteByte('!'))
+	AssertEqual(t, "foo!", b.String())
+}
+
+func TestBuffer_writeWithTrailer(t *testing.T) {
+	dest := bytes.Buffer{}
+	var b buffer
+	b.AppendString("foo")
+	n, err := b.writeWithTrailer(&dest, []byte("bar"))
+	AssertNoError(t, err)
+	AssertEqual(t, 6, int(n))
+	AssertEqual(t, "foobar", dest.String())
+	AssertZero(t, len(b))
+
+	// net.Conns get a vectored write
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	go func() {
+		b.AppendString("foo")
+		_, _ = b.writeWithTrailer(c1, []byte("baz"))
+		c1.Close()
+	}()
+	got, err := io.ReadAll(c2)
+	AssertNoError(t, err)
+	AssertEqual(t, "foobaz", string(got))
+}
+
 func TestBuffer_Reset(t *testing.T) {
 	var b buffer
 	b.AppendString("foobar")
@@ -95,3 +134,97 @@ func BenchmarkBuffer(b *testing.B) {
 		}
 	})
 }
+
+func TestEncoderPool_MaxBufferSize(t *testing.T) {
+	h := NewHandler(io.Discard, &HandlerOptions{MaxBufferSize: 4096})
+	AssertEqual(t, defaultMaxBufferSize, NewHandler(io.Discard, nil).opts.MaxBufferSize)
+
+	log := func(h *Handler, val string) {
+		rec := slog.NewRecord(time.Now(), slog.LevelInfo, "msg", 0)
+		rec.AddAttrs(slog.String("body", val))
+		AssertNoError(t, h.Handle(context.Background(), rec))
+	}
+
+	before := EncoderPoolStats()
+	log(h, "small")
+	after := EncoderPoolStats()
+	AssertEqual(t, before.Gets+1, after.Gets)
+	AssertEqual(t, before.Puts+1, after.Puts)
+	AssertEqual(t, before.Drops, after.Drops)
+
+	huge := strings.Repeat("line\n", 2000)
+	log(h, huge)
+	after2 := EncoderPoolStats()
+	AssertEqual(t, after.Drops+1, after2.Drops)
+	AssertEqual(t, after.Puts, after2.Puts)
+
+	// negative sizes disable the limit
+	log(NewHandler(io.Discard, &HandlerOptions{MaxBufferSize: -1}), huge)
+	AssertEqual(t, after2.Drops, EncoderPoolStats().Drops)
+}
+
+func TestEncoderPool_PerMaxBufferSize(t *testing.T) {
+	def := NewHandler(io.Discard, nil)
+	unlimited := NewHandler(io.Discard, &HandlerOptions{MaxBufferSize: -1})
+
+	// only handlers with the default limit share the process-wide pool
+	AssertEqual(t, encoderPool, def.pool)
+	AssertEqual(t, encoderPool, NewHandler(io.Discard, &HandlerOptions{MaxBufferSize: defaultMaxBufferSize}).pool)
+	AssertNotEqual(t, encoderPool, unlimited.pool)
+	AssertNotEqual(t, unlimited.pool, NewHandler(io.Discard, &HandlerOptions{MaxBufferSize: -1}).pool)
+
+	// derived handlers share their parent's pool
+	AssertEqual(t, unlimited.pool, unlimited.WithAttrs([]slog.Attr{slog.Int("a", 1)}).(*Handler).pool)
+	AssertEqual(t, unlimited.pool, unlimited.WithGroup("g").(*Handler).pool)
+}
+
+func TestHandler_MaxMultilineBytes(t *testing.T) {
+	AssertEqual(t, defaultMaxMultilineBytes, NewHandler(io.Discard, nil).opts.MaxMultilineBytes)
+
+	tests := []handlerTest{
+		{
+			name:  "under the limit",
+			attrs: []slog.Attr{slog.String("body", "a\nb")},
+			want:  "msg\n=== body ===\na\nb\n",
+		},
+		{
+			name:  "truncated",
+			attrs: []slog.Attr{slog.String("body", "0123456789\n0123456789\n0123456789")},
+			// "\n=== body ===\n" is 14 bytes, so 16 bytes of the value fit
+			want: "msg\n=== body ===\n0123456789\n01234\n(truncated, 16 bytes omitted)\n",
+		},
+		{
+			name:  "later attrs omitted",
+			attrs: []slog.Attr{slog.String("body", "0123456789\n0123456789"), slog.String("more", "a\nb")},
+			want:  "msg\n=== body ===\n0123456789\n01234\n(truncated, 22 bytes omitted)\n",
+		},
+		{
+			name:  "with attrs",
+			attrs: []slog.Attr{slog.String("more", "a\nb")},
+			handlerFunc: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.String("body", "0123456789\n0123456789")})
+			},
+			want: "msg\n=== body ===\n0123456789\n01234\n(truncated, 22 bytes omitted)\n",
+		},
+		{
+			name:  "multibyte characters aren't split",
+			attrs: []slog.Attr{slog.String("body", "0123456789\n01234µ")},
+			want:  "msg\n=== body ===\n0123456789\n01234\n(truncated, 2 bytes omitted)\n",
+		},
+		{
+			name:  "unlimited",
+			opts:  HandlerOptions{MaxMultilineBytes: -1},
+			attrs: []slog.Attr{slog.String("body", "0123456789\n0123456789\n0123456789")},
+			want:  "msg\n=== body ===\n0123456789\n0123456789\n0123456789\n",
+		},
+	}
+	for _, test := range tests {
+		if test.opts.MaxMultilineBytes == 0 {
+			test.opts.MaxMultilineBytes = 30
+		}
+		test.opts.NoColor = true
+		test.opts.HeaderFormat = "%m %a"
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+}
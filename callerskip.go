@@ -0,0 +1,45 @@
+package console
+
+import "runtime"
+
+// maxCallerDepth is the maximum depth of the stack searched for the frame of
+// records when CallerSkip is set.
+const maxCallerDepth = 64
+
+// WithCallerSkip returns a new Handler which skips skip more stack frames than h
+// when reporting the source of records.  It's meant for loggers used by helper
+// functions:
+//
+//	func logFailure(logger *slog.Logger, err error) {
+//		logger.Error("failed", "err", err) // reports the caller of logFailure
+//	}
+//
+//	logFailure(slog.New(h.WithCallerSkip(1)), err)
+//
+// See HandlerOptions.CallerSkip.
+func (h *Handler) WithCallerSkip(skip int) *Handler {
+	if skip == 0 {
+		return h
+	}
+	h2 := *h
+	h2.callerSkip = max(h.callerSkip+skip, 0)
+	return &h2
+}
+
+// skipCallers returns the PC of the frame skip frames above the frame of pc, in
+// the current goroutine's stack.  If pc isn't found in the stack, e.g. because the
+// record was created by another goroutine, pc is returned unchanged.
+func skipCallers(pc uintptr, skip int) uintptr {
+	var pcs [maxCallerDepth]uintptr
+	// skip runtime.Callers and skipCallers
+	n := runtime.Callers(2, pcs[:])
+	for i, p := range pcs[:n] {
+		if p == pc {
+			if i+skip < n {
+				return pcs[i+skip]
+			}
+			break
+		}
+	}
+	return pc
+}
@@ -0,0 +1,36 @@
+package console
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+//go:noinline
+func logFromHelper(l *slog.Logger, msg string) {
+	l.Info(msg)
+}
+
+func TestHandler_CallerSkip(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, AddSource: true, TruncateSourcePath: 1, HeaderFormat: "%s %m"})
+
+	logFromHelper(slog.New(h), "helper")
+	logFromHelper(slog.New(h.WithCallerSkip(1)), "caller")
+	// the skip is inherited by derived handlers
+	logFromHelper(slog.New(h.WithCallerSkip(1).WithAttrs(nil).WithGroup("g")), "derived")
+	// skipping past the top of the stack leaves the source alone
+	logFromHelper(slog.New(h.WithCallerSkip(1000)), "too far")
+
+	AssertEqual(t, "callerskip_test.go:11 helper\n"+
+		"callerskip_test.go:19 caller\n"+
+		"callerskip_test.go:21 derived\n"+
+		"callerskip_test.go:11 too far\n",
+		buf.String())
+
+	buf.Reset()
+	h = NewHandler(&buf, &HandlerOptions{NoColor: true, AddSource: true, TruncateSourcePath: 1, HeaderFormat: "%s %m", CallerSkip: 1})
+	logFromHelper(slog.New(h), "option")
+	logFromHelper(slog.New(h.WithCallerSkip(-1)), "reset")
+	AssertEqual(t, "callerskip_test.go:33 option\ncallerskip_test.go:11 reset\n", buf.String())
+}
@@ -0,0 +1,188 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+)
+
+// defaultCaptureSize is the default number of records kept by a capture handler.
+const defaultCaptureSize = 100
+
+// CaptureOptions configure the handler returned by NewCaptureHandler.
+type CaptureOptions struct {
+	// Size is the number of records kept.  When it's full, the oldest record is
+	// discarded to make room for each new one.  If 0, 100 is used.
+	Size int
+
+	// Level is the minimum level of the records captured.  If nil,
+	// slog.LevelDebug is used.
+	Level slog.Leveler
+
+	// TriggerLevel is the minimum level of the records which dump the captured
+	// records.  If nil, slog.LevelError is used.
+	TriggerLevel slog.Leveler
+}
+
+type captureEntry struct {
+	h   slog.Handler
+	ctx context.Context
+	rec slog.Record
+}
+
+// captureRing holds the captured records.  It's shared by a capture handler and
+// all the handlers derived from it.
+type captureRing struct {
+	mu      sync.Mutex
+	entries []captureEntry
+	// next is the index of the next entry to write in entries, once it's full
+	next int
+}
+
+func (r *captureRing) add(e captureEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) < cap(r.entries) {
+		r.entries = append(r.entries, e)
+		return
+	}
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+}
+
+// take removes all the entries, and returns them, oldest first.
+func (r *captureRing) take() []captureEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) == 0 {
+		return nil
+	}
+	entries := make([]captureEntry, 0, len(r.entries))
+	entries = append(entries, r.entries[r.next:]...)
+	entries = append(entries, r.entries[:r.next]...)
+	clear(r.entries)
+	r.entries, r.next = r.entries[:0], 0
+	return entries
+}
+
+type captureHandler struct {
+	next         slog.Handler
+	level        slog.Leveler
+	triggerLevel slog.Leveler
+	ring         *captureRing
+}
+
+var _ slog.Handler = (*captureHandler)(nil)
+var _ io.Closer = (*captureHandler)(nil)
+
+// NewCaptureHandler returns a handler which forwards records to next, and keeps
+// the most recent records next isn't enabled for, like debug records, in a ring
+// buffer, instead of dropping them.  When a record at or above the TriggerLevel
+// arrives, like an error, the captured records are written to next first, so the
+// error comes with the context which led up to it, without always logging debug
+// records:
+//
+//	slog.New(console.NewCaptureHandler(console.NewHandler(os.Stderr, nil), nil))
+//
+// The captured records are written between two marker records, at the trigger
+// record's level, like:
+//
+//	── captured 2 records ──────────────────────────
+//	DBG connecting addr=db:5432
+//	DBG retrying attempt=2
+//	── end of captured records ─────────────────────
+//	ERR query failed
+//
+// The markers have the [Rule] attribute, so a *Handler prints them as rules.  The
+// buffer is shared by the handlers derived from the returned one.  The returned
+// handler also implements io.Closer, and has a Flush() error method, which are
+// forwarded to next.
+func NewCaptureHandler(next slog.Handler, opts *CaptureOptions) slog.Handler {
+	if opts == nil {
+		opts = &CaptureOptions{}
+	}
+	size := opts.Size
+	if size <= 0 {
+		size = defaultCaptureSize
+	}
+	h := &captureHandler{
+		next:         next,
+		level:        opts.Level,
+		triggerLevel: opts.TriggerLevel,
+		ring:         &captureRing{entries: make([]captureEntry, 0, size)},
+	}
+	if h.level == nil {
+		h.level = slog.LevelDebug
+	}
+	if h.triggerLevel == nil {
+		h.triggerLevel = slog.LevelError
+	}
+	return h
+}
+
+// Enabled implements slog.Handler.
+func (c *captureHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return l >= c.level.Level() || c.next.Enabled(ctx, l)
+}
+
+// Handle implements slog.Handler.
+func (c *captureHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if !c.next.Enabled(ctx, rec.Level) {
+		if rec.Level >= c.level.Level() {
+			c.ring.add(captureEntry{h: c.next, ctx: ctx, rec: rec.Clone()})
+		}
+		return nil
+	}
+	if rec.Level < c.triggerLevel.Level() {
+		return c.next.Handle(ctx, rec)
+	}
+
+	entries := c.ring.take()
+	if len(entries) == 0 {
+		return c.next.Handle(ctx, rec)
+	}
+	noun := " records"
+	if len(entries) == 1 {
+		noun = " record"
+	}
+	marker := slog.NewRecord(rec.Time, rec.Level, "captured "+strconv.Itoa(len(entries))+noun, 0)
+	marker.AddAttrs(Rule())
+	errs := []error{c.next.Handle(ctx, marker)}
+	for _, e := range entries {
+		errs = append(errs, e.h.Handle(e.ctx, e.rec))
+	}
+	marker = slog.NewRecord(rec.Time, rec.Level, "end of captured records", 0)
+	marker.AddAttrs(Rule())
+	errs = append(errs, c.next.Handle(ctx, marker), c.next.Handle(ctx, rec))
+	return errors.Join(errs...)
+}
+
+// WithAttrs implements slog.Handler.
+func (c *captureHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c2 := *c
+	c2.next = c.next.WithAttrs(attrs)
+	return &c2
+}
+
+// WithGroup implements slog.Handler.
+func (c *captureHandler) WithGroup(name string) slog.Handler {
+	c2 := *c
+	c2.next = c.next.WithGroup(name)
+	return &c2
+}
+
+// Flush flushes next, if it has a Flush() error method.
+func (c *captureHandler) Flush() error {
+	return flush(c.next)
+}
+
+// Close closes next, if it implements io.Closer.
+func (c *captureHandler) Close() error {
+	if cl, ok := c.next.(io.Closer); ok {
+		return cl.Close()
+	}
+	return nil
+}
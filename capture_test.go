@@ -0,0 +1,52 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestCaptureHandler(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewCaptureHandler(
+		NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %m %a", RuleWidth: 20}),
+		&CaptureOptions{Size: 2},
+	)
+	AssertEqual(t, true, h.Enabled(context.Background(), slog.LevelDebug))
+	AssertEqual(t, false, h.Enabled(context.Background(), slog.LevelDebug-1))
+
+	l := slog.New(h)
+	l.Debug("one")
+	l.With("a", 1).WithGroup("g").Debug("two", "b", 2)
+	l.Debug("three")
+	l.Info("info")
+	l.Error("failed")
+	// the buffer is emptied by the dump
+	l.Error("again")
+
+	want := strings.Join([]string{
+		"INF info",
+		"── captured 2 records ───",
+		"DBG two a=1 g.b=2",
+		"DBG three",
+		"── end of captured records ───",
+		"ERR failed",
+		"ERR again",
+		"",
+	}, "\n")
+	AssertEqual(t, want, buf.String())
+}
+
+func TestCaptureHandler_Levels(t *testing.T) {
+	buf := bytes.Buffer{}
+	l := slog.New(NewCaptureHandler(
+		NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %m", Level: slog.LevelWarn, RuleWidth: 30}),
+		&CaptureOptions{Level: slog.LevelInfo, TriggerLevel: slog.LevelWarn},
+	))
+	l.Debug("dropped")
+	l.Info("captured")
+	l.Warn("warn")
+	AssertEqual(t, "── captured 1 record ─────────\nINF captured\n── end of captured records ───\nWRN warn\n", buf.String())
+}
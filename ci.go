@@ -0,0 +1,30 @@
+package console
+
+import "os"
+
+// ciEnvVars are environment variables set by common CI systems.
+var ciEnvVars = []string{
+	"CI",
+	"GITHUB_ACTIONS",
+	"GITLAB_CI",
+	"BUILDKITE",
+	"CIRCLECI",
+	"JENKINS_URL",
+	"TEAMCITY_VERSION",
+	"TF_BUILD",
+}
+
+// ciTimeFormat is the default TimeFormat when running in CI.
+const ciTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// IsCI reports whether the process appears to be running in a CI system, like
+// GitHub Actions or GitLab CI, based on the environment variables they set.
+// A variable set to "false" or "0" is ignored.
+func IsCI() bool {
+	for _, k := range ciEnvVars {
+		if v := os.Getenv(k); v != "" && v != "false" && v != "0" {
+			return true
+		}
+	}
+	return false
+}
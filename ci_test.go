@@ -0,0 +1,63 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// the tests expect the output of a local terminal, even when they are run in CI
+	for _, k := range ciEnvVars {
+		_ = os.Unsetenv(k)
+	}
+	_ = os.Unsetenv("FORCE_COLOR")
+	os.Exit(m.Run())
+}
+
+func TestIsCI(t *testing.T) {
+	AssertEqual(t, false, IsCI())
+	t.Setenv("CI", "false")
+	AssertEqual(t, false, IsCI())
+	t.Setenv("GITLAB_CI", "true")
+	AssertEqual(t, true, IsCI())
+}
+
+func TestHandler_CI(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	tm := time.Date(2024, 01, 02, 15, 04, 05, 123456789, time.UTC)
+
+	log := func(opts *HandlerOptions) string {
+		buf := bytes.Buffer{}
+		opts.HeaderFormat = "%t %m"
+		h := NewHandler(&buf, opts)
+		AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(tm, slog.LevelInfo, "msg", 0)))
+		return buf.String()
+	}
+
+	theme := NewDefaultTheme()
+	AssertEqual(t, "2024-01-02T15:04:05.123Z msg\n", log(&HandlerOptions{}))
+	// explicit time format is kept
+	AssertEqual(t, "15:04:05 msg\n", log(&HandlerOptions{TimeFormat: time.TimeOnly}))
+	// detection can be disabled
+	AssertEqual(t, styled("2024-01-02 15:04:05", theme.Timestamp)+" "+styled("msg", theme.Message)+"\n", log(&HandlerOptions{IgnoreCI: true}))
+
+	// the CI preset isn't written into the options, so they can be reused
+	opts := &HandlerOptions{NoColor: true}
+	AssertEqual(t, "2024-01-02T15:04:05.123Z msg\n", log(opts))
+	AssertEqual(t, "", opts.TimeFormat)
+	opts.IgnoreCI = true
+	AssertEqual(t, "2024-01-02 15:04:05 msg\n", log(opts))
+}
+
+func TestHandler_Logfmt_ReusedOptions(t *testing.T) {
+	opts := &HandlerOptions{Logfmt: true, Pretty: true}
+	NewHandler(&bytes.Buffer{}, opts)
+	AssertEqual(t, "", opts.HeaderFormat)
+	AssertEqual(t, "", opts.TimeFormat)
+	AssertEqual(t, true, opts.Pretty)
+	AssertEqual(t, nil, opts.Level)
+}
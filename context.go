@@ -0,0 +1,87 @@
+package console
+
+import (
+	"context"
+	"log/slog"
+)
+
+type contextAttrsKey struct{}
+
+// ContextWithAttrs returns a copy of ctx carrying attrs, in addition to any attributes
+// already added to ctx.  Handlers with HandlerOptions.AddContextAttrs set add these
+// attributes to each record logged with the context, e.g. with slog.InfoContext.  This
+// lets per-request fields, like a request ID, flow through code which is passed a
+// context, but not a logger.
+func ContextWithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+	prev := AttrsFromContext(ctx)
+	merged := make([]slog.Attr, 0, len(prev)+len(attrs))
+	merged = append(append(merged, prev...), attrs...)
+	return context.WithValue(ctx, contextAttrsKey{}, merged)
+}
+
+// AttrsFromContext returns the attributes added to ctx with ContextWithAttrs.  The
+// returned slice must not be modified.
+func AttrsFromContext(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(contextAttrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+type minLevelKey struct{}
+
+// WithMinLevel returns a copy of ctx which overrides the minimum level of the records
+// Handlers log with the context, e.g. with slog.DebugContext.  This traces a single
+// request at debug verbosity in a service which otherwise logs at the info level:
+//
+//	ctx = console.WithMinLevel(ctx, slog.LevelDebug)
+//	logger.DebugContext(ctx, "request headers", "headers", r.Header)
+//
+// The override can also raise the level, to silence a noisy operation.
+func WithMinLevel(ctx context.Context, level slog.Leveler) context.Context {
+	return context.WithValue(ctx, minLevelKey{}, level)
+}
+
+// MinLevelFromContext returns the minimum level set on ctx with WithMinLevel, if any.
+func MinLevelFromContext(ctx context.Context) (slog.Level, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	l, ok := ctx.Value(minLevelKey{}).(slog.Leveler)
+	if !ok || l == nil {
+		return 0, false
+	}
+	return l.Level(), true
+}
+
+// WorkerKey is the key of the attribute holding the label set with WithWorkerLabel,
+// when the HeaderFormat has no %w verb.
+const WorkerKey = "worker"
+
+type workerLabelKey struct{}
+
+// WithWorkerLabel returns a copy of ctx labeled with the name of the worker using it,
+// like "worker-3".  Handlers print the label of the context of each record in the %w
+// verb of the HeaderFormat, or as an attribute with the key WorkerKey, so the lines
+// of long-running worker pools can be told apart by stable, human-chosen names,
+// rather than goroutine IDs:
+//
+//	for i := range workers {
+//		go work(console.WithWorkerLabel(ctx, "worker-"+strconv.Itoa(i)))
+//	}
+func WithWorkerLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, workerLabelKey{}, label)
+}
+
+// WorkerLabelFromContext returns the label set on ctx with WithWorkerLabel, if any.
+func WorkerLabelFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	label, ok := ctx.Value(workerLabelKey{}).(string)
+	return label, ok && label != ""
+}
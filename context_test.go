@@ -0,0 +1,79 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+)
+
+func TestContextWithAttrs(t *testing.T) {
+	ctx := context.Background()
+	AssertEqual(t, ctx, ContextWithAttrs(ctx))
+	AssertEqual(t, 0, len(AttrsFromContext(ctx)))
+
+	ctx1 := ContextWithAttrs(ctx, slog.String("a", "1"))
+	ctx2 := ContextWithAttrs(ctx1, slog.String("b", "2"))
+	AssertEqual(t, "[a=1]", fmt.Sprint(AttrsFromContext(ctx1)))
+	AssertEqual(t, "[a=1 b=2]", fmt.Sprint(AttrsFromContext(ctx2)))
+}
+
+func TestHandler_AddContextAttrs(t *testing.T) {
+	ctx := ContextWithAttrs(context.Background(), slog.String("req", "abc"))
+
+	buf := bytes.Buffer{}
+	l := slog.New(NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%[req]h %m %a", AddContextAttrs: true}))
+	l.With("foo", "bar").InfoContext(ctx, "msg", "size", 1)
+	l.InfoContext(context.Background(), "none")
+	l.WithGroup("g").InfoContext(ContextWithAttrs(ctx, slog.Int("n", 2)), "grouped")
+	AssertEqual(t, "abc msg foo=bar size=1\nnone\nabc grouped n=2\n", buf.String())
+
+	// disabled by default
+	buf.Reset()
+	l = slog.New(NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%m %a"}))
+	l.InfoContext(ctx, "msg")
+	AssertEqual(t, "msg\n", buf.String())
+}
+
+func TestWithMinLevel(t *testing.T) {
+	_, ok := MinLevelFromContext(context.Background())
+	AssertEqual(t, false, ok)
+
+	ctx := WithMinLevel(context.Background(), slog.LevelDebug)
+	l, ok := MinLevelFromContext(ctx)
+	AssertEqual(t, true, ok)
+	AssertEqual(t, slog.LevelDebug, l)
+
+	buf := bytes.Buffer{}
+	logger := slog.New(NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %m"}))
+	logger.Debug("hidden")
+	logger.DebugContext(ctx, "traced")
+	logger.With("a", 1).WithGroup("g").DebugContext(ctx, "derived")
+	// the override can raise the level too
+	logger.InfoContext(WithMinLevel(ctx, slog.LevelWarn), "quiet")
+	AssertEqual(t, "DBG traced\nDBG derived\n", buf.String())
+}
+
+func TestWithWorkerLabel(t *testing.T) {
+	_, ok := WorkerLabelFromContext(context.Background())
+	AssertEqual(t, false, ok)
+
+	ctx := WithWorkerLabel(context.Background(), "worker-3")
+	label, ok := WorkerLabelFromContext(ctx)
+	AssertEqual(t, true, ok)
+	AssertEqual(t, "worker-3", label)
+
+	buf := bytes.Buffer{}
+	logger := slog.New(NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %{[%-8w]%} %m %a"}))
+	logger.InfoContext(ctx, "working", "job", 1)
+	logger.With("worker", "attr").WithGroup("g").InfoContext(WithWorkerLabel(ctx, "w1"), "derived")
+	logger.Info("no label")
+	AssertEqual(t, "INF [worker-3] working job=1\nINF [      w1] derived worker=attr\nINF [        ] no label\n", buf.String())
+
+	// without %w, the label is an attribute
+	buf.Reset()
+	logger = slog.New(NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%m %a"}))
+	logger.WithGroup("g").InfoContext(ctx, "working", "job", 1)
+	AssertEqual(t, "working worker=worker-3 g.job=1\n", buf.String())
+}
@@ -0,0 +1,94 @@
+package console
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// clearLine moves the cursor to the start of the line, and clears the line.
+const clearLine = "\r\x1b[2K"
+
+// TerminalWriter coordinates log output with a status line, like a progress bar or
+// spinner, drawn on the same terminal.  Log lines written to the TerminalWriter clear
+// the status line, are printed in its place, and then the status line is drawn again
+// below them, so logs scroll up above the status line, rather than being mangled by
+// it:
+//
+//	tw := console.NewTerminalWriter(os.Stderr)
+//	logger := slog.New(console.NewHandler(tw, nil))
+//	bar := progressbar.NewOptions(100, progressbar.OptionSetWriter(tw.StatusWriter()))
+//
+// The status line is the text written to StatusWriter since the last '\r' or '\n'.
+// Libraries which draw multi-line displays, or move the cursor, should write to the
+// TerminalWriter's StatusWriter too; they will redraw their displays on their next
+// refresh.
+type TerminalWriter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	status []byte
+	buf    []byte
+}
+
+var _ io.Writer = (*TerminalWriter)(nil)
+
+// NewTerminalWriter returns a TerminalWriter writing to out.
+func NewTerminalWriter(out io.Writer) *TerminalWriter {
+	return &TerminalWriter{out: out}
+}
+
+// Write writes log output.  If a status line is displayed, it's cleared first,
+// and redrawn after p.  Write should be called with whole lines, which a Handler
+// always does.
+func (w *TerminalWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.status) == 0 {
+		return w.out.Write(p)
+	}
+	w.buf = append(w.buf[:0], clearLine...)
+	w.buf = append(w.buf, p...)
+	w.buf = append(w.buf, w.status...)
+	if _, err := w.out.Write(w.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// StatusWriter returns a writer for the status line.  Writes are passed through to
+// the underlying writer, and the last line written is remembered, so it can be
+// redrawn after log lines.
+func (w *TerminalWriter) StatusWriter() io.Writer {
+	return statusWriter{w}
+}
+
+// ClearStatus clears the status line from the terminal, e.g. when a progress bar
+// has finished.
+func (w *TerminalWriter) ClearStatus() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.status) == 0 {
+		return nil
+	}
+	w.status = w.status[:0]
+	_, err := io.WriteString(w.out, clearLine)
+	return err
+}
+
+type statusWriter struct {
+	w *TerminalWriter
+}
+
+func (s statusWriter) Write(p []byte) (int, error) {
+	w := s.w
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n, err := w.out.Write(p)
+	// the status is the part of the last line after the last carriage return
+	if i := bytes.LastIndexAny(p, "\r\n"); i >= 0 {
+		w.status = append(w.status[:0], p[i+1:]...)
+	} else {
+		w.status = append(w.status, p...)
+	}
+	return n, err
+}
@@ -0,0 +1,43 @@
+package console
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"testing"
+)
+
+func TestTerminalWriter(t *testing.T) {
+	buf := bytes.Buffer{}
+	tw := NewTerminalWriter(&buf)
+	l := slog.New(NewHandler(tw, &HandlerOptions{NoColor: true, HeaderFormat: "%m"}))
+	status := tw.StatusWriter()
+
+	// no status yet
+	l.Info("first")
+	AssertEqual(t, "first\n", buf.String())
+
+	buf.Reset()
+	_, _ = fmt.Fprint(status, "\r[=   ] 25%")
+	l.Info("second")
+	AssertEqual(t, "\r[=   ] 25%"+clearLine+"second\n[=   ] 25%", buf.String())
+
+	buf.Reset()
+	_, _ = fmt.Fprint(status, "\r[==  ]")
+	_, _ = fmt.Fprint(status, " 50%")
+	l.Info("third")
+	AssertEqual(t, "\r[==  ] 50%"+clearLine+"third\n[==  ] 50%", buf.String())
+
+	// a finished status line scrolls up like the logs
+	buf.Reset()
+	_, _ = fmt.Fprint(status, "\r[====] done\n")
+	l.Info("fourth")
+	AssertEqual(t, "\r[====] done\nfourth\n", buf.String())
+
+	buf.Reset()
+	_, _ = fmt.Fprint(status, "spinning")
+	AssertNoError(t, tw.ClearStatus())
+	AssertNoError(t, tw.ClearStatus())
+	l.Info("fifth")
+	AssertEqual(t, "spinning"+clearLine+"fifth\n", buf.String())
+}
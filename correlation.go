@@ -0,0 +1,90 @@
+package console
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand"
+	"net/http"
+	"sync"
+)
+
+type correlationIDKey struct{}
+
+// NewCorrelationID returns a new random correlation ID, 8 hex digits long.  It's
+// short, rather than unique, so it's easy to match by eye, or with grep, across
+// the records of concurrent requests.
+func NewCorrelationID() string {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], rand.Uint32())
+	return hex.EncodeToString(b[:])
+}
+
+// WithCorrelationID returns a copy of ctx with a new correlation ID, unless ctx
+// already has one.  See HandlerOptions.CorrelationIDKey.
+func WithCorrelationID(ctx context.Context) context.Context {
+	if _, ok := CorrelationIDFromContext(ctx); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, correlationIDKey{}, NewCorrelationID())
+}
+
+// CorrelationIDFromContext returns the correlation ID added to ctx with
+// WithCorrelationID, if any.
+func CorrelationIDFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok := ctx.Value(correlationIDKey{}).(string)
+	return id, ok
+}
+
+// CorrelationIDHandler returns an http.Handler which adds a correlation ID to the
+// context of each request with WithCorrelationID, unless it already has one, and
+// calls next, so all the records logged with the request's context, or contexts
+// derived from it, have the same ID.  See HandlerOptions.CorrelationIDKey.
+func CorrelationIDHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(WithCorrelationID(r.Context())))
+	})
+}
+
+// generatedIDs are the correlation IDs generated for contexts without one, keyed
+// by their Done channels, which are shared by all the contexts derived from them
+// with values, but not by those derived with their own cancellation.  The IDs are
+// removed when the contexts are done.
+var generatedIDs = struct {
+	sync.Mutex
+	ids map[<-chan struct{}]string
+}{ids: map[<-chan struct{}]string{}}
+
+// correlationID returns the correlation ID of ctx.  If ctx doesn't have one, but
+// can be canceled, an ID is generated, and returned for ctx, and the contexts
+// derived from it, until it's done.  Contexts which can't be canceled, like
+// context.Background(), don't get an ID.
+func correlationID(ctx context.Context) (string, bool) {
+	if id, ok := CorrelationIDFromContext(ctx); ok {
+		return id, true
+	}
+	if ctx == nil {
+		return "", false
+	}
+	done := ctx.Done()
+	if done == nil {
+		return "", false
+	}
+
+	generatedIDs.Lock()
+	defer generatedIDs.Unlock()
+	id, ok := generatedIDs.ids[done]
+	if !ok {
+		id = NewCorrelationID()
+		generatedIDs.ids[done] = id
+		context.AfterFunc(ctx, func() {
+			generatedIDs.Lock()
+			defer generatedIDs.Unlock()
+			delete(generatedIDs.ids, done)
+		})
+	}
+	return id, true
+}
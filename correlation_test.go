@@ -0,0 +1,113 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithCorrelationID(t *testing.T) {
+	_, ok := CorrelationIDFromContext(context.Background())
+	AssertEqual(t, false, ok)
+
+	ctx := WithCorrelationID(context.Background())
+	id, ok := CorrelationIDFromContext(ctx)
+	AssertEqual(t, true, ok)
+	AssertEqual(t, 8, len(id))
+	// an existing ID is kept
+	AssertEqual(t, ctx, WithCorrelationID(ctx))
+}
+
+func TestHandler_CorrelationIDKey(t *testing.T) {
+	buf := bytes.Buffer{}
+	l := slog.New(NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%[req]h %m %a", CorrelationIDKey: "req"}))
+
+	ctx := WithCorrelationID(context.Background())
+	id, _ := CorrelationIDFromContext(ctx)
+	l.WithGroup("g").InfoContext(ctx, "explicit", "a", 1)
+	AssertEqual(t, id+" explicit g.a=1\n", buf.String())
+
+	// contexts which can't be canceled don't get an ID
+	buf.Reset()
+	l.InfoContext(context.Background(), "none")
+	l.Info("none")
+	AssertEqual(t, "none\nnone\n", buf.String())
+
+	// cancelable contexts get an ID, shared with the contexts derived from them
+	buf.Reset()
+	reqCtx, cancel := context.WithCancel(context.Background())
+	l.InfoContext(reqCtx, "first")
+	l.InfoContext(ContextWithAttrs(reqCtx, slog.Int("x", 1)), "derived")
+	otherCtx, cancelOther := context.WithCancel(context.Background())
+	defer cancelOther()
+	l.InfoContext(otherCtx, "other")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	AssertEqual(t, 3, len(lines))
+	first, _, _ := strings.Cut(lines[0], " ")
+	derived, _, _ := strings.Cut(lines[1], " ")
+	other, _, _ := strings.Cut(lines[2], " ")
+	AssertEqual(t, 8, len(first))
+	AssertEqual(t, first, derived)
+	AssertNotEqual(t, first, other)
+
+	// contexts derived with their own cancellation get their own ID, unless the
+	// ID was added with WithCorrelationID
+	buf.Reset()
+	timeoutCtx, cancelTimeout := context.WithTimeout(reqCtx, time.Hour)
+	defer cancelTimeout()
+	l.InfoContext(timeoutCtx, "timeout")
+	withID := WithCorrelationID(reqCtx)
+	childCtx, cancelChild := context.WithCancel(withID)
+	defer cancelChild()
+	l.InfoContext(withID, "with id")
+	l.InfoContext(childCtx, "child")
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	AssertEqual(t, 3, len(lines))
+	timeout, _, _ := strings.Cut(lines[0], " ")
+	parent, _, _ := strings.Cut(lines[1], " ")
+	child, _, _ := strings.Cut(lines[2], " ")
+	AssertNotEqual(t, first, timeout)
+	AssertEqual(t, parent, child)
+
+	cancel()
+	// the ID is forgotten once the context is done
+	for {
+		generatedIDs.Lock()
+		_, ok := generatedIDs.ids[reqCtx.Done()]
+		generatedIDs.Unlock()
+		if !ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCorrelationIDHandler(t *testing.T) {
+	buf := bytes.Buffer{}
+	l := slog.New(NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%[req]h %m", CorrelationIDKey: "req"}))
+
+	var id string
+	h := CorrelationIDHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ = CorrelationIDFromContext(r.Context())
+		ctx, cancel := context.WithTimeout(r.Context(), time.Hour)
+		defer cancel()
+		l.InfoContext(r.Context(), "request")
+		l.InfoContext(ctx, "timeout")
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	AssertEqual(t, 8, len(id))
+	AssertEqual(t, id+" request\n"+id+" timeout\n", buf.String())
+
+	// an existing ID is kept
+	ctx := WithCorrelationID(context.Background())
+	want, _ := CorrelationIDFromContext(ctx)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	AssertEqual(t, want, id)
+}
@@ -0,0 +1,235 @@
+package console
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines printed around the changed lines
+// of a Diff, like "diff -u".
+const diffContext = 3
+
+// maxDiffCells caps the size of the table used to find the longest common
+// subsequence of the changed lines of a Diff, which grows with the product of
+// their numbers.  Beyond it, the changed lines are printed as removed, then added.
+const maxDiffCells = 1 << 18
+
+// diffValue holds the texts compared by Diff.
+type diffValue struct {
+	a, b string
+}
+
+// Diff returns a value which is rendered as the unified diff of the lines of a and
+// b, with the added and removed lines colored by the Theme's DiffAdded and
+// DiffRemoved styles, which is handy for logging config drift, or why a test failed:
+//
+//	logger.Warn("config changed", "diff", console.Diff(old, new))
+//
+// Like "diff -u", changes are printed in hunks, each with up to 3 unchanged lines
+// around the changes, below a header like "@@ -3,7 +3,8 @@".  Since the diff spans
+// multiple lines, it is printed below the record, like any other multiline
+// attribute.  If a and b are equal, the value is empty.  Other handlers print the
+// uncolored diff.  To bound its memory, a diff of a change to hundreds of lines on
+// both sides prints them all as removed, then all as added.
+func Diff(a, b string) slog.Value {
+	return slog.AnyValue(diffValue{a: a, b: b})
+}
+
+// String returns the uncolored unified diff.
+func (d diffValue) String() string {
+	return unifiedDiff(d.a, d.b)
+}
+
+// diffOp is a line of a diff: ' ' for unchanged lines, '-' for removed lines, and
+// '+' for added lines.  a and b are the indexes of the line in each text, or, for
+// lines which aren't in a text, of the next line in it.
+type diffOp struct {
+	kind byte
+	line string
+	a, b int
+}
+
+// splitLines splits s into lines.  A final newline doesn't start another line.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines returns the edit script turning a into b, using their longest common
+// subsequence.  The common prefix and suffix are trimmed first, since most diffs
+// of logged values are small changes to large texts.
+func diffLines(a, b []string) []diffOp {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+
+	// lcs[i*w+j] is the length of the longest common subsequence of ma[i:] and
+	// mb[j:].  If it would be too large, it's left empty, and all the changed
+	// lines of a are removed, then all those of b added.
+	w := len(mb) + 1
+	var lcs []int32
+	if (len(ma)+1)*w <= maxDiffCells {
+		lcs = make([]int32, (len(ma)+1)*w)
+		for i := len(ma) - 1; i >= 0; i-- {
+			for j := len(mb) - 1; j >= 0; j-- {
+				if ma[i] == mb[j] {
+					lcs[i*w+j] = lcs[(i+1)*w+j+1] + 1
+				} else {
+					lcs[i*w+j] = max(lcs[(i+1)*w+j], lcs[i*w+j+1])
+				}
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for i := 0; i < pre; i++ {
+		ops = append(ops, diffOp{kind: ' ', line: a[i], a: i, b: i})
+	}
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case lcs != nil && i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			ops = append(ops, diffOp{kind: ' ', line: ma[i], a: pre + i, b: pre + j})
+			i++
+			j++
+		case j == len(mb) || (i < len(ma) && (lcs == nil || lcs[(i+1)*w+j] >= lcs[i*w+j+1])):
+			// removals are listed before additions
+			ops = append(ops, diffOp{kind: '-', line: ma[i], a: pre + i, b: pre + j})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: mb[j], a: pre + i, b: pre + j})
+			j++
+		}
+	}
+	for k := 0; k < suf; k++ {
+		ops = append(ops, diffOp{kind: ' ', line: a[len(a)-suf+k], a: len(a) - suf + k, b: len(b) - suf + k})
+	}
+	return ops
+}
+
+// unifiedDiff returns the hunks of the unified diff of the lines of a and b, or ""
+// if they are equal.
+func unifiedDiff(a, b string) string {
+	ops := diffLines(splitLines(a), splitLines(b))
+	var sb strings.Builder
+	for start := 0; start < len(ops); {
+		// find the next change, and extend the hunk over the following changes
+		// which are close enough for their context lines to touch
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for k := first + 1; k < len(ops) && k-last <= 2*diffContext+1; k++ {
+			if ops[k].kind != ' ' {
+				last = k
+			}
+		}
+		from, to := max(first-diffContext, start), min(last+diffContext+1, len(ops))
+
+		aLen, bLen := 0, 0
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				aLen++
+			}
+			if op.kind != '-' {
+				bLen++
+			}
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString("@@ -")
+		writeHunkRange(&sb, ops[from].a, aLen)
+		sb.WriteString(" +")
+		writeHunkRange(&sb, ops[from].b, bLen)
+		sb.WriteString(" @@")
+		for _, op := range ops[from:to] {
+			sb.WriteByte('\n')
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+		}
+		start = to
+	}
+	return sb.String()
+}
+
+// writeHunkRange writes the range of lines of a hunk in one of the texts, like
+// "3,7".  Like "diff -u", the length is omitted if it is 1, and empty ranges start
+// at the line before the hunk.
+func writeHunkRange(sb *strings.Builder, index, n int) {
+	if n > 0 {
+		index++
+	}
+	sb.WriteString(strconv.Itoa(index))
+	if n != 1 {
+		sb.WriteByte(',')
+		sb.WriteString(strconv.Itoa(n))
+	}
+}
+
+// isUnifiedDiff reports whether s looks like a unified diff, i.e. it has more than
+// one line, and a hunk header.
+func isUnifiedDiff(s string) bool {
+	if !strings.Contains(s, "\n") {
+		return false
+	}
+	return strings.HasPrefix(s, "@@ -") || strings.Contains(s, "\n@@ -")
+}
+
+// diffText returns the text of the value, if it should be rendered as a colored
+// diff.  See Diff and HandlerOptions.HighlightDiffs.
+func (e *encoder) diffText(value slog.Value) (string, bool) {
+	if e.h.opts.Logfmt || e.h.opts.TextQuoting {
+		return "", false
+	}
+	switch value.Kind() {
+	case slog.KindAny:
+		if d, ok := value.Any().(diffValue); ok {
+			return d.String(), true
+		}
+	case slog.KindString:
+		if e.h.opts.HighlightDiffs && isUnifiedDiff(value.String()) {
+			return value.String(), true
+		}
+	}
+	return "", false
+}
+
+// writeDiff writes the lines of a unified diff, with the added and removed lines
+// in the DiffAdded and DiffRemoved styles, the file and hunk headers in the Header
+// style, and the other lines in the given style.  Each line is styled on its own,
+// so the styles don't leak into the prefixes of continuation lines.
+func (e *encoder) writeDiff(buf *buffer, text string, style ANSIMod) {
+	theme := &e.h.opts.Theme
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			buf.AppendByte('\n')
+		}
+		lineStyle := style
+		switch {
+		case strings.HasPrefix(line, "@@"), strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "):
+			lineStyle = theme.Header
+		case strings.HasPrefix(line, "+") && theme.DiffAdded != "":
+			lineStyle = theme.DiffAdded
+		case strings.HasPrefix(line, "-") && theme.DiffRemoved != "":
+			lineStyle = theme.DiffRemoved
+		}
+		if line == "" {
+			continue
+		}
+		e.writeColoredString(buf, line, lineStyle)
+	}
+}
@@ -0,0 +1,133 @@
+package console
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{name: "equal", a: "a\nb\n", b: "a\nb\n", want: ""},
+		{name: "both empty", want: ""},
+		{name: "changed", a: "a\nb\nc\n", b: "a\nB\nc\n", want: "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c"},
+		{name: "added", a: "", b: "x\n", want: "@@ -0,0 +1 @@\n+x"},
+		{name: "removed", a: "x\ny", b: "y", want: "@@ -1,2 +1 @@\n-x\n y"},
+		{
+			name: "context",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:    "1\n2\n3\n4\n5\n6\n7\n8\nnine\n",
+			want: "@@ -6,4 +6,4 @@\n 6\n 7\n 8\n-9\n+nine",
+		},
+		{
+			name: "separate hunks",
+			a:    "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n",
+			b:    "a\nb\nC\nd\ne\nf\ng\nh\ni\nj\nk\nl\n",
+			want: "@@ -1,6 +1,6 @@\n a\n b\n-c\n+C\n d\n e\n f\n@@ -9,3 +9,4 @@\n i\n j\n k\n+l",
+		},
+		{
+			name: "merged hunks",
+			a:    "a\nb\nc\nd\ne\nf\ng\nh\n",
+			b:    "A\nb\nc\nd\ne\nf\ng\nH\n",
+			want: "@@ -1,8 +1,8 @@\n-a\n+A\n b\n c\n d\n e\n f\n g\n-h\n+H",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AssertEqual(t, tt.want, unifiedDiff(tt.a, tt.b))
+		})
+	}
+}
+
+func TestDiffLines_Large(t *testing.T) {
+	// the changed lines are too many to compare, so they're all replaced
+	a, b := []string{"start"}, []string{"start"}
+	for i := 0; i < 1000; i++ {
+		a = append(a, strconv.Itoa(i))
+		b = append(b, strconv.Itoa(i+1))
+	}
+	a, b = append(a, "end"), append(b, "end")
+
+	var kinds []byte
+	for _, op := range diffLines(a, b) {
+		kinds = append(kinds, op.kind)
+	}
+	want := " " + strings.Repeat("-", 1000) + strings.Repeat("+", 1000) + " "
+	AssertEqual(t, want, string(kinds))
+
+	// below the limit, the common lines are found
+	kinds = kinds[:0]
+	for _, op := range diffLines(a[:101], b[:101]) {
+		kinds = append(kinds, op.kind)
+	}
+	AssertEqual(t, " -"+strings.Repeat(" ", 99)+"+", string(kinds))
+}
+
+func TestHandler_Diff(t *testing.T) {
+	theme := Theme{
+		Name:        "test",
+		Header:      ToANSICode(Bold),
+		AttrKey:     ToANSICode(Cyan),
+		AttrValue:   ToANSICode(Blue),
+		DiffAdded:   ToANSICode(Green),
+		DiffRemoved: ToANSICode(Red),
+	}
+	diff := "@@ -1,2 +1,2 @@\n a\n-b\n+c"
+	coloredDiff := styled("@@ -1,2 +1,2 @@", theme.Header) + "\n" +
+		styled(" a", theme.AttrValue) + "\n" +
+		styled("-b", theme.DiffRemoved) + "\n" +
+		styled("+c", theme.DiffAdded)
+
+	tests := []handlerTest{
+		{
+			name:  "diff value",
+			opts:  HandlerOptions{Theme: theme, HeaderFormat: "%m %a"},
+			msg:   "changed",
+			attrs: []slog.Attr{slog.Any("diff", Diff("a\nb\n", "a\nc\n"))},
+			want:  "changed\n" + styled("=== diff ===\n", theme.AttrKey) + coloredDiff + "\n",
+		},
+		{
+			name:  "no color",
+			opts:  HandlerOptions{NoColor: true, HeaderFormat: "%m %a"},
+			msg:   "changed",
+			attrs: []slog.Attr{slog.Any("diff", Diff("a\nb\n", "a\nc\n"))},
+			want:  "changed\n=== diff ===\n" + diff + "\n",
+		},
+		{
+			name:  "highlighted string",
+			opts:  HandlerOptions{Theme: theme, HeaderFormat: "%m %a", HighlightDiffs: true},
+			msg:   "changed",
+			attrs: []slog.Attr{slog.String("diff", diff)},
+			want:  "changed\n" + styled("=== diff ===\n", theme.AttrKey) + coloredDiff + "\n",
+		},
+		{
+			name:  "string not highlighted",
+			opts:  HandlerOptions{Theme: theme, HeaderFormat: "%m %a"},
+			msg:   "changed",
+			attrs: []slog.Attr{slog.String("diff", diff)},
+			want:  "changed\n" + styled("=== diff ===\n", theme.AttrKey) + styled(diff, theme.AttrValue) + "\n",
+		},
+		{
+			name:  "not a diff",
+			opts:  HandlerOptions{Theme: theme, HeaderFormat: "%m %a", HighlightDiffs: true},
+			msg:   "changed",
+			attrs: []slog.Attr{slog.String("list", "-a\n+b")},
+			want:  "changed\n" + styled("=== list ===\n", theme.AttrKey) + styled("-a\n+b", theme.AttrValue) + "\n",
+		},
+		{
+			name:  "logfmt",
+			opts:  HandlerOptions{NoColor: true, HeaderFormat: "%m %a", Logfmt: true},
+			msg:   "changed",
+			attrs: []slog.Attr{slog.Any("diff", Diff("a\n", "b\n"))},
+			want:  `changed diff="@@ -1 +1 @@\n-a\n+b"` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, tt.run)
+	}
+}
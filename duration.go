@@ -1,6 +1,9 @@
 package console
 
-import "time"
+import (
+	"strconv"
+	"time"
+)
 
 // appendDuration appends a string representing the duration in the form "72h3m0.5s".
 // Leading zero units are omitted. As a special case, durations less than one
@@ -124,3 +127,24 @@ func fmtInt(buf []byte, v uint64) int {
 	}
 	return w
 }
+
+// appendElapsed appends d in the form "+MM:SS.mmm", or "+H:MM:SS.mmm" if d is an
+// hour or more.  Negative durations are prefixed with "-" instead.
+func appendElapsed(dst []byte, d time.Duration) []byte {
+	sign := byte('+')
+	if d < 0 {
+		sign = '-'
+		d = -d
+	}
+	ms := int64(d / time.Millisecond)
+	h, m, s := ms/3600000, ms/60000%60, ms/1000%60
+	ms %= 1000
+
+	dst = append(dst, sign)
+	if h > 0 {
+		dst = strconv.AppendInt(dst, h, 10)
+		dst = append(dst, ':')
+	}
+	dst = append(dst, byte('0'+m/10), byte('0'+m%10), ':', byte('0'+s/10), byte('0'+s%10), '.')
+	return append(dst, byte('0'+ms/100), byte('0'+ms/10%10), byte('0'+ms%10))
+}
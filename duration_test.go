@@ -31,6 +31,20 @@ func TestDuration(t *testing.T) {
 	AssertEqual(t, "2d1h0m1s", string(bd))
 }
 
+func TestAppendElapsed(t *testing.T) {
+	tests := map[time.Duration]string{
+		0: "+00:00.000",
+		3*time.Second + 214*time.Millisecond + 999: "+00:03.214",
+		59*time.Minute + 59*time.Second:            "+59:59.000",
+		time.Hour + 2*time.Minute + 3*time.Second:  "+1:02:03.000",
+		100 * time.Hour:       "+100:00:00.000",
+		-5 * time.Millisecond: "-00:00.005",
+	}
+	for d, want := range tests {
+		AssertEqual(t, want, string(appendElapsed(nil, d)))
+	}
+}
+
 func BenchmarkDuration(b *testing.B) {
 	d := 12*time.Hour + 13*time.Minute + 43*time.Second + 12*time.Millisecond
 	b.Run("std", func(b *testing.B) {
@@ -0,0 +1,156 @@
+package console
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Encoder encodes records, and parts of them, the way a Handler does, into a
+// buffer, so other slog handlers can reuse this package's colored encoding, e.g.
+// to send records to a UI widget, or to a test log, rather than to an io.Writer:
+//
+//	func (w *widgetHandler) Handle(ctx context.Context, rec slog.Record) error {
+//		enc := w.console.NewEncoder()
+//		defer enc.Free()
+//		enc.EncodeRecord(ctx, rec)
+//		w.widget.Append(enc.String())
+//		return nil
+//	}
+//
+// The encoding is configured by the options of the Handler the Encoder is created
+// from, and includes the attrs and groups added to it with WithAttrs and WithGroup.
+// Encoders are pooled, so they should be released with Free when they're no longer
+// needed, and must not be used after that.  An Encoder must not be used by more
+// than one goroutine at a time.
+type Encoder struct {
+	e *encoder
+}
+
+var _ io.Writer = (*Encoder)(nil)
+var _ io.StringWriter = (*Encoder)(nil)
+var _ io.WriterTo = (*Encoder)(nil)
+
+// NewEncoder returns an Encoder using h's options, attrs, and groups.
+func (h *Handler) NewEncoder() *Encoder {
+	return &Encoder{e: newEncoder(h)}
+}
+
+// Free returns the Encoder's buffers to the pool.
+func (e *Encoder) Free() {
+	e.e.free()
+	e.e = nil
+}
+
+// EncodeRecord appends the record, encoded like the Handler would write it,
+// including the trailing newline, and the multiline attrs on the following lines.
+// Records aren't filtered by level, sampled, or collapsed as repeats, and the
+// handler's OnRecord and OnEmit hooks aren't called.
+func (e *Encoder) EncodeRecord(ctx context.Context, rec slog.Record) {
+	h := e.e.h
+	h.prepareRecord(&rec)
+	// some options rewrite all the lines of the buffer, so the record is
+	// encoded on its own, then appended
+	enc := newEncoder(h)
+	trailer, _, _ := h.encodeRecord(enc, ctx, &rec)
+	e.e.buf.Append(enc.buf)
+	e.e.buf.Append(trailer)
+	enc.free()
+}
+
+// WriteTimestamp appends the time, formatted and styled like the timestamps of
+// records.  The zero time is omitted.
+func (e *Encoder) WriteTimestamp(t time.Time) {
+	e.e.encodeTimestamp(t)
+}
+
+// WriteLevel appends the level, styled like the levels of records, e.g. "INF" if
+// abbreviated is set, or "INFO" if it isn't.
+func (e *Encoder) WriteLevel(l slog.Level, abbreviated bool) {
+	e.e.encodeLevel(l, abbreviated)
+}
+
+// WriteMessage appends the message of a record of the given level, styled like
+// the messages of records.
+func (e *Encoder) WriteMessage(l slog.Level, msg string) {
+	e.e.encodeMessage(l, msg)
+}
+
+// WriteSource appends the source location, like "main.go:12", styled like
+// the sources of info records.
+func (e *Encoder) WriteSource(src *slog.Source) {
+	e.e.encodeSource(slog.LevelInfo, src)
+}
+
+// WriteAttr appends the attr like the attrs of records, e.g. " key=value",
+// including the separator before it, and the handler's groups.  Multiline values
+// are appended in a block, like "\n=== key ===\nvalue".
+func (e *Encoder) WriteAttr(a slog.Attr) {
+	enc := e.e
+	// the attr shouldn't be taken for one of the header's fields
+	headerAttrs := enc.headerAttrs
+	enc.headerAttrs = enc.headerAttrs[:0]
+	enc.encodeAttr(enc.h.groupPrefix, a)
+	enc.headerAttrs = headerAttrs
+	enc.buf.Append(enc.attrBuf)
+	enc.buf.Append(enc.multilineAttrBuf)
+	enc.resetAttrs()
+}
+
+// WriteValue appends the value, formatted like attr values, without any style.
+func (e *Encoder) WriteValue(v slog.Value) {
+	e.e.writeValue(&e.e.buf, v)
+}
+
+// WriteColored appends s in the style, unless the handler's colors are disabled.
+func (e *Encoder) WriteColored(s string, style ANSIMod) {
+	e.e.writeColoredString(&e.e.buf, s, style)
+}
+
+// WithColor calls f, and styles whatever f appends with the style, unless the
+// handler's colors are disabled.
+func (e *Encoder) WithColor(style ANSIMod, f func()) {
+	e.e.withColor(&e.e.buf, style, f)
+}
+
+// Write appends p.  It never returns an error.
+func (e *Encoder) Write(p []byte) (int, error) {
+	return e.e.buf.Write(p)
+}
+
+// WriteString appends s.  It never returns an error.
+func (e *Encoder) WriteString(s string) (int, error) {
+	return e.e.buf.WriteString(s)
+}
+
+// WriteByte appends c.  It never returns an error.
+func (e *Encoder) WriteByte(c byte) error {
+	return e.e.buf.WriteByte(c)
+}
+
+// Bytes returns the encoded bytes.  The slice is only valid until the next call
+// to one of the Encoder's methods.
+func (e *Encoder) Bytes() []byte {
+	return e.e.buf
+}
+
+// String returns the encoded bytes as a string.
+func (e *Encoder) String() string {
+	return string(e.e.buf)
+}
+
+// Len returns the number of encoded bytes.
+func (e *Encoder) Len() int {
+	return len(e.e.buf)
+}
+
+// Reset discards the encoded bytes.
+func (e *Encoder) Reset() {
+	e.e.buf.Reset()
+}
+
+// WriteTo writes the encoded bytes to w, and resets the Encoder.
+func (e *Encoder) WriteTo(w io.Writer) (int64, error) {
+	return e.e.buf.WriteTo(w)
+}
@@ -0,0 +1,94 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestEncoder_EncodeRecord(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, ContinuationPrefix: "| ", TimeFormat: time.Kitchen}).
+		WithAttrs([]slog.Attr{slog.String("foo", "bar")}).
+		WithGroup("g").(*Handler)
+
+	rec := slog.NewRecord(time.Date(2024, 01, 02, 15, 04, 05, 0, time.UTC), slog.LevelWarn, "msg", 0)
+	rec.AddAttrs(slog.Int("n", 1), slog.String("text", "a\nb"))
+	AssertNoError(t, h.Handle(context.Background(), rec))
+
+	enc := h.NewEncoder()
+	defer enc.Free()
+	_, _ = enc.WriteString("> ")
+	enc.EncodeRecord(context.Background(), rec)
+	AssertEqual(t, "> "+buf.String(), enc.String())
+	AssertEqual(t, "3:04PM WRN msg foo=bar g.n=1\n| === g.text ===\n| a\n| b\n", buf.String())
+
+	// records aren't filtered
+	enc.Reset()
+	enc.EncodeRecord(context.Background(), slog.NewRecord(time.Time{}, slog.LevelDebug-8, "trace", 0))
+	AssertEqual(t, "DBG-8 trace foo=bar\n", enc.String())
+}
+
+func TestEncoder_Parts(t *testing.T) {
+	theme := Theme{
+		Name:           "test",
+		Timestamp:      ToANSICode(Faint),
+		Source:         ToANSICode(Blue),
+		Message:        ToANSICode(Bold),
+		AttrKey:        ToANSICode(Cyan),
+		LevelError:     ToANSICode(Red),
+		AttrValueError: ToANSICode(BrightRed),
+	}
+	h := NewHandler(nil, &HandlerOptions{Theme: theme, HeaderFormat: "%[n]h %m", TimeFormat: time.Kitchen}).WithGroup("g").(*Handler)
+	enc := h.NewEncoder()
+	defer enc.Free()
+
+	enc.WriteTimestamp(time.Date(2024, 01, 02, 15, 04, 05, 0, time.UTC))
+	enc.WriteTimestamp(time.Time{})
+	AssertNoError(t, enc.WriteByte(' '))
+	enc.WriteLevel(slog.LevelError, true)
+	enc.WriteLevel(slog.LevelError, false)
+	_, _ = enc.Write([]byte(" "))
+	enc.WriteSource(&slog.Source{File: "/a/b.go", Line: 3})
+	_, _ = enc.WriteString(" ")
+	enc.WriteMessage(slog.LevelInfo, "hi")
+	// attrs aren't captured by the header fields
+	enc.WriteAttr(slog.Int("n", 1))
+	enc.WriteAttr(slog.Any("err", errors.New("boom")))
+	_, _ = enc.WriteString(" ")
+	enc.WriteValue(slog.DurationValue(time.Second))
+	enc.WithColor(ToANSICode(Green), func() {
+		_, _ = enc.WriteString(" ok")
+	})
+	enc.WriteColored("!", ToANSICode(Yellow))
+
+	want := styled("3:04PM", theme.Timestamp) + " " +
+		styled("ERR", theme.LevelError) + styled("ERROR", theme.LevelError) + " " +
+		styled("/a/b.go:3", theme.Source) + " " +
+		styled("hi", theme.Message) +
+		" " + styled("g.n=", theme.AttrKey) + "1" +
+		" " + styled("g.err=", theme.AttrKey) + styled("boom", theme.AttrValueError) +
+		" 1s" + styled(" ok", ToANSICode(Green)) + styled("!", ToANSICode(Yellow))
+	AssertEqual(t, want, enc.String())
+	AssertEqual(t, len(want), enc.Len())
+	AssertEqual(t, want, string(enc.Bytes()))
+
+	var out bytes.Buffer
+	n, err := enc.WriteTo(&out)
+	AssertNoError(t, err)
+	AssertEqual(t, int64(len(want)), n)
+	AssertEqual(t, want, out.String())
+	AssertEqual(t, 0, enc.Len())
+}
+
+func TestEncoder_WriteAttr_Multiline(t *testing.T) {
+	enc := NewHandler(nil, &HandlerOptions{NoColor: true}).NewEncoder()
+	defer enc.Free()
+	enc.WriteAttr(slog.String("a", "1"))
+	enc.WriteAttr(slog.String("text", "x\ny"))
+	enc.WriteAttr(slog.Group("grp", slog.Int("b", 2)))
+	AssertEqual(t, " a=1\n=== text ===\nx\ny grp.b=2", enc.String())
+}
@@ -4,25 +4,72 @@ import (
 	"bytes"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"path/filepath"
+	"reflect"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/ansel1/console-slog/internal"
 )
 
-var encoderPool = &sync.Pool{
-	New: func() any {
-		e := new(encoder)
-		e.groups = make([]string, 0, 10)
-		e.buf = make(buffer, 0, 1024)
-		e.attrBuf = make(buffer, 0, 1024)
-		e.multilineAttrBuf = make(buffer, 0, 1024)
-		e.headerAttrs = make([]slog.Attr, 0, 5)
-		return e
-	},
+// defaultMaxBufferSize is the default for HandlerOptions.MaxBufferSize.
+const defaultMaxBufferSize = 16 << 10
+
+// defaultMaxMultilineBytes is the default for HandlerOptions.MaxMultilineBytes.
+const defaultMaxMultilineBytes = 1 << 20
+
+// PoolStats are statistics about the pools of encoders used by all handlers.
+// Each record, and each call to WithAttrs, borrows an encoder from the pool.
+type PoolStats struct {
+	// Gets is the number of encoders taken from the pool.
+	Gets uint64
+	// Allocs is the number of encoders allocated because the pool was empty.
+	Allocs uint64
+	// Puts is the number of encoders returned to the pool.
+	Puts uint64
+	// Drops is the number of encoders which were not returned to the pool because
+	// one of their buffers had grown beyond HandlerOptions.MaxBufferSize.
+	Drops uint64
+}
+
+var poolGets, poolAllocs, poolPuts, poolDrops atomic.Uint64
+
+// EncoderPoolStats returns statistics about the pools of encoders used by all handlers.
+// It can be used to check whether HandlerOptions.MaxBufferSize is set appropriately:
+// a high rate of Drops means buffers are frequently re-allocated.
+func EncoderPoolStats() PoolStats {
+	return PoolStats{
+		Gets:   poolGets.Load(),
+		Allocs: poolAllocs.Load(),
+		Puts:   poolPuts.Load(),
+		Drops:  poolDrops.Load(),
+	}
+}
+
+// encoderPool is the pool of encoders shared by the handlers with the default
+// MaxBufferSize.  Handlers with another MaxBufferSize have their own pool, shared
+// with the handlers derived from them, so the buffers one handler is allowed to keep
+// aren't retained by all the others.
+var encoderPool = newEncoderPool()
+
+func newEncoderPool() *sync.Pool {
+	return &sync.Pool{
+		New: func() any {
+			poolAllocs.Add(1)
+			e := new(encoder)
+			e.groups = make([]string, 0, 10)
+			e.buf = make(buffer, 0, 1024)
+			e.attrBuf = make(buffer, 0, 1024)
+			e.multilineAttrBuf = make(buffer, 0, 1024)
+			e.headerAttrs = make([]slog.Attr, 0, 5)
+			return e
+		},
+	}
 }
 
 type encoder struct {
@@ -30,16 +77,63 @@ type encoder struct {
 	buf, attrBuf, multilineAttrBuf buffer
 	groups                         []string
 	headerAttrs                    []slog.Attr
+	// headerRanks are the ranks of the keys of the headerAttrs.  See headerField.rank.
+	headerRanks []int
+	// headerFields are the handler's header fields, with the memos of the header
+	// attrs from WithAttrs, which the record's attrs may replace.
+	headerFields []headerField
+	// valuers are the LogValuers of the groups being encoded,
+	// to detect cycles
+	valuers []slog.LogValuer
+	// scratch is a temporary buffer, e.g. for quoting values
+	scratch buffer
+	// lastTime, lastLayout, and lastTimestamp cache the last record timestamp
+	// formatted by the encoder, which is reused while records have the same
+	// timestamp.  They're kept when the encoder is returned to the pool.
+	lastTime      time.Time
+	lastLayout    string
+	lastTimestamp buffer
+	// groupDepth is the number of groups being encoded as nested
+	// blocks, rather than as key prefixes.  See HandlerOptions.GroupFormat.
+	groupDepth int
+	// rule is set if the record included the attribute returned by Rule
+	rule bool
+	// pretty is set if the attrs are encoded in pretty mode.
+	// See HandlerOptions.Pretty.
+	pretty bool
+	// prettyAttr is 1 or -1 if the record included the attribute returned
+	// by Pretty(true) or Pretty(false)
+	prettyAttr int8
+	// headerEnd is the offset in buf of the end of the header, i.e. the
+	// start of the message.  See HandlerOptions.RepeatHeader.
+	headerEnd int
+	// numAttrs and omittedAttrs are the numbers of attrs printed and
+	// omitted.  See HandlerOptions.MaxAttrs.
+	numAttrs, omittedAttrs int
+	// omittedMultiline is the number of bytes of multiline attrs omitted.
+	// See HandlerOptions.MaxMultilineBytes.
+	omittedMultiline int
+	// filteredAttrs are the attrs of the %[group]a fields, in the order of
+	// Handler.attrFilters, and filtering is set while one of them is encoded.
+	filteredAttrs []buffer
+	filtering     bool
 }
 
 func newEncoder(h *Handler) *encoder {
-	e := encoderPool.Get().(*encoder)
+	e := h.pool.Get().(*encoder)
+	poolGets.Add(1)
 	e.h = h
+	e.pretty = h.opts.Pretty
 	if h.opts.ReplaceAttr != nil {
 		e.groups = append(e.groups, h.groups...)
 	}
 	e.headerAttrs = slices.Grow(e.headerAttrs, len(h.headerFields))[:len(h.headerFields)]
 	clear(e.headerAttrs)
+	e.headerRanks = slices.Grow(e.headerRanks, len(h.headerFields))[:len(h.headerFields)]
+	e.headerFields = h.encoded().headerFields
+	if len(h.attrFilters) > 0 {
+		e.filteredAttrs = slices.Grow(e.filteredAttrs, len(h.attrFilters))[:len(h.attrFilters)]
+	}
 	return e
 }
 
@@ -47,13 +141,36 @@ func (e *encoder) free() {
 	if e == nil {
 		return
 	}
+	// To reduce peak allocation, return only smaller buffers to the pool.
+	pool := e.h.pool
+	if maxSize := e.h.opts.MaxBufferSize; maxSize > 0 &&
+		(cap(e.buf) > maxSize || cap(e.attrBuf) > maxSize || cap(e.multilineAttrBuf) > maxSize || cap(e.scratch) > maxSize) {
+		e.h = nil
+		poolDrops.Add(1)
+		return
+	}
 	e.h = nil
 	e.buf.Reset()
 	e.attrBuf.Reset()
 	e.multilineAttrBuf.Reset()
+	e.scratch.Reset()
+	e.rule = false
+	e.pretty = false
+	e.prettyAttr = 0
+	e.headerEnd = 0
+	e.numAttrs, e.omittedAttrs = 0, 0
+	e.omittedMultiline = 0
+	for i := range e.filteredAttrs {
+		e.filteredAttrs[i].Reset()
+	}
+	e.filteredAttrs = e.filteredAttrs[:0]
 	e.groups = e.groups[:0]
 	e.headerAttrs = e.headerAttrs[:0]
-	encoderPool.Put(e)
+	e.headerFields = nil
+	clear(e.valuers)
+	e.valuers = e.valuers[:0]
+	poolPuts.Add(1)
+	pool.Put(e)
 }
 
 func (e *encoder) encodeTimestamp(tt time.Time) {
@@ -87,10 +204,67 @@ func (e *encoder) encodeTimestamp(tt time.Time) {
 	}
 
 	e.withColor(&e.buf, e.h.opts.Theme.Timestamp, func() {
-		e.buf.AppendTime(tt, e.h.opts.TimeFormat)
+		l := len(e.buf)
+		if e.h.opts.RelativeTime {
+			e.buf = appendElapsed(e.buf, tt.Sub(e.h.start))
+		} else {
+			e.appendTimestamp(tt)
+		}
+		if e.h.lastTime != nil {
+			e.appendTimeDelta(tt)
+		}
+		e.quoteFrom(&e.buf, l)
 	})
 }
 
+// appendTimeDelta appends the time since the previous record, like " (+12ms)".
+// Nothing is appended for the first record.
+func (e *encoder) appendTimeDelta(tt time.Time) {
+	prev := e.h.lastTime.Swap(tt.UnixNano())
+	if prev == 0 {
+		return
+	}
+	d := time.Duration(tt.UnixNano() - prev)
+	if d >= time.Millisecond || d <= -time.Millisecond {
+		d = d.Round(time.Millisecond)
+	} else {
+		d = d.Round(time.Microsecond)
+	}
+	e.buf.AppendString(" (")
+	if d >= 0 {
+		e.buf.AppendByte('+')
+	}
+	e.buf.AppendDuration(d)
+	e.buf.AppendByte(')')
+}
+
+// appendTimestamp appends the record timestamp t, like appendTime, but reuses the
+// last timestamp the encoder formatted if t and the TimeFormat are the same, as
+// they often are in bursts of records.
+func (e *encoder) appendTimestamp(t time.Time) {
+	if e.h.opts.FormatTime != nil {
+		e.appendTime(&e.buf, t)
+		return
+	}
+	if t == e.lastTime && e.h.opts.TimeFormat == e.lastLayout && len(e.lastTimestamp) > 0 {
+		e.buf.Append(e.lastTimestamp)
+		return
+	}
+	l := len(e.buf)
+	e.appendTime(&e.buf, t)
+	e.lastTime, e.lastLayout = t, e.h.opts.TimeFormat
+	e.lastTimestamp = append(e.lastTimestamp[:0], e.buf[l:]...)
+}
+
+// appendTime formats t with HandlerOptions.FormatTime, or TimeFormat.
+func (e *encoder) appendTime(buf *buffer, t time.Time) {
+	if e.h.opts.FormatTime != nil {
+		*buf = e.h.opts.FormatTime(*buf, t)
+		return
+	}
+	buf.AppendTime(t, e.h.opts.TimeFormat)
+}
+
 func (e *encoder) encodeMessage(level slog.Level, msg string) {
 	style := e.h.opts.Theme.Message
 	if level < slog.LevelInfo {
@@ -105,53 +279,81 @@ func (e *encoder) encodeMessage(level slog.Level, msg string) {
 			return
 		}
 
-		e.writeColoredValue(&e.buf, attr.Value, style)
+		l := len(e.buf)
+		e.writeHighlightedValue(&e.buf, attr.Value, style, false)
+		e.normalizeFrom(&e.buf, l)
 		return
 	}
 
-	e.writeColoredString(&e.buf, strings.TrimSpace(msg), style)
+	l := len(e.buf)
+	e.writeHighlightedValue(&e.buf, slog.StringValue(strings.TrimSpace(msg)), style, false)
+	e.normalizeFrom(&e.buf, l)
 }
 
-func (e *encoder) encodeHeader(a slog.Attr, width int, rightAlign bool) {
+func (e *encoder) encodeHeader(a slog.Attr, f headerField) {
+	width, rightAlign := f.width, f.rightAlign
+	pad := f.padChar()
 	if a.Value.Equal(slog.Value{}) {
 		// just pad as needed
 		if width > 0 {
-			e.buf.Pad(width, ' ')
+			e.buf.Pad(width, pad)
 		}
 		return
 	}
 
-	e.withColor(&e.buf, e.h.opts.Theme.Header, func() {
+	e.withColor(&e.buf, f.style, func() {
 		l := len(e.buf)
-		e.writeValue(&e.buf, a.Value)
-		if width <= 0 {
-			return
+		e.writeHeaderValue(&e.buf, a.Value)
+		if f.transform != 0 {
+			e.transformFrom(&e.buf, l, f.transform)
 		}
-		// truncate or pad to required width
-		remainingWidth := l + width - len(e.buf)
-		if remainingWidth < 0 {
-			// truncate
-			e.buf = e.buf[:l+width]
-		} else if remainingWidth > 0 {
-			if rightAlign {
-				// For right alignment, shift the text right in-place:
-				// 1. Get the text length
-				textLen := len(e.buf) - l
-				// 2. Add padding to reach final width
-				e.buf.Pad(remainingWidth, ' ')
-				// 3. Move the text to the right by copying from end to start
-				for i := 0; i < textLen; i++ {
-					e.buf[len(e.buf)-1-i] = e.buf[l+textLen-1-i]
+		if width > 0 {
+			// truncate to required width, counting only the visible characters,
+			// so escape sequences and multibyte characters aren't split
+			if cut := l + visibleIndex(e.buf[l:], width); cut < len(e.buf) {
+				marker := e.h.opts.TruncationMarker
+				if marker != "" {
+					// make room for the marker
+					markerWidth := min(visibleWidth([]byte(marker)), width)
+					cut = l + visibleIndex(e.buf[l:], width-markerWidth)
+					marker = marker[:visibleIndex([]byte(marker), markerWidth)]
 				}
-				// 4. Fill the left side with spaces
-				for i := 0; i < remainingWidth; i++ {
-					e.buf[l+i] = ' '
+				colored := bytes.IndexByte(e.buf[cut:], '\x1b') >= 0
+				e.buf = e.buf[:cut]
+				if colored {
+					// the sequence resetting the colors of the value was cut
+					e.buf.AppendString(string(ResetMod))
 				}
-			} else {
-				// Left align - just pad with spaces
-				e.buf.Pad(remainingWidth, ' ')
+				e.buf.AppendString(marker)
+			}
+		}
+		e.quoteFrom(&e.buf, l)
+		if width <= 0 {
+			return
+		}
+		// pad to required width
+		remainingWidth := width - visibleWidth(e.buf[l:])
+		if remainingWidth <= 0 {
+			return
+		}
+		// the padding on the left: all of it when right aligned, and half of it,
+		// rounded down, when centered
+		leftPad := 0
+		if rightAlign {
+			leftPad = remainingWidth
+		} else if f.center {
+			leftPad = remainingWidth / 2
+		}
+		if leftPad > 0 {
+			// make room for the left padding, and shift the text right in one copy
+			textLen := len(e.buf) - l
+			e.buf.Pad(leftPad, pad)
+			copy(e.buf[l+leftPad:], e.buf[l:l+textLen])
+			for i := l; i < l+leftPad; i++ {
+				e.buf[i] = pad
 			}
 		}
+		e.buf.Pad(remainingWidth-leftPad, pad)
 	})
 }
 
@@ -181,63 +383,38 @@ func (e *encoder) encodeLevel(l slog.Level, abbreviated bool) {
 		}
 	}
 
-	var style ANSIMod
-	var str string
-	var delta int
+	style := levelStyle(e.h.opts.Theme, l)
+	if writeVal {
+		e.writeColoredValue(&e.buf, val, style)
+	} else {
+		e.writeColoredValue(&e.buf, slog.StringValue(levelName(l, abbreviated)), style)
+	}
+}
+
+// levelStyle returns the theme's style for the level.
+func levelStyle(theme Theme, l slog.Level) ANSIMod {
+	if style, ok := theme.LevelColor(l); ok {
+		return style
+	}
 	switch {
 	case l >= slog.LevelError:
-		style = e.h.opts.Theme.LevelError
-		str = "ERR"
-		if !abbreviated {
-			str = "ERROR"
-		}
-		delta = int(l - slog.LevelError)
+		return theme.LevelError
 	case l >= slog.LevelWarn:
-		style = e.h.opts.Theme.LevelWarn
-		str = "WRN"
-		if !abbreviated {
-			str = "WARN"
-		}
-		delta = int(l - slog.LevelWarn)
+		return theme.LevelWarn
 	case l >= slog.LevelInfo:
-		style = e.h.opts.Theme.LevelInfo
-		str = "INF"
-		if !abbreviated {
-			str = "INFO"
-		}
-		delta = int(l - slog.LevelInfo)
-	case l >= slog.LevelDebug:
-		style = e.h.opts.Theme.LevelDebug
-		str = "DBG"
-		if !abbreviated {
-			str = "DEBUG"
-		}
-		delta = int(l - slog.LevelDebug)
+		return theme.LevelInfo
 	default:
-		style = e.h.opts.Theme.LevelDebug
-		str = "DBG"
-		if !abbreviated {
-			str = "DEBUG"
-		}
-		delta = int(l - slog.LevelDebug)
-	}
-	if writeVal {
-		e.writeColoredValue(&e.buf, val, style)
-	} else {
-		if delta != 0 {
-			str = fmt.Sprintf("%s%+d", str, delta)
-		}
-		e.writeColoredString(&e.buf, str, style)
+		return theme.LevelDebug
 	}
 }
 
-func (e *encoder) encodeSource(src slog.Source) {
-	if src.File == "" && src.Line == 0 {
+func (e *encoder) encodeSource(level slog.Level, src *slog.Source) {
+	if src == nil || (src.File == "" && src.Line == 0) {
 		// elide empty source
 		return
 	}
 
-	v := slog.AnyValue(&src)
+	v := slog.AnyValue(src)
 
 	if e.h.opts.ReplaceAttr != nil {
 		attr := e.h.opts.ReplaceAttr(nil, slog.Attr{Key: slog.SourceKey, Value: v})
@@ -249,66 +426,505 @@ func (e *encoder) encodeSource(src slog.Source) {
 		}
 		v = attr.Value
 	}
-	// Use source style for the value
-	e.writeColoredValue(&e.buf, v, e.h.opts.Theme.Source)
+	// Use source style for the value, or the stronger styles of warnings and
+	// errors, if the theme has them
+	style := e.h.opts.Theme.Source
+	switch {
+	case level >= slog.LevelError && e.h.opts.Theme.SourceError != "":
+		style = e.h.opts.Theme.SourceError
+	case level >= slog.LevelWarn && level < slog.LevelError && e.h.opts.Theme.SourceWarn != "":
+		style = e.h.opts.Theme.SourceWarn
+	}
+	e.writeColoredValue(&e.buf, v, style)
+}
+
+func (e *encoder) encodeName(name string, style ANSIMod) {
+	if name == "" {
+		return
+	}
+
+	if e.h.opts.ReplaceAttr != nil {
+		attr := e.h.opts.ReplaceAttr(nil, slog.String(e.h.opts.LoggerNameKey, name))
+		attr.Value = attr.Value.Resolve()
+
+		if attr.Value.Equal(slog.Value{}) {
+			// elide
+			return
+		}
+
+		e.writeColoredValue(&e.buf, attr.Value, style)
+		return
+	}
+
+	e.writeColoredValue(&e.buf, slog.StringValue(name), style)
 }
 
 func (e *encoder) encodeAttr(groupPrefix string, a slog.Attr) {
 
-	a.Value = a.Value.Resolve()
+	var valuer slog.LogValuer
+	a.Value, valuer = e.resolve(a.Value)
+	if a.Value.Kind() == slog.KindAny {
+		switch m := a.Value.Any().(type) {
+		case ruleMarker:
+			e.rule = true
+			return
+		case prettyMarker:
+			e.prettyAttr = 1
+			if !m {
+				e.prettyAttr = -1
+			}
+			return
+		}
+	}
 	if a.Value.Kind() != slog.KindGroup && e.h.opts.ReplaceAttr != nil {
 		a = e.h.opts.ReplaceAttr(e.groups, a)
-		a.Value = a.Value.Resolve()
+		a.Value, valuer = e.resolve(a.Value)
 	}
 	// Elide empty Attrs.
 	if a.Equal(slog.Attr{}) {
 		return
 	}
+	if e.h.opts.OmitZero && isZeroValue(a.Value) {
+		return
+	}
 
-	value := a.Value
+	if a.Value.Kind() == slog.KindGroup && valuer != nil && len(e.valuers) >= e.h.opts.MaxResolveDepth {
+		// groups nested this deep by LogValuers are most likely cycles
+		a.Value = cycleValue
+	}
 
-	if value.Kind() == slog.KindGroup {
-		subgroup := a.Key
-		if groupPrefix != "" {
-			subgroup = groupPrefix + "." + a.Key
+	if i := e.attrFilter(groupPrefix, a); i >= 0 {
+		// encode the attr into the buffer of its %[group]a field
+		e.attrBuf, e.filteredAttrs[i] = e.filteredAttrs[i], e.attrBuf
+		e.filtering = true
+		e.encodeResolvedAttr(groupPrefix, a, valuer)
+		e.filtering = false
+		e.attrBuf, e.filteredAttrs[i] = e.filteredAttrs[i], e.attrBuf
+		return
+	}
+	e.encodeResolvedAttr(groupPrefix, a, valuer)
+}
+
+// isZeroValue reports whether v is the zero value of its kind, like "", 0, false,
+// the zero time, or nil, including nil pointers, slices, and maps.
+func isZeroValue(v slog.Value) bool {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String() == ""
+	case slog.KindInt64:
+		return v.Int64() == 0
+	case slog.KindUint64:
+		return v.Uint64() == 0
+	case slog.KindFloat64:
+		return v.Float64() == 0
+	case slog.KindBool:
+		return !v.Bool()
+	case slog.KindDuration:
+		return v.Duration() == 0
+	case slog.KindTime:
+		return v.Time().IsZero()
+	case slog.KindAny:
+		if v.Any() == nil {
+			return true
 		}
-		if e.h.opts.ReplaceAttr != nil {
-			e.groups = append(e.groups, a.Key)
+		// typed nils, like a nil slice or pointer
+		switch rv := reflect.ValueOf(v.Any()); rv.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface, reflect.Chan, reflect.Func:
+			return rv.IsNil()
 		}
-		for _, attr := range value.Group() {
-			e.encodeAttr(subgroup, attr)
+	}
+	return false
+}
+
+// encodeResolvedAttr encodes an attr which has been resolved, and passed to ReplaceAttr.
+func (e *encoder) encodeResolvedAttr(groupPrefix string, a slog.Attr, valuer slog.LogValuer) {
+	value := a.Value
+	if value.Kind() == slog.KindGroup {
+		if valuer != nil {
+			e.valuers = append(e.valuers, valuer)
 		}
-		if e.h.opts.ReplaceAttr != nil {
-			e.groups = e.groups[:len(e.groups)-1]
+		e.encodeGroup(groupPrefix, a)
+		if valuer != nil {
+			e.valuers = e.valuers[:len(e.valuers)-1]
 		}
 		return
 	}
 
-	for i, f := range e.h.headerFields {
-		if f.key == a.Key && f.groupPrefix == groupPrefix {
-			e.headerAttrs[i] = a
+	// headerAttrs is empty if attrs shouldn't be captured by the header, see
+	// Encoder.WriteAttr
+	for i := range e.headerAttrs {
+		f := &e.headerFields[i]
+		rank := f.rank(groupPrefix, a.Key)
+		if rank < 0 {
+			continue
+		}
+		cur, curRank := e.headerAttrs[i], e.headerRanks[i]
+		if cur.Equal(slog.Attr{}) {
+			if !f.memoized {
+				e.headerAttrs[i], e.headerRanks[i] = a, rank
+				return
+			}
+			// the header prints an attr from WithAttrs, which an attr with
+			// the same key overrides
+			cur, curRank = f.memoAttr, f.memoRank
+			if rank == curRank {
+				e.headerAttrs[i], e.headerRanks[i] = a, rank
+				return
+			}
+		}
+		if rank < curRank {
+			// the attr it replaces is printed with the other attrs
+			groupPrefix = f.keys[curRank].groupPrefix
+			e.headerAttrs[i], e.headerRanks[i], a = a, rank, cur
+		}
+		break
+	}
+
+	if e.h.opts.MaxAttrs > 0 {
+		if e.numAttrs >= e.h.opts.MaxAttrs {
+			e.omittedAttrs++
 			return
 		}
+		e.numAttrs++
 	}
 
 	offset := len(e.attrBuf)
 	valOffset := e.writeAttr(a, groupPrefix)
+	e.normalizeFrom(&e.attrBuf, valOffset)
+
+	if e.inBlock() {
+		e.indentValue(valOffset)
+	}
 
 	// check if the last attr written has newlines in it
-	// if so, move it to the trailerBuf
-	if bytes.IndexByte(e.attrBuf[offset:], '\n') >= 0 {
+	// if so, move it to the trailerBuf.  Attrs in indented groups
+	// are moved with the whole group.
+	if !e.inBlock() && bytes.IndexByte(e.attrBuf[offset:], '\n') >= 0 {
 		if internal.FeatureFlagNewMultilineAttrs {
 			val := e.attrBuf[valOffset:]
 			e.writeMultilineAttr(a.Key, groupPrefix, val)
 		} else {
 			e.multilineAttrBuf.Append(e.attrBuf[offset:])
 		}
+		e.capMultiline()
 
 		// rewind the middle buffer
 		e.attrBuf = e.attrBuf[:offset]
 	}
 }
 
+func (e *encoder) encodeGroup(groupPrefix string, a slog.Attr) {
+	if (e.pretty || e.h.opts.GroupFormat != GroupDotted) && a.Key != "" {
+		e.encodeNestedGroup(groupPrefix, a)
+		return
+	}
+	if a.Key == "" {
+		// groups with empty keys are inlined
+		for _, attr := range a.Value.Group() {
+			e.encodeAttr(groupPrefix, attr)
+		}
+		return
+	}
+	subgroup := a.Key
+	if groupPrefix != "" {
+		subgroup = groupPrefix + e.h.opts.GroupSeparator + a.Key
+	}
+	if e.h.opts.ReplaceAttr != nil {
+		e.groups = append(e.groups, a.Key)
+	}
+	for _, attr := range a.Value.Group() {
+		e.encodeAttr(subgroup, attr)
+	}
+	if e.h.opts.ReplaceAttr != nil {
+		e.groups = e.groups[:len(e.groups)-1]
+	}
+}
+
+// encodeNestedGroup encodes a group as "key=(a=1 b=2)", or as an indented block,
+// depending on HandlerOptions.GroupFormat and Pretty.  Groups with no attributes
+// are elided.
+func (e *encoder) encodeNestedGroup(groupPrefix string, a slog.Attr) {
+	indent := e.pretty || e.h.opts.GroupFormat == GroupIndent
+	offset := len(e.attrBuf)
+	e.writeAttrSep()
+	e.withColor(&e.attrBuf, e.h.opts.Theme.AttrKey, func() {
+		if groupPrefix != "" && e.groupDepth == 0 {
+			e.attrBuf.AppendString(groupPrefix)
+			e.attrBuf.AppendString(e.h.opts.GroupSeparator)
+		}
+		e.attrBuf.AppendString(a.Key)
+		if indent {
+			e.attrBuf.AppendByte(':')
+		} else {
+			e.attrBuf.AppendString(e.kvSep())
+			e.attrBuf.AppendByte('(')
+		}
+	})
+	childrenOffset := len(e.attrBuf)
+
+	subgroup := a.Key
+	if groupPrefix != "" {
+		subgroup = groupPrefix + e.h.opts.GroupSeparator + a.Key
+	}
+	if e.h.opts.ReplaceAttr != nil {
+		e.groups = append(e.groups, a.Key)
+	}
+	e.groupDepth++
+	for _, attr := range a.Value.Group() {
+		e.encodeAttr(subgroup, attr)
+	}
+	e.groupDepth--
+	if e.h.opts.ReplaceAttr != nil {
+		e.groups = e.groups[:len(e.groups)-1]
+	}
+
+	if len(e.attrBuf) == childrenOffset {
+		// all the attrs were elided
+		e.attrBuf = e.attrBuf[:offset]
+		return
+	}
+
+	if !indent {
+		// drop the separator written before the first attr
+		e.attrBuf = append(e.attrBuf[:childrenOffset], e.attrBuf[childrenOffset+1:]...)
+		e.withColor(&e.attrBuf, e.h.opts.Theme.AttrKey, func() {
+			e.attrBuf.AppendByte(')')
+		})
+		return
+	}
+
+	if e.groupDepth == 0 && !e.pretty {
+		// move the whole block after the line, like other multiline attrs
+		if internal.FeatureFlagNewMultilineAttrs {
+			e.writeMultilineAttr(a.Key, groupPrefix, e.attrBuf[childrenOffset+1:])
+		} else {
+			e.multilineAttrBuf.Append(e.attrBuf[offset:])
+		}
+		e.capMultiline()
+		e.attrBuf = e.attrBuf[:offset]
+	}
+}
+
+// inBlock reports whether attrs are being encoded one per line, in pretty mode,
+// or in an indented group.
+func (e *encoder) inBlock() bool {
+	return e.pretty || (e.groupDepth > 0 && e.h.opts.GroupFormat == GroupIndent)
+}
+
+// blockIndent returns the indentation of attrs encoded one per line.  In pretty
+// mode, all the attrs are indented below the header line.  Otherwise, the attrs
+// of indented groups are only indented relative to the block.
+func (e *encoder) blockIndent() int {
+	if e.pretty {
+		return 2 * (e.groupDepth + 1)
+	}
+	return 2 * (e.groupDepth - 1)
+}
+
+// writeAttrSep writes the separator before an attr: a space, a comma in Compact
+// mode, or a newline and indentation inside blocks.
+func (e *encoder) writeAttrSep() {
+	if !e.inBlock() {
+		if e.h.opts.Compact && !e.h.opts.Logfmt && len(e.attrBuf) > 0 {
+			e.attrBuf.AppendByte(',')
+		} else {
+			e.attrBuf.AppendByte(' ')
+		}
+		return
+	}
+	e.attrBuf.AppendByte('\n')
+	e.attrBuf.Pad(e.blockIndent(), ' ')
+}
+
+// indentValue indents the lines of a multiline value written to attrBuf at offset,
+// so they line up below the attr's key in a block.
+func (e *encoder) indentValue(offset int) {
+	if bytes.IndexByte(e.attrBuf[offset:], '\n') < 0 {
+		return
+	}
+	e.scratch = append(e.scratch[:0], e.attrBuf[offset:]...)
+	e.attrBuf = e.attrBuf[:offset]
+	indent := e.blockIndent() + 2
+	for i, line := range bytes.Split(e.scratch, []byte{'\n'}) {
+		if i > 0 {
+			e.attrBuf.AppendByte('\n')
+			e.attrBuf.Pad(indent, ' ')
+		}
+		e.attrBuf.Append(line)
+	}
+}
+
+// attrFilter returns the index of the %[group]a field the attr belongs in, i.e.
+// the index in Handler.attrFilters of the group the attr is in, or the group the
+// attr is, or -1 if the attr belongs in the %a field.
+func (e *encoder) attrFilter(groupPrefix string, a slog.Attr) int {
+	if len(e.h.attrFilters) == 0 || e.filtering || e.pretty || e.groupDepth > 0 {
+		return -1
+	}
+	sep := e.h.opts.GroupSeparator
+	for i, f := range e.h.attrFilters {
+		if strings.HasPrefix(groupPrefix, f) && (len(groupPrefix) == len(f) || strings.HasPrefix(groupPrefix[len(f):], sep)) {
+			return i
+		}
+		if a.Value.Kind() == slog.KindGroup && qualifiedKeyEquals(f, groupPrefix, sep, a.Key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// qualifiedKeyEquals reports whether path equals the key qualified by the group
+// prefix, i.e. groupPrefix + sep + key, without concatenating them.
+func qualifiedKeyEquals(path, groupPrefix, sep, key string) bool {
+	if groupPrefix == "" {
+		return path == key
+	}
+	return len(path) == len(groupPrefix)+len(sep)+len(key) &&
+		strings.HasPrefix(path, groupPrefix) && strings.HasPrefix(path[len(groupPrefix):], sep) && strings.HasSuffix(path, key)
+}
+
+// attrFilterIndex returns the index of group in attrFilters.
+func (h *Handler) attrFilterIndex(group string) int {
+	return slices.Index(h.attrFilters, group)
+}
+
+// writeOmittedAttrs writes the summary of the attrs omitted because of
+// HandlerOptions.MaxAttrs, like "…(+7 more)".
+func (e *encoder) writeOmittedAttrs() {
+	e.writeAttrSep()
+	e.withColor(&e.attrBuf, e.h.opts.Theme.Header, func() {
+		e.attrBuf.AppendString("…(+")
+		e.attrBuf.AppendInt(int64(e.omittedAttrs))
+		e.attrBuf.AppendString(" more)")
+	})
+}
+
+// capMultiline truncates the multiline attrs to HandlerOptions.MaxMultilineBytes,
+// and counts the bytes omitted.  The cut is moved back to the start of a character
+// or escape sequence, so neither is split.
+func (e *encoder) capMultiline() {
+	limit := e.h.opts.MaxMultilineBytes
+	if limit <= 0 || len(e.multilineAttrBuf) <= limit {
+		return
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(e.multilineAttrBuf[cut]) {
+		cut--
+	}
+	if esc := bytes.LastIndexByte(e.multilineAttrBuf[:cut], '\x1b'); esc >= 0 && csiLen(e.multilineAttrBuf[esc:cut]) < 0 {
+		cut = esc
+	}
+	colored := bytes.IndexByte(e.multilineAttrBuf[cut:], '\x1b') >= 0
+	e.omittedMultiline += len(e.multilineAttrBuf) - cut
+	e.multilineAttrBuf = e.multilineAttrBuf[:cut]
+	if colored {
+		// the sequence resetting the colors of the value may have been cut
+		e.multilineAttrBuf.AppendString(string(ResetMod))
+	}
+}
+
+// writeOmittedMultiline writes the trailer summarizing the bytes of the multiline
+// attrs omitted, like "(truncated, 4096 bytes omitted)", on its own line.
+func (e *encoder) writeOmittedMultiline() {
+	e.multilineAttrBuf.AppendByte('\n')
+	e.withColor(&e.multilineAttrBuf, e.h.opts.Theme.Header, func() {
+		e.multilineAttrBuf.AppendString("(truncated, ")
+		e.multilineAttrBuf.AppendInt(int64(e.omittedMultiline))
+		e.multilineAttrBuf.AppendString(" bytes omitted)")
+	})
+}
+
+// normalizeFrom normalizes the carriage returns and tabs in the value written to buf
+// at offset.  See HandlerOptions.NormalizeNewlines and HandlerOptions.TabWidth.
+func (e *encoder) normalizeFrom(buf *buffer, offset int) {
+	b := (*buf)[offset:]
+	crs := e.h.opts.NormalizeNewlines && bytes.IndexByte(b, '\r') >= 0
+	tabs := e.h.opts.TabWidth > 0 && bytes.IndexByte(b, '\t') >= 0 && bytes.IndexByte(b, '\n') >= 0
+	if !crs && !tabs {
+		return
+	}
+	e.scratch = append(e.scratch[:0], b...)
+	*buf = (*buf)[:offset]
+	col := 0
+	for i := 0; i < len(e.scratch); i++ {
+		c := e.scratch[i]
+		switch {
+		case c == '\r' && crs:
+			// "\r\n" becomes "\n", and stray carriage returns are dropped
+			continue
+		case c == '\t' && tabs:
+			n := e.h.opts.TabWidth - col%e.h.opts.TabWidth
+			buf.Pad(n, ' ')
+			col += n
+			continue
+		case c == '\n':
+			col = 0
+		case c == '\x1b':
+			if n := csiLen(e.scratch[i:]); n > 0 {
+				// colors don't take up columns
+				buf.Append(e.scratch[i : i+n])
+				i += n - 1
+				continue
+			}
+			col++
+		case c&0xc0 != 0x80:
+			// count runes, not the continuation bytes of multibyte runes
+			col++
+		}
+		buf.AppendByte(c)
+	}
+}
+
+// kvSep returns the separator between attr keys and values.
+func (e *encoder) kvSep() string {
+	if e.pretty {
+		return e.h.prettyKVSep
+	}
+	return e.h.opts.KeyValueSeparator
+}
+
+// wrapLines adds prefix to the start, and suffix to the end, of each line of the
+// encoded record in buf.  The suffix is inserted before the newline.
+func (e *encoder) wrapLines(prefix, suffix string) {
+	e.scratch = e.scratch[:0]
+	for b := e.buf; len(b) > 0; {
+		line := b
+		nl := false
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			line = b[:i]
+			nl = true
+		}
+		e.scratch.AppendString(prefix)
+		e.scratch.Append(line)
+		e.scratch.AppendString(suffix)
+		if nl {
+			e.scratch.AppendByte('\n')
+			b = b[len(line)+1:]
+		} else {
+			b = b[len(line):]
+		}
+	}
+	e.buf, e.scratch = e.scratch, e.buf
+}
+
+// prefixContinuationLines adds header and prefix to the start of each line of the
+// encoded record in buf, except the first.
+func (e *encoder) prefixContinuationLines(header []byte, prefix string) {
+	first := bytes.IndexByte(e.buf, '\n') + 1
+	e.scratch = append(e.scratch[:0], e.buf[:first]...)
+	for b := e.buf[first:]; len(b) > 0; {
+		line := b
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			line = b[:i+1]
+		}
+		e.scratch.Append(header)
+		e.scratch.AppendString(prefix)
+		e.scratch.Append(line)
+		b = b[len(line):]
+	}
+	e.buf, e.scratch = e.scratch, e.buf
+}
+
 func (e *encoder) withColor(b *buffer, c ANSIMod, f func()) {
 	if c == "" || e.h.opts.NoColor {
 		f()
@@ -326,43 +942,102 @@ func (e *encoder) writeColoredString(w *buffer, s string, c ANSIMod) {
 }
 
 // writeAttr encodes the attr to the attrBuf.  The group will be prepended
-// to the key, joined with a '.'
+// to the key, joined with HandlerOptions.GroupSeparator.
 //
 // returns the offset where the value starts, which may be used by the
 // caller to split the key and value
 func (e *encoder) writeAttr(a slog.Attr, group string) int {
 	value := a.Value
 
-	e.attrBuf.AppendByte(' ')
+	e.writeAttrSep()
 	e.withColor(&e.attrBuf, e.h.opts.Theme.AttrKey, func() {
-		if group != "" {
-			e.attrBuf.AppendString(group)
-			e.attrBuf.AppendByte('.')
+		l := len(e.attrBuf)
+		if alias, ok := e.keyAlias(group, a.Key); ok {
+			e.attrBuf.AppendString(alias)
+		} else {
+			if group != "" && e.groupDepth == 0 {
+				e.attrBuf.AppendString(group)
+				e.attrBuf.AppendString(e.h.opts.GroupSeparator)
+			}
+			e.attrBuf.AppendString(a.Key)
 		}
-		e.attrBuf.AppendString(a.Key)
-		e.attrBuf.AppendByte('=')
+		e.sanitizeKeyFrom(&e.attrBuf, l)
+		e.attrBuf.AppendString(e.kvSep())
 	})
 
 	style := e.h.opts.Theme.AttrValue
-	if value.Kind() == slog.KindAny {
+	switch value.Kind() {
+	case slog.KindAny:
 		if _, ok := value.Any().(error); ok {
 			style = e.h.opts.Theme.AttrValueError
 		}
+	case slog.KindBool:
+		// themes without the bool styles fall back to AttrValue
+		if value.Bool() && e.h.opts.Theme.AttrValueTrue != "" {
+			style = e.h.opts.Theme.AttrValueTrue
+		} else if !value.Bool() && e.h.opts.Theme.AttrValueFalse != "" {
+			style = e.h.opts.Theme.AttrValueFalse
+		}
+	}
+	if s, ok := e.thresholdStyle(group, a); ok {
+		style = s
+	}
+	if format, ok := e.keyFormat(group, a.Key); ok {
+		value = slog.StringValue(fmt.Sprintf(format, value.Any()))
 	}
 	valOffset := len(e.attrBuf)
-	e.writeColoredValue(&e.attrBuf, value, style)
+	if text, ok := e.diffText(value); ok {
+		e.writeDiff(&e.attrBuf, text, style)
+	} else if e.h.opts.HighlightAttrValues {
+		e.writeHighlightedValue(&e.attrBuf, value, style, true)
+	} else {
+		e.writeAttrValue(&e.attrBuf, value, style)
+	}
 	return valOffset
 }
 
+// keyAlias returns the alias of the key in the group, if it has one.
+// See HandlerOptions.KeyAliases.
+func (e *encoder) keyAlias(group, key string) (string, bool) {
+	return e.lookupKey(e.h.keyAliases, group, key)
+}
+
+// keyFormat returns the format of the values of the key in the group, if it has
+// one.  See HandlerOptions.KeyFormats.
+func (e *encoder) keyFormat(group, key string) (string, bool) {
+	return e.lookupKey(e.h.keyFormats, group, key)
+}
+
+// lookupKey looks up the key in the group in m, whose keys are qualified by their
+// groups, joined with the GroupSeparator.
+func (e *encoder) lookupKey(m map[string]string, group, key string) (string, bool) {
+	if len(m) == 0 {
+		return "", false
+	}
+	if group == "" {
+		v, ok := m[key]
+		return v, ok
+	}
+	e.scratch = append(e.scratch[:0], group...)
+	e.scratch = append(e.scratch, e.h.opts.GroupSeparator...)
+	e.scratch = append(e.scratch, key...)
+	v, ok := m[string(e.scratch)]
+	return v, ok
+}
+
 func (e *encoder) writeMultilineAttr(key, group string, value []byte) {
 	e.multilineAttrBuf.AppendByte('\n')
 	e.withColor(&e.multilineAttrBuf, e.h.opts.Theme.AttrKey, func() {
 		e.multilineAttrBuf.AppendString("=== ")
-		if group != "" {
-			e.multilineAttrBuf.AppendString(group)
-			e.multilineAttrBuf.AppendByte('.')
+		if alias, ok := e.keyAlias(group, key); ok {
+			e.multilineAttrBuf.AppendString(alias)
+		} else {
+			if group != "" {
+				e.multilineAttrBuf.AppendString(group)
+				e.multilineAttrBuf.AppendString(e.h.opts.GroupSeparator)
+			}
+			e.multilineAttrBuf.AppendString(key)
 		}
-		e.multilineAttrBuf.AppendString(key)
 		e.multilineAttrBuf.AppendString(" ===\n")
 	})
 	e.multilineAttrBuf.Append(value)
@@ -377,7 +1052,7 @@ func (e *encoder) writeValue(buf *buffer, value slog.Value) {
 	case slog.KindFloat64:
 		buf.AppendFloat(value.Float64())
 	case slog.KindTime:
-		buf.AppendTime(value.Time(), e.h.opts.TimeFormat)
+		e.appendTime(buf, value.Time())
 	case slog.KindUint64:
 		buf.AppendUint(value.Uint64())
 	case slog.KindDuration:
@@ -385,6 +1060,10 @@ func (e *encoder) writeValue(buf *buffer, value slog.Value) {
 	case slog.KindAny:
 		switch v := value.Any().(type) {
 		case error:
+			if errs, ok := joinedErrors(v); ok {
+				e.writeErrorList(buf, errs)
+				return
+			}
 			if _, ok := v.(fmt.Formatter); ok {
 				fmt.Fprintf(buf, "%+v", v)
 			} else {
@@ -394,11 +1073,39 @@ func (e *encoder) writeValue(buf *buffer, value slog.Value) {
 		case fmt.Stringer:
 			buf.AppendString(v.String())
 			return
+		case *http.Request:
+			if e.h.opts.RenderHTTP && v != nil {
+				e.writeRequest(buf, v)
+				return
+			}
+		case *http.Response:
+			if e.h.opts.RenderHTTP && v != nil {
+				e.writeResponse(buf, v)
+				return
+			}
+		case []byte:
+			if e.h.opts.BytesFormat != BytesDefault {
+				e.writeBytes(buf, v)
+				return
+			}
 		case *slog.Source:
-			buf.AppendString(trimmedPath(v.File, cwd, e.h.opts.TruncateSourcePath))
+			buf.AppendString(trimmedPath(v.File, cwd, e.h.opts.SourceTrimPrefixes, e.h.opts.TruncateSourcePath))
 			buf.AppendByte(':')
 			buf.AppendInt(int64(v.Line))
 			return
+		case tableValue:
+			if rows, ok := tableRows(v.rows); ok {
+				e.writeTable(buf, rows)
+			} else {
+				e.writeValue(buf, slog.AnyValue(v.rows))
+			}
+			return
+		}
+		if e.h.opts.RenderTables {
+			if rows, ok := tableRows(value.Any()); ok {
+				e.writeTable(buf, rows)
+				return
+			}
 		}
 		fallthrough
 	case slog.KindString:
@@ -408,20 +1115,107 @@ func (e *encoder) writeValue(buf *buffer, value slog.Value) {
 	}
 }
 
+// joinedErrors returns the errors joined in err, if it joins several errors, like
+// the errors created by errors.Join.
+func joinedErrors(err error) ([]error, bool) {
+	j, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return nil, false
+	}
+	errs := j.Unwrap()
+	return errs, len(errs) > 1
+}
+
+// writeHeaderValue writes a header field's value, like writeValue, but keeps
+// joined errors on the header line, separated like "a; b".
+func (e *encoder) writeHeaderValue(buf *buffer, value slog.Value) {
+	if value.Kind() != slog.KindAny {
+		e.writeValue(buf, value)
+		return
+	}
+	if err, ok := value.Any().(error); ok {
+		if errs, ok := joinedErrors(err); ok {
+			first := true
+			for _, err := range errs {
+				if err == nil {
+					continue
+				}
+				if !first {
+					buf.AppendString("; ")
+				}
+				first = false
+				e.writeHeaderValue(buf, slog.AnyValue(err))
+			}
+			return
+		}
+	}
+	e.writeValue(buf, value)
+}
+
+// writeErrorList writes each of the errors on its own line, like "- failed", with
+// the following lines of multiline errors, and of nested joined errors, indented
+// below it.  The newlines move the value to a block below the record.
+func (e *encoder) writeErrorList(buf *buffer, errs []error) {
+	first := true
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if !first {
+			buf.AppendByte('\n')
+		}
+		first = false
+		buf.AppendString("- ")
+		start := len(*buf)
+		e.writeValue(buf, slog.AnyValue(err))
+		if bytes.IndexByte((*buf)[start:], '\n') < 0 {
+			continue
+		}
+		e.scratch = append(e.scratch[:0], (*buf)[start:]...)
+		*buf = (*buf)[:start]
+		for i, line := range bytes.Split(e.scratch, []byte{'\n'}) {
+			if i > 0 {
+				buf.AppendString("\n  ")
+			}
+			buf.Append(line)
+		}
+	}
+}
+
 func (e *encoder) writeColoredValue(buf *buffer, value slog.Value, style ANSIMod) {
 	e.withColor(buf, style, func() {
+		l := len(*buf)
 		e.writeValue(buf, value)
+		e.quoteFrom(buf, l)
 	})
 }
 
-func trimmedPath(path string, cwd string, truncate int) string {
+// writeAttrValue writes the value of an attr, like writeColoredValue, quoting it
+// with quoteValueFrom.
+func (e *encoder) writeAttrValue(buf *buffer, value slog.Value, style ANSIMod) {
+	e.withColor(buf, style, func() {
+		l := len(*buf)
+		e.writeValue(buf, value)
+		e.quoteValueFrom(buf, l)
+	})
+}
+
+func trimmedPath(path string, cwd string, prefixes []string, truncate int) string {
 	path = filepath.ToSlash(path)
+	trimmed := false
+	for _, prefix := range prefixes {
+		if prefix != "" && strings.HasPrefix(path, prefix) {
+			path, trimmed = path[len(prefix):], true
+			break
+		}
+	}
+
 	// if the file path appears to be under the current
 	// working directory, then we're probably running
 	// in a dev environment, and we can show the
 	// path of the source file relative to the
 	// working directory
-	if cwd != "" && strings.HasPrefix(path, cwd) {
+	if !trimmed && cwd != "" && strings.HasPrefix(path, cwd) {
 		if ff, err := filepath.Rel(cwd, path); err == nil {
 			path = filepath.ToSlash(ff)
 		}
@@ -445,6 +1239,9 @@ func trimmedPath(path string, cwd string, truncate int) string {
 	for idx := len(path); truncate > 0; truncate-- {
 		idx = strings.LastIndexByte(path[:idx], '/')
 		if idx == -1 {
+			// a relative path, e.g. one trimmed by SourceTrimPrefixes, with no
+			// more segments than truncate is printed whole
+			start = 0
 			break
 		}
 		start = idx + 1
@@ -0,0 +1,28 @@
+package console
+
+import (
+	"log/slog"
+	"os"
+	"slices"
+)
+
+// envAttrs returns the attrs of HandlerOptions.EnvAttrs, sorted by key, with the
+// values of the environment variables which are set.
+func envAttrs(vars map[string]string) []slog.Attr {
+	if len(vars) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var attrs []slog.Attr
+	for _, k := range keys {
+		if v, ok := os.LookupEnv(vars[k]); ok {
+			attrs = append(attrs, slog.String(k, v))
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,42 @@
+package console
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+func TestHandler_EnvAttrs(t *testing.T) {
+	t.Setenv("CONSOLE_TEST_DEPLOY_ENV", "staging")
+	t.Setenv("CONSOLE_TEST_REGION", "eu-west-1")
+
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{
+		NoColor:      true,
+		HeaderFormat: "%[env]h %m %a",
+		EnvAttrs: map[string]string{
+			"region":  "CONSOLE_TEST_REGION",
+			"env":     "CONSOLE_TEST_DEPLOY_ENV",
+			"missing": "CONSOLE_TEST_MISSING",
+		},
+	})
+	// the variables are only read once
+	t.Setenv("CONSOLE_TEST_REGION", "us-east-1")
+
+	l := slog.New(h)
+	l.Info("msg", "a", 1)
+	l.WithGroup("g").Info("grouped", "b", 2)
+	AssertEqual(t, "staging msg region=eu-west-1 a=1\nstaging grouped region=eu-west-1 g.b=2\n", buf.String())
+
+	// WithOptions reads them again, instead of adding them twice
+	buf.Reset()
+	l = slog.New(h.WithOptions(func(o *HandlerOptions) { o.HeaderFormat = "%m %a" }))
+	l.Info("msg")
+	AssertEqual(t, "msg env=staging region=us-east-1\n", buf.String())
+
+	// pretty mode encodes them again
+	buf.Reset()
+	l = slog.New(h.WithOptions(func(o *HandlerOptions) { o.HeaderFormat = "%m %a"; o.Pretty = true }))
+	l.Info("msg")
+	AssertEqual(t, "msg\n  env: staging\n  region: us-east-1\n", buf.String())
+}
@@ -0,0 +1,429 @@
+package console
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// FieldKind is the kind of a FormatField.
+type FieldKind int
+
+const (
+	// FieldLiteral is fixed text, like "[" or ">".
+	FieldLiteral FieldKind = iota
+	// FieldSpace separates fields.  Consecutive spaces are merged into one.
+	FieldSpace
+	// FieldTimestamp is the %t verb.
+	FieldTimestamp
+	// FieldLevel is the %l verb, the abbreviated level.
+	FieldLevel
+	// FieldLevelFull is the %L verb, the full level.
+	FieldLevelFull
+	// FieldMessage is the %m verb.
+	FieldMessage
+	// FieldSource is the %s verb.
+	FieldSource
+	// FieldName is the %N verb, the logger name.
+	FieldName
+	// FieldAttrs is the %a verb, or %[group]a.
+	FieldAttrs
+	// FieldHeader is the %[key]h verb.
+	FieldHeader
+	// FieldError is the %e verb.
+	FieldError
+	// FieldGroup is a group of fields, between %{ and %}.
+	FieldGroup
+	// FieldWorker is the %w verb, the worker label.
+	FieldWorker
+)
+
+var fieldKindNames = [...]string{
+	FieldLiteral:   "Literal",
+	FieldSpace:     "Space",
+	FieldTimestamp: "Timestamp",
+	FieldLevel:     "Level",
+	FieldLevelFull: "LevelFull",
+	FieldMessage:   "Message",
+	FieldSource:    "Source",
+	FieldName:      "Name",
+	FieldAttrs:     "Attrs",
+	FieldHeader:    "Header",
+	FieldError:     "Error",
+	FieldGroup:     "Group",
+	FieldWorker:    "Worker",
+}
+
+// String returns the name of the kind, like "Header".
+func (k FieldKind) String() string {
+	if k >= 0 && int(k) < len(fieldKindNames) {
+		return fieldKindNames[k]
+	}
+	return "FieldKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// FormatField is a field of a Format.  Which of its properties apply depends on
+// its Kind.
+type FormatField struct {
+	Kind FieldKind
+
+	// Text is the text of a FieldLiteral.
+	Text string
+
+	// Keys are the keys of a FieldHeader, in order of preference, or the group
+	// of a FieldAttrs, if any.  Keys in groups are qualified by the groups,
+	// joined with dots, like "http.method".
+	Keys []string
+
+	// Width is the width of a FieldHeader, FieldError, or FieldWorker, or 0.
+	Width int
+
+	// RightAlign right-aligns a FieldHeader, FieldError, or FieldWorker within
+	// its Width.
+	RightAlign bool
+
+	// Center centers a FieldHeader, FieldError, or FieldWorker within its Width.
+	// It is ignored if RightAlign is set.
+	Center bool
+
+	// PadChar is the character a FieldHeader, FieldError, or FieldWorker is padded
+	// to its Width with, or 0 for spaces.
+	PadChar byte
+
+	// Upper, Lower, and Basename are the transformations of a FieldHeader:
+	// upper case, lower case, and the part after the last "/".
+	Upper, Lower, Basename bool
+
+	// Style is the name of the Theme style of a FieldGroup, like "source",
+	// or "" for the Header style.
+	Style string
+
+	// Fields are the fields in a FieldGroup.
+	Fields []FormatField
+}
+
+// Format is a parsed HeaderFormat.  Formats can be inspected and modified, e.g. to
+// validate formats from configuration files, or to add fields to them, and turned
+// back into a HeaderFormat with String.
+type Format struct {
+	Fields []FormatField
+
+	// noSpace is set by NoSpace
+	noSpace bool
+}
+
+// NewFormat returns an empty Format, whose methods add fields to it, separated
+// by spaces, as an alternative to writing a HeaderFormat string:
+//
+//	console.NewFormat().Timestamp().Level().Header("logger", console.Width(10)).Message().Attrs()
+//
+// is the same as "%t %l %[logger]10h %m %a".  See HandlerOptions.Format.
+func NewFormat() *Format {
+	return &Format{}
+}
+
+// HeaderOption configures a header field added with Format.Header, Format.Error, or
+// Format.Worker.
+type HeaderOption func(f *FormatField)
+
+// Width sets the width of a header, like %[key]10h.
+func Width(n int) HeaderOption {
+	return func(f *FormatField) { f.Width = n }
+}
+
+// RightAlign right-aligns a header within its width, like %[key]-10h.
+func RightAlign() HeaderOption {
+	return func(f *FormatField) { f.RightAlign = true }
+}
+
+// Center centers a header within its width, like %[key]^10h.
+func Center() HeaderOption {
+	return func(f *FormatField) { f.Center = true }
+}
+
+// PadChar pads a header to its width with the character c, instead of spaces, like
+// %[key]10.h.  c must be ASCII punctuation, other than "%", "-", "^", "{", and "}".
+func PadChar(c byte) HeaderOption {
+	return func(f *FormatField) { f.PadChar = c }
+}
+
+// Upper prints a header in upper case, like %[key]Uh.
+func Upper() HeaderOption {
+	return func(f *FormatField) { f.Upper = true }
+}
+
+// Lower prints a header in lower case, like %[key]uh.
+func Lower() HeaderOption {
+	return func(f *FormatField) { f.Lower = true }
+}
+
+// Basename prints the part of a header after the last "/", like %[key]Bh.
+func Basename() HeaderOption {
+	return func(f *FormatField) { f.Basename = true }
+}
+
+// add appends the field, after a space, unless it's the first field, or
+// NoSpace was called.
+func (f *Format) add(field FormatField) *Format {
+	if len(f.Fields) > 0 && !f.noSpace && f.Fields[len(f.Fields)-1].Kind != FieldSpace {
+		f.Fields = append(f.Fields, FormatField{Kind: FieldSpace})
+	}
+	f.Fields = append(f.Fields, field)
+	f.noSpace = false
+	return f
+}
+
+// NoSpace joins the next field to the previous one, without a space, e.g.
+// NewFormat().Level().NoSpace().Literal(":") is "%l:".
+func (f *Format) NoSpace() *Format {
+	f.noSpace = true
+	return f
+}
+
+// Literal adds fixed text, like ">".
+func (f *Format) Literal(text string) *Format {
+	return f.add(FormatField{Kind: FieldLiteral, Text: text})
+}
+
+// Timestamp adds the timestamp, like %t.
+func (f *Format) Timestamp() *Format {
+	return f.add(FormatField{Kind: FieldTimestamp})
+}
+
+// Level adds the abbreviated level, like %l.
+func (f *Format) Level() *Format {
+	return f.add(FormatField{Kind: FieldLevel})
+}
+
+// LevelFull adds the full level, like %L.
+func (f *Format) LevelFull() *Format {
+	return f.add(FormatField{Kind: FieldLevelFull})
+}
+
+// Message adds the message, like %m.
+func (f *Format) Message() *Format {
+	return f.add(FormatField{Kind: FieldMessage})
+}
+
+// Source adds the source, like %s.
+func (f *Format) Source() *Format {
+	return f.add(FormatField{Kind: FieldSource})
+}
+
+// Name adds the logger name, like %N.
+func (f *Format) Name() *Format {
+	return f.add(FormatField{Kind: FieldName})
+}
+
+// Attrs adds the attributes, like %a.  If group is set, only the attributes
+// in the group are added, like %[group]a.
+func (f *Format) Attrs(group ...string) *Format {
+	return f.add(FormatField{Kind: FieldAttrs, Keys: group[:min(len(group), 1)]})
+}
+
+// Header adds a header with the key, like %[key]h.  The key can list several keys,
+// separated by "|", like "request_id|trace_id".
+func (f *Format) Header(key string, opts ...HeaderOption) *Format {
+	field := FormatField{Kind: FieldHeader, Keys: strings.Split(key, "|")}
+	for _, opt := range opts {
+		opt(&field)
+	}
+	return f.add(field)
+}
+
+// Error adds the error header, like %e.
+func (f *Format) Error(opts ...HeaderOption) *Format {
+	field := FormatField{Kind: FieldError}
+	for _, opt := range opts {
+		opt(&field)
+	}
+	return f.add(field)
+}
+
+// Worker adds the worker label, like %w.
+func (f *Format) Worker(opts ...HeaderOption) *Format {
+	field := FormatField{Kind: FieldWorker}
+	for _, opt := range opts {
+		opt(&field)
+	}
+	return f.add(field)
+}
+
+// Group adds the fields of inner as a group, like %{...%}, which is omitted if
+// all its fields are.  style is the name of the Theme style of the group's
+// literals, like "source", or "" for the Header style.
+func (f *Format) Group(style string, inner *Format) *Format {
+	return f.add(FormatField{Kind: FieldGroup, Style: style, Fields: inner.Fields})
+}
+
+// ParseHeaderFormat parses a HeaderFormat.  See HandlerOptions.HeaderFormat for the
+// syntax.  Unlike NewHandler, which prints errors in the format, like
+// "%!x(INVALID_VERB)", in the header of each record, ParseHeaderFormat returns an
+// error for invalid verbs and modifiers, and for unbalanced groups.
+func ParseHeaderFormat(s string) (*Format, error) {
+	fields, _ := parseFormat(s, Theme{})
+	// the group being built is the last element of stack
+	stack := []*[]FormatField{{}}
+	add := func(f FormatField) {
+		cur := stack[len(stack)-1]
+		if f.Kind == FieldLiteral && len(*cur) > 0 && (*cur)[len(*cur)-1].Kind == FieldLiteral {
+			// merge the literals split by %%
+			(*cur)[len(*cur)-1].Text += f.Text
+			return
+		}
+		*cur = append(*cur, f)
+	}
+
+	for _, f := range fields {
+		switch f := f.(type) {
+		case string:
+			if strings.HasPrefix(f, "%!") {
+				return nil, errors.New("console: invalid header format " + strconv.Quote(s) + ": " + f)
+			}
+			add(FormatField{Kind: FieldLiteral, Text: f})
+		case spacer:
+			add(FormatField{Kind: FieldSpace})
+		case timestampField:
+			add(FormatField{Kind: FieldTimestamp})
+		case levelField:
+			if f.abbreviated {
+				add(FormatField{Kind: FieldLevel})
+			} else {
+				add(FormatField{Kind: FieldLevelFull})
+			}
+		case messageField:
+			add(FormatField{Kind: FieldMessage})
+		case sourceField:
+			add(FormatField{Kind: FieldSource})
+		case nameField:
+			add(FormatField{Kind: FieldName})
+		case attrsField:
+			af := FormatField{Kind: FieldAttrs}
+			if f.group != "" {
+				af.Keys = []string{f.group}
+			}
+			add(af)
+		case headerField:
+			hf := FormatField{
+				Kind:       FieldHeader,
+				Width:      f.width,
+				RightAlign: f.rightAlign,
+				Center:     f.center,
+				PadChar:    f.pad,
+				Upper:      f.transform&transformUpper != 0,
+				Lower:      f.transform&transformLower != 0,
+				Basename:   f.transform&transformBase != 0,
+			}
+			switch {
+			case f.errors:
+				hf.Kind = FieldError
+			case f.worker:
+				hf.Kind = FieldWorker
+			}
+			for _, k := range f.keys {
+				if k.groupPrefix != "" {
+					hf.Keys = append(hf.Keys, k.groupPrefix+"."+k.key)
+				} else {
+					hf.Keys = append(hf.Keys, k.key)
+				}
+			}
+			add(hf)
+		case groupOpen:
+			add(FormatField{Kind: FieldGroup, Style: f.style})
+			cur := stack[len(stack)-1]
+			stack = append(stack, &(*cur)[len(*cur)-1].Fields)
+		case groupClose:
+			if len(stack) == 1 {
+				return nil, errors.New("console: invalid header format " + strconv.Quote(s) + ": %} without %{")
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) > 1 {
+		return nil, errors.New("console: invalid header format " + strconv.Quote(s) + ": %{ without %}")
+	}
+	return &Format{Fields: *stack[0]}, nil
+}
+
+// String returns the HeaderFormat of the Format.
+func (f *Format) String() string {
+	var sb strings.Builder
+	writeFormatFields(&sb, f.Fields)
+	return sb.String()
+}
+
+func writeFormatFields(sb *strings.Builder, fields []FormatField) {
+	for _, f := range fields {
+		switch f.Kind {
+		case FieldLiteral:
+			sb.WriteString(strings.ReplaceAll(f.Text, "%", "%%"))
+		case FieldSpace:
+			sb.WriteByte(' ')
+		case FieldTimestamp:
+			sb.WriteString("%t")
+		case FieldLevel:
+			sb.WriteString("%l")
+		case FieldLevelFull:
+			sb.WriteString("%L")
+		case FieldMessage:
+			sb.WriteString("%m")
+		case FieldSource:
+			sb.WriteString("%s")
+		case FieldName:
+			sb.WriteString("%N")
+		case FieldAttrs:
+			sb.WriteByte('%')
+			writeFormatKeys(sb, f.Keys)
+			sb.WriteByte('a')
+		case FieldHeader, FieldError, FieldWorker:
+			sb.WriteByte('%')
+			if f.Kind == FieldHeader {
+				writeFormatKeys(sb, f.Keys)
+			}
+			if f.RightAlign {
+				sb.WriteByte('-')
+			} else if f.Center {
+				sb.WriteByte('^')
+			}
+			if f.Width > 0 {
+				sb.WriteString(strconv.Itoa(f.Width))
+				if f.PadChar != 0 && f.PadChar != ' ' {
+					sb.WriteByte(f.PadChar)
+				}
+			}
+			if f.Kind == FieldHeader {
+				if f.Upper {
+					sb.WriteByte('U')
+				}
+				if f.Lower {
+					sb.WriteByte('u')
+				}
+				if f.Basename {
+					sb.WriteByte('B')
+				}
+				sb.WriteByte('h')
+			} else if f.Kind == FieldWorker {
+				sb.WriteByte('w')
+			} else {
+				sb.WriteByte('e')
+			}
+		case FieldGroup:
+			sb.WriteByte('%')
+			if f.Style != "" {
+				sb.WriteString("(" + f.Style + ")")
+			}
+			sb.WriteByte('{')
+			writeFormatFields(sb, f.Fields)
+			sb.WriteString("%}")
+		}
+	}
+}
+
+func writeFormatKeys(sb *strings.Builder, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	sb.WriteByte('[')
+	sb.WriteString(strings.Join(keys, "|"))
+	sb.WriteByte(']')
+}
@@ -0,0 +1,132 @@
+package console
+
+import (
+	"fmt"
+	"log/slog"
+	"testing"
+)
+
+func TestParseHeaderFormat(t *testing.T) {
+	f, err := ParseHeaderFormat("%t  [%L] %(source){%[logger|name]-10Uh %s >%} %e %[http]a 100%% %m %a")
+	AssertNoError(t, err)
+
+	AssertEqual(t, 17, len(f.Fields))
+	var kinds []FieldKind
+	for _, ff := range f.Fields {
+		kinds = append(kinds, ff.Kind)
+	}
+	AssertEqual(t,
+		"[Timestamp Space Literal LevelFull Literal Space Group Space Error Space Attrs Space Literal Space Message Space Attrs]",
+		fmt.Sprint(kinds))
+
+	group := f.Fields[6]
+	AssertEqual(t, "source", group.Style)
+	AssertEqual(t, 5, len(group.Fields))
+	header := group.Fields[0]
+	AssertEqual(t, FieldHeader, header.Kind)
+	AssertEqual(t, "[logger name]", fmt.Sprint(header.Keys))
+	AssertEqual(t, 10, header.Width)
+	AssertEqual(t, true, header.RightAlign)
+	AssertEqual(t, true, header.Upper)
+	AssertEqual(t, false, header.Lower)
+	AssertEqual(t, FieldSource, group.Fields[2].Kind)
+	AssertEqual(t, ">", group.Fields[4].Text)
+
+	AssertEqual(t, "[http]", fmt.Sprint(f.Fields[10].Keys))
+	AssertEqual(t, "100%", f.Fields[12].Text)
+
+	// whitespace is normalized, like NewHandler does
+	AssertEqual(t, "%t [%L] %(source){%[logger|name]-10Uh %s >%} %e %[http]a 100%% %m %a", f.String())
+}
+
+func TestParseHeaderFormat_RoundTrip(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"%t %l %m",
+		"%t %l %[source]h > %m",
+		"%L %[a.b]5uBh %-3e",
+		"%{%{[%t]%}%} %N %m",
+		"%[g]a%a",
+		"%%%m%%",
+		"%-8w %m",
+		"%[a]^10.h %^4*e %-3_w",
+	} {
+		f, err := ParseHeaderFormat(s)
+		AssertNoError(t, err)
+		AssertEqual(t, s, f.String())
+	}
+}
+
+func TestParseHeaderFormat_Errors(t *testing.T) {
+	tests := map[string]string{
+		"%x":           `console: invalid header format "%x": %!x(INVALID_VERB)`,
+		"%[key]":       `console: invalid header format "%[key]": %!(MISSING_VERB)`,
+		"%[key]m":      `console: invalid header format "%[key]m": %![(INVALID_MODIFIER)m`,
+		"%(bogus){%}":  `console: invalid header format "%(bogus){%}": %!{(bogus)(INVALID_STYLE_MODIFIER)`,
+		"%{%m":         `console: invalid header format "%{%m": %{ without %}`,
+		"%m %}":        `console: invalid header format "%m %}": %} without %{`,
+		"%[a":          `console: invalid header format "%[a": %![a(MISSING_CLOSING_BRACKET)`,
+		"%t %[]h":      `console: invalid header format "%t %[]h": %!h(MISSING_HEADER_NAME)`,
+		"%5m":          `console: invalid header format "%5m": %!5(INVALID_MODIFIER)m`,
+		"%^m":          `console: invalid header format "%^m": %!^(INVALID_MODIFIER)m`,
+		"%Uw":          `console: invalid header format "%Uw": %!U(INVALID_VERB)`,
+		"%t %l %m %":   `console: invalid header format "%t %l %m %": %!(MISSING_VERB)`,
+		"%t %l %m %U%": `console: invalid header format "%t %l %m %U%": %!U(INVALID_VERB)`,
+	}
+	for s, want := range tests {
+		f, err := ParseHeaderFormat(s)
+		if err == nil {
+			t.Fatalf("expected an error for %q, got %v", s, f)
+		}
+		AssertEqual(t, want, err.Error())
+	}
+}
+
+func TestFieldKind_String(t *testing.T) {
+	AssertEqual(t, "Header", FieldHeader.String())
+	AssertEqual(t, "FieldKind(99)", FieldKind(99).String())
+}
+
+func TestNewFormat(t *testing.T) {
+	f := NewFormat().Timestamp().Level().Header("logger", Width(10)).Message().Attrs()
+	AssertEqual(t, "%t %l %[logger]10h %m %a", f.String())
+
+	f = NewFormat().
+		Literal("[").NoSpace().LevelFull().NoSpace().Literal("]").
+		Group("source", NewFormat().Header("request_id|trace_id", RightAlign(), Width(8), Upper(), Lower(), Basename()).Source().Literal(">")).
+		Error(Width(3)).
+		Worker(RightAlign(), Width(8)).
+		Name().
+		Attrs("http").
+		Message().
+		Literal("100%")
+	AssertEqual(t, "[%L] %(source){%[request_id|trace_id]-8UuBh %s >%} %3e %-8w %N %[http]a %m 100%%", f.String())
+
+	parsed, err := ParseHeaderFormat(f.String())
+	AssertNoError(t, err)
+	AssertEqual(t, f.String(), parsed.String())
+
+	f = NewFormat().Header("logger", Center(), Width(10), PadChar('.')).Worker(Center(), PadChar('-'))
+	AssertEqual(t, "%[logger]^10.h %^w", f.String())
+}
+
+func TestHandler_Format(t *testing.T) {
+	tests := []handlerTest{
+		{
+			name:  "builder",
+			opts:  HandlerOptions{Format: NewFormat().Level().Header("logger", Width(6)).Message().Attrs()},
+			attrs: []slog.Attr{slog.String("logger", "main"), slog.Int("size", 1)},
+			want:  "INF main   with headers size=1\n",
+		},
+		{
+			name: "overrides HeaderFormat",
+			opts: HandlerOptions{HeaderFormat: "%L %m", Format: NewFormat().Message().NoSpace().Literal("!")},
+			want: "with headers!\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = true
+		test.msg = "with headers"
+		t.Run(test.name, test.run)
+	}
+}
@@ -3,6 +3,7 @@ package console
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -11,6 +12,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ansel1/console-slog/internal"
@@ -37,11 +39,18 @@ type HandlerOptions struct {
 	// of the log statement and add a SourceKey attribute to the output.
 	AddSource bool
 
+	// CallerSkip is the number of additional stack frames to skip when reporting the
+	// source of records, so logging helpers, which wrap the logger, report the
+	// file:line of their callers, rather than their own.  See also
+	// [Handler.WithCallerSkip].
+	CallerSkip int
+
 	// Level reports the minimum record level that will be logged.
 	// The handler discards records with lower levels.
 	// If Level is nil, the handler assumes LevelInfo.
 	// The handler calls Level.Level for each record processed;
-	// to adjust the minimum level dynamically, use a LevelVar.
+	// to adjust the minimum level dynamically, use a LevelVar, or
+	// call Handler.SetLevel.
 	Level slog.Leveler
 
 	// Disable colorized output
@@ -50,6 +59,42 @@ type HandlerOptions struct {
 	// TimeFormat is the format used for time.DateTime
 	TimeFormat string
 
+	// FormatTime, if set, formats the record's timestamp, and time attribute values,
+	// instead of TimeFormat.  It should append the formatted time to buf and return
+	// the extended buffer, like time.Time.AppendFormat.  For example, to print
+	// milliseconds since the epoch:
+	//
+	//	FormatTime: func(buf []byte, t time.Time) []byte {
+	//		return strconv.AppendInt(buf, t.UnixMilli(), 10)
+	//	},
+	FormatTime func(buf []byte, t time.Time) []byte
+
+	// RelativeTime prints the record's timestamp as the time elapsed since the
+	// handler was created, like "+00:03.214", or "+1:02:03.214" after an hour,
+	// instead of the wall clock time.  This is often more useful for CLI tools and
+	// benchmarks.  Handlers derived with WithAttrs and WithGroup share the start time.
+	// Time attribute values are still formatted with TimeFormat.
+	RelativeTime bool
+
+	// TimeDelta appends the time elapsed since the previous record to the timestamp,
+	// like "15:04:05 (+12ms)", which makes slow steps easy to spot.  The previous record
+	// is the last one logged by this handler, or any handler derived from it via
+	// WithAttrs and WithGroup.
+	TimeDelta bool
+
+	// DateRules writes a rule labeled with the date, like "── 2024-05-07 ───", before
+	// the first record, and whenever the date of the records changes, so short
+	// TimeFormats, like time.Kitchen, stay unambiguous in long-running sessions.  The
+	// rules are styled like those of WriteRule.  Handlers derived with WithAttrs and
+	// WithGroup share the date of the previous record.
+	DateRules bool
+
+	// Now, if set, is the clock used to timestamp records whose time is zero, which
+	// would otherwise be printed without a timestamp, and to get the start time of
+	// RelativeTime.  A fixed clock makes the output byte-for-byte reproducible,
+	// e.g. in tests and demos.
+	Now func() time.Time
+
 	// Theme defines the colorized output using ANSI escape sequences
 	Theme Theme
 
@@ -67,6 +112,13 @@ type HandlerOptions struct {
 	//     ...etc
 	TruncateSourcePath int
 
+	// SourceTrimPrefixes are removed from the start of source file paths, before
+	// TruncateSourcePath is applied, so paths are short and the same on every
+	// machine, e.g. "/home/ci/build/", or the module cache, like "/root/go/pkg/mod/".
+	// Only the first matching prefix is removed.  Paths under the working directory
+	// which don't match any prefix are still printed relative to it.
+	SourceTrimPrefixes []string
+
 	// HeaderFormat specifies the format of the log header.
 	//
 	// The default format is "%t %l %[source]h > %m".
@@ -78,8 +130,13 @@ type HandlerOptions struct {
 	//	%L	       level (e.g. "INFO")
 	//	%m	       message
 	//	%s	       source (if omitted, source is just handled as an attribute)
+	//	%N	       logger name (see Named; if omitted, the name is handled as an attribute)
 	//	%a	       attributes
+	//	%[group]a  attributes in the given group
 	//	%[key]h	   header with the given key.
+	//	%[k1|k2]h  header with the first of the given keys present
+	//	%e	       error (the first of the ErrorKeys attributes, in the AttrValueError style)
+	//	%w	       worker label (see WithWorkerLabel; if omitted, the label is handled as an attribute)
 	//  %{         group open
 	//  %(style){  group open with style - applies the specified Theme style to any strings in the group
 	//  %}         group close
@@ -92,6 +149,40 @@ type HandlerOptions struct {
 	//
 	//	%[key]10h		// left-aligned, width 10
 	//	%[key]-10h		// right-aligned, width 10
+	//	%[key]^10h		// centered, width 10
+	//
+	// The width can be followed by the character to pad the header with, instead of spaces,
+	// which can be any ASCII punctuation but "%", "-", "^", "{", and "}".  For example:
+	//
+	//	%[key]^10.h		// centered, width 10, padded with dots
+	//	%[key]-8*h		// right-aligned, width 8, padded with asterisks
+	//
+	// Header values can be transformed with the modifiers U, for upper case, u, for lower
+	// case, and B, for the basename, i.e. the part after the last "/".  For example:
+	//
+	//	%[logger]Uh		// upper case
+	//	%[file]B20h		// basename, width 20
+	//
+	// Attributes can be split between several %a verbs, by the group they're in.  %[http]a prints
+	// the attributes in the "http" group, including those added with WithGroup("http"), and %a
+	// prints the rest.  Nested groups are joined with dots, like %[http.request]a.  For example:
+	//
+	//	"%l %[http]a %m %a"
+	//
+	// will print "INF http.method=GET http.path=/ request finished status=200".  In Pretty mode, all the
+	// attributes are printed after the line, as usual.
+	//
+	// A header can list several keys, separated by "|", in order of preference, like
+	// %[request_id|trace_id]h, for services which haven't standardized on one key.  The header
+	// prints the first one present.  The others are printed with the rest of the attributes.
+	//
+	// %e is a header for errors, which are usually the first thing people look for.  It prints
+	// the attribute with the first of the ErrorKeys present, in the AttrValueError style, and
+	// removes it from the end of the line, like %[err]h.  It supports the same modifiers.
+	//
+	// %w is a header for the label of the worker which logged the record, set on the context
+	// with WithWorkerLabel, like "worker-3".  It supports the width and alignment modifiers,
+	// so the labels line up, like %-10w.
 	//
 	// Groups will omit their contents if all the fields in that group are omitted.  For example:
 	//
@@ -125,30 +216,504 @@ type HandlerOptions struct {
 	//	"%% %t %l %m"                      // literal "%", timestamp, level, message
 	//  "%{[%t]%} %{[%l]%} %m"             // timestamp and level in brackets, message, brackets will be omitted if empty
 	HeaderFormat string
+
+	// Format, if set, is used instead of HeaderFormat.  It can be built with
+	// NewFormat, rather than written as a string, or parsed with ParseHeaderFormat.
+	Format *Format
+
+	// ErrorKeys are the keys of the attributes printed by the %e verb of the HeaderFormat, in
+	// order of preference.  Defaults to "err" and "error".
+	ErrorKeys []string
+
+	// TruncationMarker, if set, replaces the end of header values which are truncated
+	// to the width of their field, so readers can tell the value was cut, rather than
+	// being exactly that string.  For example, with "…", %[path]8h prints
+	// "/usr/local/bin" as "/usr/lo…".  If empty, values are truncated without a marker.
+	TruncationMarker string
+
+	// AlignAttrs pads the header of each line so that the attributes start at the same
+	// column as the widest header seen recently.  This makes bursts of similar records
+	// much easier to scan.  The remembered width is shared by all handlers derived from
+	// this one via WithAttrs and WithGroup, and shrinks again after a run of narrower lines.
+	AlignAttrs bool
+
+	// AutoAlign pads the level, logger name, and source columns of the header to the
+	// widest value seen recently in each, like AlignAttrs does for the attributes, so
+	// variable width fields, like %L or %s, line up without hardcoding their widths
+	// in the HeaderFormat.  The widths are shared, and shrink, like AlignAttrs.
+	AutoAlign bool
+
+	// RenderTables renders attribute values which are slices of structs as aligned
+	// tables below the record, as if they had been wrapped with [Table].
+	RenderTables bool
+
+	// HighlightDiffs colors the added and removed lines of multiline string values
+	// which are unified diffs, like the output of "diff -u" or "git diff", with the
+	// Theme's DiffAdded and DiffRemoved styles, as if they had been created with
+	// [Diff].  Ignored if Logfmt or TextQuoting is set, since those quote the diff.
+	HighlightDiffs bool
+
+	// ErrorWriter, if set, receives records at or above ErrorLevel, instead of the
+	// handler's writer.  For example, CLI tools often send warnings and errors to
+	// os.Stderr, and everything else to os.Stdout:
+	//
+	//	console.NewHandler(os.Stdout, &console.HandlerOptions{ErrorWriter: os.Stderr})
+	ErrorWriter io.Writer
+
+	// ErrorLevel is the minimum level of records written to ErrorWriter.
+	// If nil, slog.LevelWarn is used.  Ignored if ErrorWriter is nil.
+	ErrorLevel slog.Leveler
+
+	// Sampling, if set, limits how many similar records are logged.
+	// See [SamplingOptions].  The sampling counters are shared by all the
+	// handlers derived from this one via WithAttrs and WithGroup.
+	Sampling *SamplingOptions
+
+	// CollapseRepeats suppresses consecutive identical records.  Records are identical
+	// if they render the same, ignoring the timestamp.  The first record is written
+	// as usual, and the number of repeats is written as a single summary line, like
+	// "last message repeated 3 times", when a different record arrives, when
+	// RepeatTimeout elapses without another repeat, or when the handler is flushed or
+	// closed.
+	CollapseRepeats bool
+
+	// RepeatTimeout is how long to wait for another repeat before writing the summary
+	// of suppressed records.  If 0, one second is used.
+	RepeatTimeout time.Duration
+
+	// CollectStats counts the records handled, the bytes written, the records dropped
+	// by sampling and suppressed as repeats, and the records written per level.  See
+	// [Handler.Stats].  The counts are shared by all the handlers derived from this one.
+	CollectStats bool
+
+	// Mutex, if set, is held while each record is written.  Handlers which write to
+	// the same destination through different writers (e.g. a wrapper around a shared
+	// connection) can share a Mutex so that whole lines are never interleaved.
+	//
+	// If nil, handlers created by NewHandler with files with the same descriptor (e.g.
+	// os.Stderr) share a lock automatically, and other writers get a lock of their own, shared
+	// only with the handlers derived from it via WithAttrs and WithGroup.
+	Mutex *sync.Mutex
+
+	// MaxBufferSize is the maximum capacity, in bytes, of the buffers which are
+	// reused between records.  Records which need larger buffers, e.g. because of a
+	// huge multiline attribute, still work, but their buffers are released after the
+	// record is written, rather than being held onto for reuse.  If 0, 16KiB is
+	// used.  If negative, buffers of any size are reused.
+	//
+	// The buffers are pooled with those of the other handlers with the same
+	// MaxBufferSize.  Handlers with the default size share a process-wide pool, and
+	// handlers with another size have their own, shared with the handlers derived
+	// from them.
+	//
+	// See also [EncoderPoolStats].
+	MaxBufferSize int
+
+	// MaxMultilineBytes is the maximum number of bytes of the multiline attributes
+	// printed below each record, so a huge value, like a dumped response body, can't
+	// balloon the buffers or flood the console.  The rest are omitted, and summarized
+	// by a trailer like "(truncated, 4096 bytes omitted)".  If 0, 1MiB is used.  If
+	// negative, multiline attributes aren't truncated.
+	MaxMultilineBytes int
+
+	// OnRecord, if set, is called with each record before it is encoded.  It may modify
+	// the record, e.g. to add attributes, or change its message or level.  It's called
+	// after the record passed Enabled and the levels set with SetPackageLevel, but
+	// before sampling.
+	OnRecord func(ctx context.Context, rec *slog.Record)
+
+	// OnEmit, if set, is called with each fully encoded line, including the trailing
+	// newline, just before it is written.  It may modify the bytes of the line in place,
+	// but must not retain the slice after returning.  OnEmit is called for every encoded
+	// record, including repeats later suppressed by CollapseRepeats.
+	OnEmit func(line []byte)
+
+	// AddContextAttrs adds the attributes attached to the context with
+	// [ContextWithAttrs] to each record.  They're added after the attributes from
+	// WithAttrs and before the record's own attributes, and aren't qualified by the
+	// groups from WithGroup, since they usually describe the whole request, rather
+	// than the component logging it.
+	AddContextAttrs bool
+
+	// CorrelationIDKey, if set, adds the correlation ID of the context of each record
+	// as an attribute with this key, which can be printed in the header, e.g. with
+	// "%t %l %[req]h %m %a", so the records of concurrent requests can be told apart.
+	// The ID is the one added to the context with [WithCorrelationID], e.g. by
+	// [CorrelationIDHandler] for HTTP requests, and is shared by all the contexts
+	// derived from it.  Contexts without one, which can be canceled, get a generated
+	// ID until they are done.  It's shared by the contexts derived from them with
+	// values, like ContextWithAttrs, but not by those derived with their own
+	// cancellation, like context.WithTimeout, which get IDs of their own, so add the
+	// ID with WithCorrelationID where the records of a request should match.  Records
+	// logged without a context, or with context.Background(), don't get an ID.
+	CorrelationIDKey string
+
+	// EnvAttrs maps attribute keys to the names of environment variables, like
+	// {"env": "DEPLOY_ENV", "region": "AWS_REGION"}.  The variables are read once, by
+	// NewHandler, and their values are added to every record, like attributes added
+	// with WithAttrs, in the order of their keys.  Variables which aren't set are
+	// skipped.
+	EnvAttrs map[string]string
+
+	// LoggerNameKey is the key of the attribute holding the name of loggers created
+	// with [Named].  If empty, "logger" is used.  The name is printed by the %N verb, or
+	// as an attribute with this key, if the HeaderFormat doesn't include %N.
+	LoggerNameKey string
+
+	// LoggerNameStyles overrides the Theme's LoggerName style for particular loggers.
+	// Keys are logger names, and also match the names nested under them, so the key
+	// "server" styles the loggers "server" and "server.http", unless there is a key for
+	// "server.http" too.
+	LoggerNameStyles map[string]ANSIMod
+
+	// MaxResolveDepth limits how many times attribute values implementing
+	// slog.LogValuer are resolved in a row, and how deeply the groups returned by
+	// LogValuers may be nested.  Values exceeding the limit, and LogValuers which
+	// return themselves, directly or nested in a group, are printed as "!CYCLE",
+	// rather than hanging or overflowing the stack.  If 0, 32 is used.
+	MaxResolveDepth int
+
+	// GroupFormat controls how attributes with group values, e.g. from slog.Group,
+	// are printed.  By default, the group name is prepended to the key of each
+	// attribute in the group, like slog.TextHandler.  The names of groups opened with
+	// WithGroup are always prepended to the keys.  See [GroupFormat].
+	GroupFormat GroupFormat
+
+	// GroupSeparator joins the names of groups, and the keys of the attributes in
+	// them, e.g. "/" prints "http/method=GET".  If empty, "." is used.  Header keys
+	// in the HeaderFormat always use ".", e.g. "%[http.method]h", regardless of
+	// the separator.
+	GroupSeparator string
+
+	// KeyValueSeparator separates the keys and values of attributes, e.g. ": " prints
+	// "method: GET".  If empty, "=" is used.
+	KeyValueSeparator string
+
+	// Logfmt makes the output strictly valid logfmt, so it can still be parsed by log
+	// collectors when it's redirected to a file: values containing spaces, quotes, '=',
+	// newlines, or other control characters are quoted and escaped, and invalid
+	// characters in keys are replaced with '_'.  This applies to the values printed
+	// by the HeaderFormat verbs too, but the HeaderFormat is still responsible for
+	// labeling them, e.g. "time=%t level=%L msg=%m %a", which is the default
+	// HeaderFormat in this mode.  The default TimeFormat is RFC 3339 with milliseconds.
+	// Colors are still printed, unless NoColor is set.  GroupFormat and
+	// KeyValueSeparator are ignored.
+	Logfmt bool
+
+	// TextQuoting quotes and escapes keys and values exactly like [slog.TextHandler]:
+	// those which are empty, or contain spaces, '=', '"', control characters, or
+	// non-printable characters are quoted with [strconv.Quote].  Combined with Logfmt,
+	// it replaces the logfmt quoting, so the output can be parsed by the same
+	// patterns as the output of a slog.TextHandler.  Without Logfmt, only attribute
+	// keys and values are quoted; the headers and the message are printed as is.
+	// HighlightRules are ignored for attribute values.
+	TextQuoting bool
+
+	// JournaldPrefix prefixes each line with the sd-daemon priority of the record's
+	// level, like "<3>" for errors, and disables colors.  When a service's output is
+	// captured by journald, this lets the journal record the severity of each line.
+	// See [IsJournald].
+	JournaldPrefix bool
+
+	// IgnoreCI disables the detection of CI systems.  By default, when [IsCI] reports
+	// the handler is running in CI, whose log viewers often garble ANSI sequences,
+	// colors are disabled, and unless TimeFormat is set, timestamps include the date,
+	// milliseconds, and time zone.
+	IgnoreCI bool
+
+	// ColorAlways prints colors even when they would otherwise be disabled, by
+	// NoColor, CI detection, or JournaldPrefix, e.g. when piping output through
+	// "less -R".  Colors can also be forced on by setting the FORCE_COLOR environment
+	// variable, e.g. in CI, or forced off by setting it to "0" or "false", unless
+	// ColorAlways is set.  FORCE_COLOR doesn't override NoColor or JournaldPrefix.
+	ColorAlways bool
+
+	// HighlightRules style the parts of each message which match their patterns, so
+	// critical phrases, like "connection refused", or IDs, stand out across records:
+	//
+	//	HighlightRules: []console.HighlightRule{
+	//		{Pattern: regexp.MustCompile(`connection refused`), Style: console.ToANSICode(console.Bold, console.Red)},
+	//		{Pattern: regexp.MustCompile(`order-\d+`), Style: console.ToANSICode(console.Underline)},
+	//	},
+	//
+	// When matches overlap, the first one wins.  Rules have no effect when colors are
+	// disabled, or with Logfmt.  With TextQuoting, they don't apply to attribute values.
+	HighlightRules []HighlightRule
+
+	// HighlightAttrValues applies the HighlightRules to attribute values too, not just
+	// to messages.
+	HighlightAttrValues bool
+
+	// ColorLines colors each line with a single style, the Theme's style for the
+	// record's level, e.g. LevelError for errors, instead of coloring each field with
+	// its own style.  Some find this easier to scan.  It has no effect if colors are
+	// disabled.
+	ColorLines bool
+
+	// RuleWidth is the width of the rules written by WriteRule, in columns.  If 0,
+	// the value of the COLUMNS environment variable is used, or 80.
+	RuleWidth int
+
+	// Pretty prints the attributes below the header line, one per line, like YAML,
+	// rather than on the same line.  Groups are printed as indented blocks, under a
+	// line with the group's name.  For example:
+	//
+	//	INF request finished
+	//	  status: 200
+	//	  http:
+	//	    method: GET
+	//	    headers:
+	//	      accept: application/json
+	//
+	// This is much easier to read for deeply nested records.  Keys and values are
+	// separated with ": ", unless KeyValueSeparator is set.  Ignored with Logfmt.
+	Pretty bool
+
+	// AddStackOnLevel, if set, adds the stack trace of the goroutine which logged
+	// the record to records at or above this level, as a multiline attribute with
+	// the key StackKey, like zap's AddStacktrace option.  The trace starts at the
+	// function which logged the record.
+	AddStackOnLevel slog.Leveler
+
+	// PrettyLevel, if set, renders records at or above this level in pretty
+	// mode, as if Pretty were set, while the other records are rendered
+	// normally.  This makes errors stand out, for example.  A single record
+	// can also be switched in or out of pretty mode with the Pretty attribute.
+	// Ignored with Logfmt.
+	PrettyLevel slog.Leveler
+
+	// NormalizeNewlines translates "\r\n" in messages and attr values to "\n",
+	// and removes other carriage returns, which move the cursor back to the
+	// start of the line on terminals, so the rest of the line overwrites it.
+	NormalizeNewlines bool
+
+	// TabWidth, if greater than 0, expands the tabs in multiline messages and attr
+	// values to spaces, up to the next multiple of TabWidth columns, so tab-indented
+	// text, like stack traces, lines up once it's indented in a block.
+	TabWidth int
+
+	// ContinuationPrefix, if set, is printed at the start of each line of a record
+	// after the first, like the lines of multiline attr values, or of Pretty mode, in
+	// the Theme's Continuation style.  This visually attaches them to the record,
+	// rather than making them look like separate log lines.  For example, "  │ ".
+	ContinuationPrefix string
+
+	// RepeatHeader prints the header of a record, i.e. the part of the first line
+	// before the message, like the timestamp and level, again at the start of each
+	// of the following lines, like the lines of multiline attr values.  Then every
+	// line carries the record's metadata, for tools which process logs line by line,
+	// like grep.  The ContinuationPrefix, if any, follows the header.
+	RepeatHeader bool
+
+	// MaxAttrs, if greater than 0, is the maximum number of attributes printed for
+	// a record, including those added with WithAttrs.  The rest are replaced by a
+	// summary, like "…(+7 more)", in the Theme's Header style, which keeps records
+	// readable when middleware attaches dozens of attributes.  Attributes in groups
+	// count individually; attributes printed in the header don't count.
+	MaxAttrs int
+
+	// RecordAttrsFirst prints the attributes of each record before the attributes
+	// added with WithAttrs, and those from the context, instead of after them, so the
+	// attributes of each call are next to the message.  The attributes added with
+	// WithAttrs are then encoded again for every record, which is slower.
+	RecordAttrsFirst bool
+
+	// OmitZero skips attributes whose values are the zero values of their kinds: empty
+	// strings, 0, false, zero times and durations, and nil, including nil pointers,
+	// slices, and maps.  Empty, non-nil slices and maps are kept.  This declutters records
+	// from instrumentation which always logs every field.
+	OmitZero bool
+
+	// Compact separates attributes with commas, instead of spaces, like
+	// "msg a=1,b=2,g=(c=3)", to fit more on narrow terminals.  Values containing
+	// commas are quoted, like "msg a=1,b=\"x,y\"".  Ignored with Logfmt.
+	Compact bool
+
+	// KeyAliases maps attribute keys to the keys printed instead, like "method" for
+	// "http.request.method", to shorten verbose keys on the console, while the attributes
+	// keep their canonical keys for other handlers.  Keys are qualified by their groups,
+	// joined with dots, like in RemoveKeys.  The alias replaces the whole qualified key.
+	KeyAliases map[string]string
+
+	// KeyFormats maps attribute keys to fmt formats their values are printed with,
+	// like "%.2fms" for "latency", or "%q" for "addr", to fine-tune how fields are
+	// presented without a ReplaceAttr or LogValuer.  Keys are qualified by their groups,
+	// joined with dots, like in KeyAliases.  The format is applied to the value, like
+	// fmt.Sprintf(format, v.Any()), so it sees time.Duration rather than a string for
+	// durations.  Only attributes are formatted, not headers.
+	KeyFormats map[string]string
+
+	// BytesFormat selects how []byte attribute values are printed: like fmt does, by
+	// default, as a hex dump, or base64 encoded.
+	BytesFormat BytesFormat
+
+	// MaxBytes is the maximum number of bytes of []byte values printed as a hex dump,
+	// or base64 encoded.  The rest are summarized, like "…(+1024 bytes)".  Defaults
+	// to 256.
+	MaxBytes int
+
+	// RenderHTTP prints *http.Request and *http.Response attribute values compactly,
+	// like "GET http://example.com/api", and "404 Not Found GET http://example.com/api",
+	// with the status colored by its class, instead of dumping their fields.
+	RenderHTTP bool
+
+	// HTTPHeaders are the headers printed with requests and responses, when RenderHTTP
+	// is set, like "GET http://example.com/api [Accept: application/json]".
+	HTTPHeaders []string
+
+	// ThresholdRules style the numeric values of attributes which reach thresholds,
+	// like status codes of 500 and above, or durations over a second.  The first rule
+	// which applies to a value sets its style.
+	ThresholdRules []ThresholdRule
 }
 
+// GroupFormat is the format of attributes with group values.
+type GroupFormat int
+
+const (
+	// GroupDotted prints a group as the attributes in the group, with keys qualified
+	// by the group name, e.g.
+	//
+	//	http.method=GET http.path=/
+	GroupDotted GroupFormat = iota
+
+	// GroupParens prints a group as a single attribute, with the attributes in the
+	// group in parentheses, e.g.
+	//
+	//	http=(method=GET path=/ headers=(accept=json))
+	GroupParens
+
+	// GroupIndent prints a group as a block after the log line, like multiline
+	// attribute values, with one attribute per line, and nested groups indented, e.g.
+	//
+	//	=== http ===
+	//	method=GET
+	//	path=/
+	//	headers:
+	//	  accept=json
+	GroupIndent
+)
+
 const defaultHeaderFormat = "%t %l %{%s >%} %m %a"
 
+const defaultLoggerNameKey = "logger"
+
+var defaultErrorKeys = []string{"err", "error"}
+
 type Handler struct {
-	opts                      HandlerOptions
-	out                       io.Writer
-	groupPrefix               string
-	groups                    []string
-	context, multilineContext buffer
-	fields                    []any
-	headerFields              []headerField
-	sourceAsAttr              bool
-	mu                        *sync.Mutex
+	opts         HandlerOptions
+	out          *output
+	groupPrefix  string
+	groups       []string
+	fields       []any
+	headerFields []headerField
+	sourceAsAttr bool
+	mu           *sync.Mutex
+	attrsColumn  *columnTracker
+	columns      *headerColumns
+	sampler      *sampler
+	repeats      *repeatState
+	stats        *statsCollector
+	level        *atomic.Pointer[slog.Leveler]
+	levels       *levelRegistry
+	callerSkip   int
+	name         string
+	nameStyle    ANSIMod
+	nameAsAttr   bool
+	workerAsAttr bool
+	start        time.Time
+	lastTime     *atomic.Int64
+	lastDate     *atomic.Int64
+	lineColors   bool
+	prettyKVSep  string
+	// pool is the pool of the handler's encoders.  See encoderPool.
+	pool *sync.Pool
+	// userOpts are the options passed to NewHandler, before the defaults were
+	// applied.  See WithOptions.
+	userOpts *HandlerOptions
+	// groupLevel is the level set with WithGroupLevel, if any, which replaces
+	// the handler's level
+	groupLevel slog.Leveler
+	// attrs are the attrs passed to the last call of WithAttrs, linked to those
+	// of the earlier calls.  See encoded.
+	attrs *handlerAttrs
+	// continuation is the ContinuationPrefix, rendered in its style
+	continuation string
+	// attrFilters are the groups of the %[group]a fields in the HeaderFormat
+	attrFilters []string
+	// keyAliases are the KeyAliases, with the groups of the keys joined
+	// with the GroupSeparator
+	keyAliases map[string]string
+	// keyFormats are the KeyFormats, with the groups of the keys joined
+	// with the GroupSeparator
+	keyFormats map[string]string
 }
 
 type timestampField struct{}
 
 type headerField struct {
+	// keys are the keys of the attrs printed in the header, in order of
+	// preference.  The header prints the first one present.
+	keys       []headerKey
+	width      int
+	rightAlign bool
+	center     bool
+	// pad is the character the header is padded with, or 0 for spaces
+	pad       byte
+	style     ANSIMod
+	transform headerTransform
+	// errors is set for the %e verb, whose keys are HandlerOptions.ErrorKeys
+	errors bool
+	// worker is set for the %w verb, which prints the label from WithWorkerLabel
+	worker bool
+	memo   string
+	// memoized is set if memo is the rendering of memoAttr, an attr from
+	// WithAttrs, whose key has the rank memoRank
+	memoized bool
+	memoAttr slog.Attr
+	memoRank int
+}
+
+type headerKey struct {
 	groupPrefix string
 	key         string
-	width       int
-	rightAlign  bool
-	memo        string
+}
+
+// rank returns the index of the attr's key in the header's keys, or -1 if the
+// header doesn't print the attr.
+func (f headerField) rank(groupPrefix, key string) int {
+	for i, k := range f.keys {
+		if k.key == key && k.groupPrefix == groupPrefix {
+			return i
+		}
+	}
+	return -1
+}
+
+// padChar returns the character the header is padded with.
+func (f headerField) padChar() byte {
+	if f.pad == 0 {
+		return ' '
+	}
+	return f.pad
+}
+
+// isPadChar reports whether c can follow the width of a header, as the character
+// it's padded with.
+func isPadChar(c byte) bool {
+	switch c {
+	case '%', '-', '^', '{', '}':
+		return false
+	}
+	return c > ' ' && c < 0x7f && !('0' <= c && c <= '9') && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z')
+}
+
+// newHeaderKey splits a dotted key into the group prefix and the key.
+func newHeaderKey(key string) headerKey {
+	if idx := strings.LastIndexByte(key, '.'); idx > -1 {
+		return headerKey{groupPrefix: key[:idx], key: key[idx+1:]}
+	}
+	return headerKey{key: key}
 }
 
 type levelField struct {
@@ -156,7 +721,10 @@ type levelField struct {
 }
 type messageField struct{}
 
-type attrsField struct{}
+type attrsField struct {
+	// group, if set, is the group whose attrs are printed by this field
+	group string
+}
 
 type groupOpen struct {
 	style string
@@ -167,28 +735,141 @@ type spacer struct {
 	hard bool
 }
 
+// literal is a fixed string from the format, already wrapped in the
+// ANSI codes of the style of the enclosing group.
+type literal string
+
 type sourceField struct{}
 
+type nameField struct{}
+
 var _ slog.Handler = (*Handler)(nil)
+var _ io.Closer = (*Handler)(nil)
+var _ slog.Leveler = (*Handler)(nil)
+
+// FromSlogOptions returns HandlerOptions with the Level, AddSource, and ReplaceAttr
+// of opts, so code which already builds a [slog.HandlerOptions] can switch to this
+// handler:
+//
+//	h := console.NewHandler(os.Stderr, console.FromSlogOptions(&slogOpts))
+//
+// The other options have their default values.  If opts is nil, it returns the default
+// options.
+func FromSlogOptions(opts *slog.HandlerOptions) *HandlerOptions {
+	if opts == nil {
+		return new(HandlerOptions)
+	}
+	return &HandlerOptions{
+		Level:       opts.Level,
+		AddSource:   opts.AddSource,
+		ReplaceAttr: opts.ReplaceAttr,
+	}
+}
 
 // NewHandler creates a Handler that writes to w,
 // using the given options.
 // If opts is nil, the default options are used.
+// opts isn't modified, so it can be reused for other handlers.
 func NewHandler(out io.Writer, opts *HandlerOptions) *Handler {
 	if opts == nil {
 		opts = new(HandlerOptions)
 	}
+	// the options as given, before the defaults are applied, for WithOptions
+	userOpts := *opts
+	// the defaults and presets are applied to a copy, so the caller's options can
+	// be reused for other handlers
+	optsCopy := userOpts
+	opts = &optsCopy
 	if opts.Level == nil {
 		opts.Level = slog.LevelInfo
 	}
+	// noColor is whether colors are disabled, by the options or the environment.
+	// It's kept out of the caller's options, so they can be reused.
+	noColor := opts.NoColor
+	// colors disabled by the options can't be forced on by the environment
+	explicitNoColor := opts.NoColor || opts.JournaldPrefix
+	ci := !opts.IgnoreCI && IsCI()
+	if ci {
+		noColor = true
+	}
 	if opts.TimeFormat == "" {
 		opts.TimeFormat = time.DateTime
+		if ci {
+			opts.TimeFormat = ciTimeFormat
+		}
+		if opts.Logfmt {
+			opts.TimeFormat = defaultLogfmtTimeFormat
+		}
 	}
 	if opts.Theme.Name == "" {
 		opts.Theme = NewDefaultTheme()
 	}
+	if opts.Format != nil {
+		opts.HeaderFormat = opts.Format.String()
+	}
 	if opts.HeaderFormat == "" {
 		opts.HeaderFormat = defaultHeaderFormat // default format
+		if opts.Logfmt {
+			opts.HeaderFormat = defaultLogfmtHeaderFormat
+		}
+	}
+	if opts.ErrorLevel == nil {
+		opts.ErrorLevel = slog.LevelWarn
+	}
+	if opts.MaxBufferSize == 0 {
+		opts.MaxBufferSize = defaultMaxBufferSize
+	}
+	if opts.MaxMultilineBytes == 0 {
+		opts.MaxMultilineBytes = defaultMaxMultilineBytes
+	}
+	if opts.RepeatTimeout <= 0 {
+		opts.RepeatTimeout = defaultRepeatTimeout
+	}
+	if opts.MaxResolveDepth <= 0 {
+		opts.MaxResolveDepth = defaultMaxResolveDepth
+	}
+	if opts.RuleWidth <= 0 {
+		opts.RuleWidth = terminalWidth()
+	}
+	if opts.GroupSeparator == "" {
+		opts.GroupSeparator = "."
+	}
+	prettyKVSep := opts.KeyValueSeparator
+	if prettyKVSep == "" {
+		prettyKVSep = ": "
+	}
+	// kvSep is kept out of the caller's options, so Pretty still sees whether
+	// the separator was set if they're reused
+	kvSep := opts.KeyValueSeparator
+	if kvSep == "" || opts.Logfmt {
+		kvSep = "="
+	}
+	if opts.Logfmt {
+		opts.GroupFormat = GroupDotted
+		opts.Pretty = false
+	}
+	if opts.JournaldPrefix {
+		noColor = true
+	}
+	if color, ok := forceColor(); ok && !explicitNoColor {
+		noColor = !color
+	}
+	if opts.ColorAlways {
+		noColor = false
+	}
+	// lines are rendered without colors, and then colored as a whole
+	lineColors := opts.ColorLines && !noColor
+	if lineColors {
+		noColor = true
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultMaxBytes
+	}
+	if opts.ErrorKeys == nil {
+		opts.ErrorKeys = defaultErrorKeys
+	}
+	if opts.LoggerNameKey == "" {
+		opts.LoggerNameKey = defaultLoggerNameKey
 	}
 
 	fields, headerFields := parseFormat(opts.HeaderFormat, opts.Theme)
@@ -222,76 +903,467 @@ func NewHandler(out io.Writer, opts *HandlerOptions) *Handler {
 		}
 	}
 
+	fields = renderLiterals(fields, opts.Theme, noColor)
+
+	// pre-render the padding printed for fixed width headers when the
+	// header attribute is missing.
+	for i, hf := range headerFields {
+		if hf.width > 0 {
+			headerFields[i].memo = strings.Repeat(string(hf.padChar()), hf.width)
+		}
+		if hf.errors {
+			keys := make([]headerKey, len(opts.ErrorKeys))
+			for j, k := range opts.ErrorKeys {
+				keys[j] = headerKey{key: k}
+			}
+			headerFields[i].keys = keys
+		}
+		if opts.GroupSeparator != "." {
+			// header keys always use dots, but attrs are matched against
+			// group prefixes joined with the configured separator
+			for j, k := range hf.keys {
+				hf.keys[j].groupPrefix = strings.ReplaceAll(k.groupPrefix, ".", opts.GroupSeparator)
+			}
+		}
+	}
+
 	// Check if the parsed fields include any sourceField instances
 	// If not, set sourceAsAttr to true so source is handled as a regular attribute
 	sourceAsAttr := true
-	for _, f := range fields {
-		if _, ok := f.(sourceField); ok {
+	nameAsAttr := true
+	workerAsAttr := !slices.ContainsFunc(headerFields, func(f headerField) bool { return f.worker })
+	var attrFilters []string
+	for i, f := range fields {
+		switch f := f.(type) {
+		case sourceField:
 			sourceAsAttr = false
-			break
+		case nameField:
+			nameAsAttr = false
+		case attrsField:
+			if f.group == "" {
+				continue
+			}
+			f.group = strings.ReplaceAll(f.group, ".", opts.GroupSeparator)
+			fields[i] = f
+			if !slices.Contains(attrFilters, f.group) {
+				attrFilters = append(attrFilters, f.group)
+			}
 		}
 	}
 
-	return &Handler{
+	var attrsColumn *columnTracker
+	if opts.AlignAttrs {
+		attrsColumn = &columnTracker{}
+	}
+
+	keyAliases := qualifyKeys(opts.KeyAliases, opts.GroupSeparator)
+	keyFormats := qualifyKeys(opts.KeyFormats, opts.GroupSeparator)
+
+	var columns *headerColumns
+	if opts.AutoAlign {
+		columns = &headerColumns{}
+	}
+
+	var smp *sampler
+	if opts.Sampling != nil {
+		smp = newSampler(*opts.Sampling)
+	}
+
+	var repeats *repeatState
+	if opts.CollapseRepeats {
+		repeats = &repeatState{}
+	}
+
+	var stats *statsCollector
+	if opts.CollectStats {
+		stats = &statsCollector{}
+	}
+
+	start := time.Now()
+	if opts.Now != nil {
+		start = opts.Now()
+	}
+
+	continuation := opts.ContinuationPrefix
+	if continuation != "" && !noColor && opts.Theme.Continuation != "" {
+		continuation = string(opts.Theme.Continuation) + continuation + string(ResetMod)
+	}
+
+	var lastTime *atomic.Int64
+	if opts.TimeDelta {
+		lastTime = &atomic.Int64{}
+	}
+	var lastDate *atomic.Int64
+	if opts.DateRules {
+		lastDate = &atomic.Int64{}
+	}
+
+	level := &atomic.Pointer[slog.Leveler]{}
+	level.Store(&opts.Level)
+
+	pool := encoderPool
+	if opts.MaxBufferSize != defaultMaxBufferSize {
+		pool = newEncoderPool()
+	}
+
+	h := &Handler{
 		opts:         *opts, // Copy struct
-		out:          out,
+		out:          &output{out},
 		groupPrefix:  "",
-		context:      nil,
 		fields:       fields,
 		headerFields: headerFields,
 		sourceAsAttr: sourceAsAttr,
-		mu:           &sync.Mutex{},
+		mu:           writerMutex(out, opts.Mutex),
+		attrsColumn:  attrsColumn,
+		columns:      columns,
+		sampler:      smp,
+		repeats:      repeats,
+		stats:        stats,
+		level:        level,
+		levels:       &levelRegistry{},
+		callerSkip:   opts.CallerSkip,
+		nameAsAttr:   nameAsAttr,
+		workerAsAttr: workerAsAttr,
+		start:        start,
+		lastTime:     lastTime,
+		lastDate:     lastDate,
+		lineColors:   lineColors,
+		prettyKVSep:  prettyKVSep,
+		pool:         pool,
+		userOpts:     &userOpts,
+		continuation: continuation,
+		attrFilters:  attrFilters,
+		keyAliases:   keyAliases,
+		keyFormats:   keyFormats,
+	}
+	h.opts.NoColor = noColor
+	h.opts.KeyValueSeparator = kvSep
+	if attrs := envAttrs(opts.EnvAttrs); len(attrs) > 0 {
+		h = h.WithAttrs(attrs).(*Handler)
+		h.attrs.env = true
 	}
+	return h
+}
+
+// qualifyKeys returns m, with the dots joining the groups of its keys replaced by
+// the separator.
+func qualifyKeys(m map[string]string, sep string) map[string]string {
+	if sep == "." || len(m) == 0 {
+		return m
+	}
+	qualified := make(map[string]string, len(m))
+	for k, v := range m {
+		qualified[strings.ReplaceAll(k, ".", sep)] = v
+	}
+	return qualified
+}
+
+// fileMutexes holds a lock for each file descriptor handlers have been created
+// for, so independent handlers writing to the same file don't interleave lines.
+// It's keyed by the descriptor rather than the *os.File, so the files aren't kept
+// alive, and it only grows as large as the highest descriptor used.
+var fileMutexes sync.Map
+
+// writerMutex returns the lock for a new handler writing to w.
+func writerMutex(w io.Writer, mu *sync.Mutex) *sync.Mutex {
+	if mu != nil {
+		return mu
+	}
+	if f, ok := w.(*os.File); ok && f != nil {
+		if fd, ok := fileDescriptor(f); ok {
+			m, _ := fileMutexes.LoadOrStore(fd, &sync.Mutex{})
+			return m.(*sync.Mutex)
+		}
+	}
+	return &sync.Mutex{}
+}
+
+// fileDescriptor returns the descriptor of f.  Unlike f.Fd, it leaves the file in
+// non-blocking mode.
+func fileDescriptor(f *os.File) (uintptr, bool) {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return 0, false
+	}
+	var fd uintptr
+	if err := rc.Control(func(d uintptr) { fd = d }); err != nil {
+		return 0, false
+	}
+	return fd, true
 }
 
 // Enabled implements slog.Handler.
-func (h *Handler) Enabled(_ context.Context, l slog.Level) bool {
-	return l >= h.opts.Level.Level()
+func (h *Handler) Enabled(ctx context.Context, l slog.Level) bool {
+	if min, ok := MinLevelFromContext(ctx); ok {
+		return l >= min
+	}
+	min := h.nameLevel()
+	if pmin, ok := h.levels.minPackageLevel(); ok && pmin < min {
+		// Handle checks the level of the record's package
+		min = pmin
+	}
+	return l >= min
+}
+
+// Level returns the minimum level of records the handler currently logs.
+// It implements slog.Leveler.
+func (h *Handler) Level() slog.Level {
+	return (*h.level.Load()).Level()
+}
+
+// SetLevel atomically replaces the minimum level of records the handler logs,
+// even if it was created with a fixed level.  The level is shared with the handler
+// this handler was derived from, and all the handlers derived from it via WithAttrs
+// and WithGroup, so changing it adjusts the verbosity of all the loggers built on
+// the same root handler.  If l is nil, slog.LevelInfo is used.
+func (h *Handler) SetLevel(l slog.Leveler) {
+	if l == nil {
+		l = slog.LevelInfo
+	}
+	h.level.Store(&l)
+}
+
+// flusher is implemented by buffered writers, like *bufio.Writer.
+type flusher interface {
+	Flush() error
+}
+
+// Flush writes any pending summary of repeated records (see
+// HandlerOptions.CollapseRepeats), and flushes any output buffered by the
+// handler's writer, if the writer has a Flush() error method.  The handler doesn't buffer output itself:
+// each record is written to the writer with a single call to Write.
+func (h *Handler) Flush() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	err := errors.Join(h.flushRepeats(), flush(h.out.w))
+	if h.opts.ErrorWriter != nil && h.opts.ErrorWriter != h.out.w {
+		err = errors.Join(err, flush(h.opts.ErrorWriter))
+	}
+	return err
+}
+
+// Close flushes the handler's writer, and then closes it, if it implements
+// io.Closer.  os.Stdout and os.Stderr are flushed, but never closed.
+// HandlerOptions.ErrorWriter is closed the same way.
+//
+// The writer is shared by all the handlers derived from this one via WithAttrs
+// and WithGroup, so closing any of them closes the writer for all of them.
+// Records logged after Close will most likely return errors.
+func (h *Handler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	err := errors.Join(h.flushRepeats(), closeWriter(h.out.w))
+	if h.opts.ErrorWriter != nil && h.opts.ErrorWriter != h.out.w {
+		err = errors.Join(err, closeWriter(h.opts.ErrorWriter))
+	}
+	return err
+}
+
+func flush(w any) error {
+	if f, ok := w.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func closeWriter(w any) error {
+	err := flush(w)
+	if w == os.Stdout || w == os.Stderr {
+		return err
+	}
+	if c, ok := w.(io.Closer); ok {
+		err = errors.Join(err, c.Close())
+	}
+	return err
 }
 
 func (h *Handler) Handle(ctx context.Context, rec slog.Record) error {
+	h.prepareRecord(&rec)
+
+	if !h.packageEnabled(ctx, rec) {
+		if h.stats != nil {
+			h.stats.filtered.Add(1)
+		}
+		return nil
+	}
+
+	if h.opts.OnRecord != nil {
+		// the hook gets a pointer to a clone, so rec itself doesn't escape, and
+		// attrs the hook adds don't share the caller's backing array
+		r := rec.Clone()
+		h.opts.OnRecord(ctx, &r)
+		rec = r
+	}
+
+	if h.sampler != nil && !h.sampler.sample(rec) {
+		if h.stats != nil {
+			h.stats.sampled.Add(1)
+		}
+		return nil
+	}
+
 	enc := newEncoder(h)
+	trailer, tsStart, tsEnd := h.encodeRecord(enc, ctx, &rec)
+
+	if h.opts.OnEmit != nil {
+		// the hook sees the whole line, so the trailer can't be written separately
+		enc.buf.Append(trailer)
+		trailer = nil
+		h.opts.OnEmit(enc.buf)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := h.out.w
+	toErr := h.opts.ErrorWriter != nil && rec.Level >= h.opts.ErrorLevel.Level()
+	if toErr {
+		out = h.opts.ErrorWriter
+	}
+	// summaryErr is the error writing the summary of the previous run of repeats,
+	// which shouldn't stop the record from being written
+	var summaryErr error
+	if h.repeats != nil {
+		var skip bool
+		skip, summaryErr = h.suppress(enc.buf, trailer, tsStart, tsEnd, toErr, out)
+		if skip {
+			if h.stats != nil {
+				h.stats.suppressed.Add(1)
+			}
+			enc.free()
+			return nil
+		}
+	}
+	if h.lastDate != nil && !rec.Time.IsZero() {
+		if err := h.writeDateRule(out, rec.Time); err != nil {
+			enc.free()
+			return errors.Join(summaryErr, err)
+		}
+	}
+	n, err := enc.buf.writeWithTrailer(out, trailer)
+	if h.stats != nil {
+		h.stats.written(rec.Level, n, err)
+	}
+	enc.free()
+	return errors.Join(summaryErr, err)
+}
+
+// prepareRecord fills in the parts of the record the handler computes itself, before
+// it's filtered and encoded: the caller, with CallerSkip, and the time, with Now.
+func (h *Handler) prepareRecord(rec *slog.Record) {
+	if h.callerSkip > 0 && rec.PC != 0 {
+		rec.PC = skipCallers(rec.PC, h.callerSkip)
+	}
+
+	if rec.Time.IsZero() && h.opts.Now != nil {
+		rec.Time = h.opts.Now()
+	}
+}
+
+// encodeRecord encodes the record into enc.buf, and returns the multiline attrs to
+// write after it, if they weren't appended to enc.buf, and the offsets of the
+// timestamp in enc.buf.
+func (h *Handler) encodeRecord(enc *encoder, ctx context.Context, rec *slog.Record) (trailer buffer, tsStart, tsEnd int) {
+	if h.opts.AddStackOnLevel != nil && rec.Level >= h.opts.AddStackOnLevel.Level() {
+		// rec shares its attrs with the caller's record, which may be passed to
+		// other handlers too
+		*rec = rec.Clone()
+		rec.AddAttrs(stackAttr(rec.PC))
+	}
 
-	var src slog.Source
+	// only allocated if needed, so records without source don't allocate
+	var src *slog.Source
 
 	if h.opts.AddSource && rec.PC > 0 {
 		frame, _ := runtime.CallersFrames([]uintptr{rec.PC}).Next()
-		src.Function = frame.Function
-		src.File = frame.File
-		src.Line = frame.Line
+		src = &slog.Source{
+			Function: frame.Function,
+			File:     frame.File,
+			Line:     frame.Line,
+		}
+	}
 
-		if h.sourceAsAttr {
-			// the source attr should not be inside any open groups
-			groups := enc.groups
-			enc.groups = nil
-			enc.encodeAttr("", slog.Any(slog.SourceKey, &src))
-			enc.groups = groups
+	if h.opts.PrettyLevel != nil && !h.opts.Logfmt && rec.Level >= h.opts.PrettyLevel.Level() {
+		enc.pretty = true
+	}
+	h.encodeAttrs(enc, ctx, rec, src)
+	if enc.prettyAttr != 0 && (enc.prettyAttr > 0) != enc.pretty && !h.opts.Logfmt {
+		// the record switches the mode with the Pretty attribute, so
+		// start over in the other mode
+		enc.pretty = !enc.pretty
+		enc.resetAttrs()
+		h.encodeAttrs(enc, ctx, rec, src)
+	}
+
+	var attrsFieldSeen bool
+	if enc.rule {
+		enc.encodeRule(rec.Message)
+	} else {
+		tsStart, tsEnd, attrsFieldSeen = enc.encodeFields(rec.Level, rec.Message, rec.Time, src)
+	}
+
+	if enc.pretty && attrsFieldSeen {
+		// in pretty mode, all the attrs are written after the line, with the multiline attrs
+		enc.attrBuf.Append(enc.multilineAttrBuf)
+		enc.attrBuf, enc.multilineAttrBuf = enc.multilineAttrBuf[:0], enc.attrBuf
+	}
+
+	// multiline attrs are written after the rest of the line.  They're kept in their own
+	// buffer so they don't need to be copied for writers which support vectored writes.
+	if internal.FeatureFlagNewMultilineAttrs && attrsFieldSeen && len(enc.multilineAttrBuf) > 0 {
+		enc.multilineAttrBuf.AppendByte('\n')
+		trailer = enc.multilineAttrBuf
+	} else {
+		enc.buf.AppendByte('\n')
+	}
+
+	if (h.continuation != "" || h.opts.RepeatHeader) && (len(trailer) > 0 || bytes.IndexByte(enc.buf, '\n') < len(enc.buf)-1) {
+		enc.buf.Append(trailer)
+		trailer = nil
+		var header []byte
+		if h.opts.RepeatHeader {
+			header = enc.buf[:min(enc.headerEnd, bytes.IndexByte(enc.buf, '\n'))]
 		}
+		enc.prefixContinuationLines(header, h.continuation)
 	}
 
-	enc.attrBuf.Append(h.context)
-	enc.multilineAttrBuf.Append(h.multilineContext)
+	if h.lineColors {
+		if style := levelStyle(h.opts.Theme, rec.Level); style != "" {
+			enc.buf.Append(trailer)
+			trailer = nil
+			enc.wrapLines(string(style), string(ResetMod))
+			tsStart, tsEnd = tsStart+len(style), tsEnd+len(style)
+		}
+	}
 
-	rec.Attrs(func(a slog.Attr) bool {
-		enc.encodeAttr(h.groupPrefix, a)
-		return true
-	})
+	if h.opts.JournaldPrefix {
+		prefix := journaldPriority(rec.Level)
+		enc.buf.Append(trailer)
+		trailer = nil
+		enc.wrapLines(prefix, "")
+		tsStart, tsEnd = tsStart+len(prefix), tsEnd+len(prefix)
+	}
 
+	return trailer, tsStart, tsEnd
+}
+
+// encodeFields encodes the fields of the HeaderFormat into buf.  It returns the
+// position of the timestamp in buf, which is ignored when comparing repeats, and
+// whether the format included the attributes.
+func (e *encoder) encodeFields(level slog.Level, msg string, t time.Time, src *slog.Source) (tsStart, tsEnd int, attrsFieldSeen bool) {
+	headerFields := e.headerFields
 	headerIdx := 0
+	headerEndSeen := false
 	var state encodeState
 	// use a fixed size stack to avoid allocations, 3 deep nested groups should be enough for most cases
 	stackArr := [3]encodeState{}
 	stack := stackArr[:0]
-	var attrsFieldSeen bool
-	for _, f := range h.fields {
+	for _, f := range e.h.fields {
 		switch f := f.(type) {
 		case groupOpen:
 			stack = append(stack, state)
-			state.groupStart = len(enc.buf)
+			state.groupStart = len(e.buf)
 			state.printedField = false
 			state.seenFields = 0
-			// Store the style to use for this group
-			state.style = f.style
 			continue
 		case groupClose:
 			if len(stack) == 0 {
@@ -304,20 +1376,19 @@ func (h *Handler) Handle(ctx context.Context, rec slog.Record) error {
 				// merge the current state with the prior state
 				lastState := stack[len(stack)-1]
 				state.groupStart = lastState.groupStart
-				state.style = lastState.style
 				state.seenFields += lastState.seenFields
 			} else {
 				// no fields were printed in this group, so
 				// rollback the entire group and pop back to
 				// the outer state
-				enc.buf = enc.buf[:state.groupStart]
+				e.buf = e.buf[:state.groupStart]
 				state = stack[len(stack)-1]
 			}
 			// pop a state off the stack
 			stack = stack[:len(stack)-1]
 			continue
 		case spacer:
-			if len(enc.buf) == 0 {
+			if len(e.buf) == 0 {
 				// special case, always skip leading space
 				continue
 			}
@@ -331,59 +1402,81 @@ func (h *Handler) Handle(ctx context.Context, rec slog.Record) error {
 			}
 
 			continue
-		case string:
+		case literal:
 			if state.pendingHardSpace {
-				enc.buf.AppendByte(' ')
+				e.buf.AppendByte(' ')
 			}
 			state.pendingHardSpace = false
 			state.pendingSpace = false
 			state.anchored = false
 
-			// Use the style specified for the group if available
-			style, _ := getThemeStyleByName(h.opts.Theme, state.style)
-			enc.withColor(&enc.buf, style, func() {
-				enc.buf.AppendString(f)
-			})
+			e.buf.AppendString(string(f))
 			continue
 		}
 		if state.pendingSpace || state.pendingHardSpace {
-			enc.buf.AppendByte(' ')
+			e.buf.AppendByte(' ')
 		}
-		l := len(enc.buf)
+		l := len(e.buf)
 		state.seenFields++
 		switch f := f.(type) {
 		case headerField:
-			hf := h.headerFields[headerIdx]
-			if enc.headerAttrs[headerIdx].Equal(slog.Attr{}) && hf.memo != "" {
-				enc.buf.AppendString(hf.memo)
+			hf := headerFields[headerIdx]
+			if e.headerAttrs[headerIdx].Equal(slog.Attr{}) && hf.memo != "" {
+				e.buf.AppendString(hf.memo)
 			} else {
-				enc.encodeHeader(enc.headerAttrs[headerIdx], hf.width, hf.rightAlign)
+				e.encodeHeader(e.headerAttrs[headerIdx], hf)
 			}
 			headerIdx++
 
 		case levelField:
-			enc.encodeLevel(rec.Level, f.abbreviated)
+			e.encodeLevel(level, f.abbreviated)
+			e.alignColumn(l, columnLevel)
 		case messageField:
-			enc.encodeMessage(rec.Level, rec.Message)
+			if !headerEndSeen {
+				e.headerEnd, headerEndSeen = l, true
+			}
+			e.encodeMessage(level, msg)
 		case attrsField:
+			attrsFieldSeen = true
+			if !headerEndSeen {
+				e.headerEnd, headerEndSeen = l, true
+			}
+			if f.group != "" {
+				if !e.pretty {
+					e.buf.Append(bytes.TrimSpace(e.filteredAttrs[e.h.attrFilterIndex(f.group)]))
+				}
+				break
+			}
+			if e.pretty {
+				// the attrs are written after the line
+				break
+			}
 			// trim the attrBuf and multilineAttrBuf to remove leading spaces
 			// but leave a space between attrBuf and multilineAttrBuf
-			if len(enc.attrBuf) > 0 {
-				enc.attrBuf = bytes.TrimSpace(enc.attrBuf)
-			} else if len(enc.multilineAttrBuf) > 0 && !internal.FeatureFlagNewMultilineAttrs {
-				enc.multilineAttrBuf = bytes.TrimSpace(enc.multilineAttrBuf)
+			if len(e.attrBuf) > 0 {
+				e.attrBuf = bytes.TrimSpace(e.attrBuf)
+			} else if len(e.multilineAttrBuf) > 0 && !internal.FeatureFlagNewMultilineAttrs {
+				e.multilineAttrBuf = bytes.TrimSpace(e.multilineAttrBuf)
 			}
-			attrsFieldSeen = true
-			enc.buf.Append(enc.attrBuf)
+			if e.h.attrsColumn != nil && len(e.attrBuf) > 0 {
+				w := visibleWidth(e.buf)
+				e.buf.Pad(e.h.attrsColumn.fit(w)-w, ' ')
+			}
+			e.buf.Append(e.attrBuf)
 			if !internal.FeatureFlagNewMultilineAttrs {
-				enc.buf.Append(enc.multilineAttrBuf)
+				e.buf.Append(e.multilineAttrBuf)
 			}
 		case sourceField:
-			enc.encodeSource(src)
+			e.encodeSource(level, src)
+			e.alignColumn(l, columnSource)
+		case nameField:
+			e.encodeName(e.h.name, e.h.nameStyle)
+			e.alignColumn(l, columnName)
 		case timestampField:
-			enc.encodeTimestamp(rec.Time)
+			e.encodeTimestamp(t)
+			tsStart, tsEnd = l, len(e.buf)
 		}
-		printed := len(enc.buf) > l
+		printed := len(e.buf) > l
 		state.printedField = state.printedField || printed
 		if printed {
 			state.pendingSpace = false
@@ -391,26 +1484,115 @@ func (h *Handler) Handle(ctx context.Context, rec slog.Record) error {
 			state.anchored = true
 		} else if state.pendingSpace || state.pendingHardSpace {
 			// chop the last space
-			enc.buf = bytes.TrimSpace(enc.buf)
+			e.buf = bytes.TrimSpace(e.buf)
 			// leave state.spacePending as is for next
 			// field to handle
 		}
 	}
+	return tsStart, tsEnd, attrsFieldSeen
+}
 
-	if internal.FeatureFlagNewMultilineAttrs && attrsFieldSeen && len(enc.multilineAttrBuf) > 0 {
-		enc.buf.Append(enc.multilineAttrBuf)
+// encodeAttrs encodes the attrs of the record, including the source and logger name,
+// if they aren't in the header, and the attrs from WithAttrs and the context.
+func (h *Handler) encodeAttrs(enc *encoder, ctx context.Context, rec *slog.Record, src *slog.Source) {
+	if src != nil && h.sourceAsAttr {
+		// the source attr should not be inside any open groups
+		groups := enc.groups
+		enc.groups = nil
+		enc.encodeAttr("", slog.Any(slog.SourceKey, src))
+		enc.groups = groups
 	}
 
-	enc.buf.AppendByte('\n')
+	if h.name != "" && h.nameAsAttr {
+		// like the source, the name should not be inside any open groups
+		groups := enc.groups
+		enc.groups = nil
+		enc.encodeAttr("", slog.String(h.opts.LoggerNameKey, h.name))
+		enc.groups = groups
+	}
 
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	if _, err := enc.buf.WriteTo(h.out); err != nil {
-		return err
+	if h.opts.RecordAttrsFirst {
+		h.encodeRecordAttrs(enc, rec)
 	}
 
-	enc.free()
-	return nil
+	if enc.pretty == h.opts.Pretty && len(h.attrFilters) == 0 && !h.opts.RecordAttrsFirst {
+		ea := h.encoded()
+		enc.attrBuf.Append(ea.context)
+		enc.multilineAttrBuf.Append(ea.multilineContext)
+		enc.numAttrs += ea.numAttrs
+		enc.omittedAttrs += ea.omittedAttrs
+		enc.omittedMultiline += ea.omittedMultiline
+	} else {
+		// the context was encoded in the other mode, has to be split between
+		// the attrs fields, or follows the record's attrs, so encode it again,
+		// along with its header attrs
+		enc.headerFields = h.headerFields
+		h.attrs.each(func(ha *handlerAttrs) {
+			enc.groups = append(enc.groups[:0], ha.groups...)
+			for _, a := range ha.attrs {
+				enc.encodeAttr(ha.groupPrefix, a)
+			}
+		})
+		enc.groups = enc.groups[:0]
+		if h.opts.ReplaceAttr != nil {
+			enc.groups = append(enc.groups, h.groups...)
+		}
+	}
+
+	if label, ok := WorkerLabelFromContext(ctx); ok {
+		if h.workerAsAttr {
+			// like the source, the label should not be inside any open groups
+			groups := enc.groups
+			enc.groups = nil
+			enc.encodeAttr("", slog.String(WorkerKey, label))
+			enc.groups = groups
+		} else {
+			for i := range h.headerFields {
+				if h.headerFields[i].worker {
+					enc.headerAttrs[i] = slog.String(WorkerKey, label)
+				}
+			}
+		}
+	}
+
+	if h.opts.CorrelationIDKey != "" {
+		if id, ok := correlationID(ctx); ok {
+			groups := enc.groups
+			enc.groups = nil
+			enc.encodeAttr("", slog.String(h.opts.CorrelationIDKey, id))
+			enc.groups = groups
+		}
+	}
+
+	if h.opts.AddContextAttrs {
+		if attrs := AttrsFromContext(ctx); len(attrs) > 0 {
+			groups := enc.groups
+			enc.groups = nil
+			for _, a := range attrs {
+				enc.encodeAttr("", a)
+			}
+			enc.groups = groups
+		}
+	}
+
+	if !h.opts.RecordAttrsFirst {
+		h.encodeRecordAttrs(enc, rec)
+	}
+
+	if enc.omittedAttrs > 0 {
+		enc.writeOmittedAttrs()
+	}
+	if enc.omittedMultiline > 0 {
+		enc.writeOmittedMultiline()
+	}
+}
+
+// encodeRecordAttrs encodes the record's own attrs, in the groups from WithGroup.
+func (h *Handler) encodeRecordAttrs(enc *encoder, rec *slog.Record) {
+	rec.Attrs(func(a slog.Attr) bool {
+		enc.encodeAttr(h.groupPrefix, a)
+		return true
+	})
 }
 
 type encodeState struct {
@@ -427,99 +1609,260 @@ type encodeState struct {
 	seenFields int
 
 	anchored, pendingSpace, pendingHardSpace bool
-	style                                    string
+}
+
+// handlerAttrs are the attrs passed to a call of WithAttrs, with the handler's
+// groups at the time, and the attrs passed to the earlier calls in prev.
+type handlerAttrs struct {
+	prev        *handlerAttrs
+	groupPrefix string
+	groups      []string
+	attrs       []slog.Attr
+	// env is set for the attrs added by NewHandler for HandlerOptions.EnvAttrs
+	env bool
+	// encoded are the attrs of all the calls, encoded
+	encoded encodedAttrs
+}
+
+// each calls fn with the attrs of each call of WithAttrs, in order.
+func (ha *handlerAttrs) each(fn func(*handlerAttrs)) {
+	if ha == nil {
+		return
+	}
+	ha.prev.each(fn)
+	fn(ha)
+}
+
+// encodedAttrs are the attrs of a handler, encoded like the attrs of a record.
+type encodedAttrs struct {
+	context, multilineContext buffer
+	// headerFields are the handler's header fields, with the header attrs
+	// pre-rendered in their memos
+	headerFields []headerField
+	// numAttrs and omittedAttrs are the numbers of attrs printed in, and omitted
+	// from, context.  See HandlerOptions.MaxAttrs.
+	numAttrs, omittedAttrs int
+	// omittedMultiline is the number of bytes of the multiline attrs omitted
+	// from the context.  See HandlerOptions.MaxMultilineBytes.
+	omittedMultiline int
 }
 
 // WithAttrs implements slog.Handler.
 func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	ha := &handlerAttrs{
+		prev:        h.attrs,
+		groupPrefix: h.groupPrefix,
+		groups:      h.groups,
+		attrs:       attrs,
+		encoded:     h.encodeContext(attrs),
+	}
+	return &Handler{
+		opts:         h.opts,
+		out:          h.out,
+		groupPrefix:  h.groupPrefix,
+		groups:       h.groups,
+		fields:       h.fields,
+		headerFields: h.headerFields,
+		sourceAsAttr: h.sourceAsAttr,
+		mu:           h.mu,
+		attrsColumn:  h.attrsColumn,
+		columns:      h.columns,
+		sampler:      h.sampler,
+		repeats:      h.repeats,
+		stats:        h.stats,
+		level:        h.level,
+		levels:       h.levels,
+		groupLevel:   h.groupLevel,
+		callerSkip:   h.callerSkip,
+		name:         h.name,
+		nameStyle:    h.nameStyle,
+		nameAsAttr:   h.nameAsAttr,
+		workerAsAttr: h.workerAsAttr,
+		start:        h.start,
+		lastTime:     h.lastTime,
+		lastDate:     h.lastDate,
+		lineColors:   h.lineColors,
+		prettyKVSep:  h.prettyKVSep,
+		pool:         h.pool,
+		userOpts:     h.userOpts,
+		continuation: h.continuation,
+		attrFilters:  h.attrFilters,
+		keyAliases:   h.keyAliases,
+		keyFormats:   h.keyFormats,
+		attrs:        ha,
+	}
+}
+
+// encoded returns the handler's encoded attrs.
+func (h *Handler) encoded() encodedAttrs {
+	if h.attrs == nil {
+		return encodedAttrs{headerFields: h.headerFields}
+	}
+	return h.attrs.encoded
+}
+
+// encodeContext encodes the attrs passed to WithAttrs after the handler's own.
+func (h *Handler) encodeContext(attrs []slog.Attr) encodedAttrs {
+	base := h.encoded()
 	enc := newEncoder(h)
+	enc.numAttrs, enc.omittedAttrs = base.numAttrs, base.omittedAttrs
+	// the new attrs are encoded after the context, so they're separated from
+	// it like the attrs of a record, e.g. by commas in Compact mode
+	enc.attrBuf.Append(base.context)
+	enc.multilineAttrBuf.Append(base.multilineContext)
+	enc.omittedMultiline = base.omittedMultiline
 
 	for _, a := range attrs {
 		enc.encodeAttr(h.groupPrefix, a)
 	}
 
-	headerFields := memoizeHeaders(enc, h.headerFields)
-
-	newCtx := h.context
-	newMultiCtx := h.multilineContext
-	if len(enc.attrBuf) > 0 {
-		newCtx = append(newCtx, enc.attrBuf...)
-		newCtx = slices.Clip(newCtx)
+	ea := encodedAttrs{
+		context:          base.context,
+		multilineContext: base.multilineContext,
+		headerFields:     memoizeHeaders(enc, base.headerFields),
+		numAttrs:         enc.numAttrs,
+		omittedAttrs:     enc.omittedAttrs,
+		omittedMultiline: enc.omittedMultiline,
 	}
-	if len(enc.multilineAttrBuf) > 0 {
-		newMultiCtx = append(newMultiCtx, enc.multilineAttrBuf...)
-		newMultiCtx = slices.Clip(newMultiCtx)
+	if len(enc.attrBuf) > len(base.context) {
+		ea.context = slices.Clip(append([]byte(nil), enc.attrBuf...))
 	}
-
-	enc.free()
-
-	return &Handler{
-		opts:             h.opts,
-		out:              h.out,
-		groupPrefix:      h.groupPrefix,
-		context:          newCtx,
-		multilineContext: newMultiCtx,
-		groups:           h.groups,
-		fields:           h.fields,
-		headerFields:     headerFields,
-		sourceAsAttr:     h.sourceAsAttr,
-		mu:               h.mu,
+	if len(enc.multilineAttrBuf) > len(base.multilineContext) || enc.omittedMultiline > base.omittedMultiline {
+		// the encoder started with the context, so the multiline attrs are capped
+		// along with it
+		ea.multilineContext = slices.Clip(append([]byte(nil), enc.multilineAttrBuf...))
 	}
+	enc.free()
+	return ea
 }
 
 // WithGroup implements slog.Handler.
 func (h *Handler) WithGroup(name string) slog.Handler {
 	name = strings.TrimSpace(name)
+	if name == "" {
+		// If the name is empty, WithGroup returns the receiver.
+		// https://pkg.go.dev/log/slog#Handler
+		return h
+	}
 	groupPrefix := name
 	if h.groupPrefix != "" {
-		groupPrefix = h.groupPrefix + "." + name
+		groupPrefix = h.groupPrefix + h.opts.GroupSeparator + name
 	}
 	return &Handler{
 		opts:         h.opts,
 		out:          h.out,
 		groupPrefix:  groupPrefix,
-		context:      h.context,
-		groups:       append(h.groups, name),
+		groups:       append(slices.Clip(h.groups), name),
 		fields:       h.fields,
 		headerFields: h.headerFields,
 		sourceAsAttr: h.sourceAsAttr,
 		mu:           h.mu,
+		attrsColumn:  h.attrsColumn,
+		columns:      h.columns,
+		sampler:      h.sampler,
+		repeats:      h.repeats,
+		stats:        h.stats,
+		level:        h.level,
+		levels:       h.levels,
+		groupLevel:   h.groupLevel,
+		callerSkip:   h.callerSkip,
+		name:         h.name,
+		nameStyle:    h.nameStyle,
+		nameAsAttr:   h.nameAsAttr,
+		workerAsAttr: h.workerAsAttr,
+		start:        h.start,
+		lastTime:     h.lastTime,
+		lastDate:     h.lastDate,
+		lineColors:   h.lineColors,
+		prettyKVSep:  h.prettyKVSep,
+		pool:         h.pool,
+		userOpts:     h.userOpts,
+		continuation: h.continuation,
+		attrFilters:  h.attrFilters,
+		keyAliases:   h.keyAliases,
+		keyFormats:   h.keyFormats,
+		attrs:        h.attrs,
 	}
 }
 
+// memoizeHeaders returns the header fields, with the header attrs found by enc
+// pre-rendered in their memos.  The header fields are shared by the derived
+// handlers, so they're only copied if enc found any header attrs.
 func memoizeHeaders(enc *encoder, headerFields []headerField) []headerField {
-	newFields := make([]headerField, len(headerFields))
-	copy(newFields, headerFields)
-
-	for i := range newFields {
-		if !enc.headerAttrs[i].Equal(slog.Attr{}) {
-			enc.buf.Reset()
-			enc.encodeHeader(enc.headerAttrs[i], newFields[i].width, newFields[i].rightAlign)
-			newFields[i].memo = enc.buf.String()
+	newFields := headerFields
+	copied := false
+	for i := range headerFields {
+		if enc.headerAttrs[i].Equal(slog.Attr{}) {
+			continue
+		}
+		if !copied {
+			newFields = slices.Clone(headerFields)
+			copied = true
 		}
+		enc.buf.Reset()
+		enc.encodeHeader(enc.headerAttrs[i], newFields[i])
+		newFields[i].memo = enc.buf.String()
+		newFields[i].memoized = true
+		newFields[i].memoAttr, newFields[i].memoRank = enc.headerAttrs[i], enc.headerRanks[i]
 	}
 	return newFields
 }
 
+// renderLiterals replaces the fixed strings in the fields with literals, pre-rendered
+// in the style of their enclosing group, so they can just be copied into each record.
+func renderLiterals(fields []any, theme Theme, noColor bool) []any {
+	styles := []string{""}
+	for i, f := range fields {
+		switch f := f.(type) {
+		case groupOpen:
+			styles = append(styles, f.style)
+		case groupClose:
+			if len(styles) > 1 {
+				styles = styles[:len(styles)-1]
+			}
+		case string:
+			style, _ := getThemeStyleByName(theme, styles[len(styles)-1])
+			if noColor || style == "" {
+				fields[i] = literal(f)
+			} else {
+				fields[i] = literal(string(style) + f + string(ResetMod))
+			}
+		}
+	}
+	return fields
+}
+
 // parseFormat parses a format string into a list of fields and the number of headerFields.
 //
 // Supported format verbs:
 //
 //		%t	- timestampField
 //		%h	- headerField, requires the [name] modifier.
-//		      Supports width, right-alignment (-) modifiers.
+//		      Supports width, pad character, right-alignment (-), and centering (^) modifiers.
 //		%m	- messageField
 //		%l	- abbreviated levelField: The log level in abbreviated form (e.g., "INF").
 //		%L	- non-abbreviated levelField: The log level in full form (e.g., "INFO").
 //		%{	- groupOpen
 //		%}	- groupClose
 //	    %s  - sourceField
+//	    %N  - nameField
+//	    %a  - attrsField
+//	    %e  - headerField, with the HandlerOptions.ErrorKeys
 //
 // Modifiers:
 //
 //	[name] (for %h): The key of the attribute to capture as a header. This modifier is required for the %h verb.
+//	                 Several keys can be separated by "|", and the first one present is printed.
+//	[group] (for %a): The group of the attributes to print.  This modifier is optional.
 //	width (for %h): An integer specifying the fixed width of the header. This modifier is optional.
+//	pad (for %h): An ASCII punctuation character following the width, which pads the header
+//	              instead of spaces. This modifier is optional.
 //	- (for %h): Indicates right-alignment of the header. This modifier is optional.
+//	^ (for %h): Indicates centering of the header. This modifier is optional.
+//	U, u, B (for %h): Transforms the header to upper case, lower case, or its basename. These modifiers are optional.
 //
 // Examples:
 //
@@ -582,9 +1925,11 @@ func parseFormat(format string, theme Theme) (fields []any, headerFields []heade
 
 		// Check for modifiers before verb
 		var width int
-		var rightAlign bool
+		var rightAlign, center bool
+		var pad byte
 		var key string
 		var style string
+		var transform headerTransform
 		var styleSeen, keySeen, widthSeen bool
 
 		// Look for (style) modifier for groupOpen
@@ -624,7 +1969,10 @@ func parseFormat(format string, theme Theme) (fields []any, headerFields []heade
 		// Look for modifiers
 		for i < len(format) {
 			if format[i] == '-' {
-				rightAlign = true
+				rightAlign, center = true, false
+				i++
+			} else if format[i] == '^' {
+				rightAlign, center = false, true
 				i++
 			} else if format[i] >= '0' && format[i] <= '9' {
 				widthSeen = true
@@ -633,6 +1981,13 @@ func parseFormat(format string, theme Theme) (fields []any, headerFields []heade
 					width = width*10 + int(format[i]-'0')
 					i++
 				}
+				if i < len(format) && isPadChar(format[i]) {
+					pad = format[i]
+					i++
+				}
+			} else if t := headerTransforms[format[i]]; keySeen && t != 0 {
+				transform |= t
+				i++
 			} else {
 				break
 			}
@@ -655,20 +2010,43 @@ func parseFormat(format string, theme Theme) (fields []any, headerFields []heade
 		case 't':
 			field = timestampField{}
 		case 'h':
-			if key == "" {
+			var keys []headerKey
+			for _, k := range strings.Split(key, "|") {
+				if k != "" {
+					keys = append(keys, newHeaderKey(k))
+				}
+			}
+			if len(keys) == 0 {
 				fields = append(fields, "%!h(MISSING_HEADER_NAME)")
 				continue
 			}
-			hf := headerField{
-				key:        key,
+			field = headerField{
+				keys:       keys,
+				width:      width,
+				rightAlign: rightAlign,
+				center:     center,
+				pad:        pad,
+				style:      theme.Header,
+				transform:  transform,
+			}
+		case 'e':
+			field = headerField{
 				width:      width,
 				rightAlign: rightAlign,
+				center:     center,
+				pad:        pad,
+				style:      theme.AttrValueError,
+				errors:     true,
 			}
-			if idx := strings.LastIndexByte(key, '.'); idx > -1 {
-				hf.groupPrefix = key[:idx]
-				hf.key = key[idx+1:]
+		case 'w':
+			field = headerField{
+				width:      width,
+				rightAlign: rightAlign,
+				center:     center,
+				pad:        pad,
+				style:      theme.Header,
+				worker:     true,
 			}
-			field = hf
 		case 'm':
 			field = messageField{}
 		case 'l':
@@ -685,8 +2063,10 @@ func parseFormat(format string, theme Theme) (fields []any, headerFields []heade
 			field = groupClose{}
 		case 's':
 			field = sourceField{}
+		case 'N':
+			field = nameField{}
 		case 'a':
-			field = attrsField{}
+			field = attrsField{group: key}
 		default:
 			fields = append(fields, fmt.Sprintf("%%!%c(INVALID_VERB)", format[i]))
 			continue
@@ -697,15 +2077,21 @@ func parseFormat(format string, theme Theme) (fields []any, headerFields []heade
 		case styleSeen && format[i] != '{':
 			fields = append(fields, fmt.Sprintf("%%!((INVALID_MODIFIER)%c", format[i]))
 			continue
-		case keySeen && format[i] != 'h':
+		case keySeen && format[i] != 'h' && format[i] != 'a':
 			fields = append(fields, fmt.Sprintf("%%![(INVALID_MODIFIER)%c", format[i]))
 			continue
-		case widthSeen && format[i] != 'h':
+		case widthSeen && format[i] != 'h' && format[i] != 'e' && format[i] != 'w':
 			fields = append(fields, fmt.Sprintf("%%!%d(INVALID_MODIFIER)%c", width, format[i]))
 			continue
-		case rightAlign && format[i] != 'h':
+		case transform != 0 && format[i] != 'h':
+			fields = append(fields, fmt.Sprintf("%%!%s(INVALID_MODIFIER)%c", transform, format[i]))
+			continue
+		case rightAlign && format[i] != 'h' && format[i] != 'e' && format[i] != 'w':
 			fields = append(fields, fmt.Sprintf("%%!-(INVALID_MODIFIER)%c", format[i]))
 			continue
+		case center && format[i] != 'h' && format[i] != 'e' && format[i] != 'w':
+			fields = append(fields, fmt.Sprintf("%%!^(INVALID_MODIFIER)%c", format[i]))
+			continue
 		}
 
 		fields = append(fields, field)
@@ -728,6 +2114,10 @@ func getThemeStyleByName(theme Theme, name string) (ANSIMod, bool) {
 		return theme.Header, true
 	case "source":
 		return theme.Source, true
+	case "sourceWarn":
+		return theme.SourceWarn, true
+	case "sourceError":
+		return theme.SourceError, true
 	case "message":
 		return theme.Message, true
 	case "messageDebug":
@@ -738,6 +2128,10 @@ func getThemeStyleByName(theme Theme, name string) (ANSIMod, bool) {
 		return theme.AttrValue, true
 	case "attrValueError":
 		return theme.AttrValueError, true
+	case "attrValueTrue":
+		return theme.AttrValueTrue, true
+	case "attrValueFalse":
+		return theme.AttrValueFalse, true
 	case "levelError":
 		return theme.LevelError, true
 	case "levelWarn":
@@ -746,6 +2140,14 @@ func getThemeStyleByName(theme Theme, name string) (ANSIMod, bool) {
 		return theme.LevelInfo, true
 	case "levelDebug":
 		return theme.LevelDebug, true
+	case "loggerName":
+		return theme.LoggerName, true
+	case "continuation":
+		return theme.Continuation, true
+	case "diffAdded":
+		return theme.DiffAdded, true
+	case "diffRemoved":
+		return theme.DiffRemoved, true
 	default:
 		return theme.Header, false // Default to header style, but indicate style was not recognized
 	}
@@ -1,6 +1,7 @@
 package console
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"errors"
@@ -11,7 +12,9 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -25,6 +28,32 @@ func TestNewHandler(t *testing.T) {
 	AssertEqual(t, defaultHeaderFormat, h.opts.HeaderFormat)
 }
 
+func TestFromSlogOptions(t *testing.T) {
+	AssertEqual(t, HandlerOptions{}.AddSource, FromSlogOptions(nil).AddSource)
+
+	opts := FromSlogOptions(&slog.HandlerOptions{
+		Level:     slog.LevelWarn,
+		AddSource: true,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == "secret" {
+				a.Value = slog.StringValue("***")
+			}
+			return a
+		},
+	})
+	AssertEqual(t, true, opts.AddSource)
+	AssertEqual(t, slog.Leveler(slog.LevelWarn), opts.Level)
+
+	opts.AddSource = false
+	opts.NoColor = true
+	opts.HeaderFormat = "%l %m %a"
+	buf := bytes.Buffer{}
+	l := slog.New(NewHandler(&buf, opts))
+	l.Info("hidden")
+	l.Warn("shown", "secret", "hunter2")
+	AssertEqual(t, "WRN shown secret=***\n", buf.String())
+}
+
 func TestHandler_Enabled(t *testing.T) {
 	tests := []slog.Level{
 		slog.LevelDebug - 1, slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError, slog.LevelError + 1,
@@ -103,6 +132,107 @@ func TestHandler_TimeFormat(t *testing.T) {
 	}
 }
 
+func TestHandler_TimestampCache(t *testing.T) {
+	tm := time.Date(2024, 01, 02, 15, 04, 05, 0, time.UTC)
+	buf := bytes.Buffer{}
+	kitchen := NewHandler(&buf, &HandlerOptions{NoColor: true, TimeFormat: time.Kitchen, HeaderFormat: "%t %m"})
+	dateTime := NewHandler(&buf, &HandlerOptions{NoColor: true, TimeFormat: time.DateTime, HeaderFormat: "%t %m"})
+	ctx := context.Background()
+	for _, r := range []struct {
+		h *Handler
+		t time.Time
+	}{
+		{kitchen, tm},
+		{kitchen, tm},
+		// the same time, in another layout or location, or another time, is formatted again
+		{dateTime, tm},
+		{kitchen, tm.In(time.FixedZone("X", 3600))},
+		{kitchen, tm.Add(time.Minute)},
+		{kitchen, tm},
+	} {
+		AssertNoError(t, r.h.Handle(ctx, slog.NewRecord(r.t, slog.LevelInfo, "msg", 0)))
+	}
+	AssertEqual(t, "3:04PM msg\n3:04PM msg\n2024-01-02 15:04:05 msg\n4:04PM msg\n3:05PM msg\n3:04PM msg\n", buf.String())
+}
+
+func TestHandler_FormatTime(t *testing.T) {
+	handlerTest{
+		time: time.Date(2024, 01, 02, 15, 04, 05, 123456789, time.UTC),
+		opts: HandlerOptions{
+			TimeFormat:   time.Kitchen, // ignored
+			NoColor:      true,
+			HeaderFormat: "%t %m %a",
+			FormatTime: func(buf []byte, t time.Time) []byte {
+				return strconv.AppendInt(buf, t.UnixMilli(), 10)
+			},
+		},
+		msg:   "msg",
+		attrs: []slog.Attr{slog.Time("foo", time.UnixMilli(1000))},
+		want:  "1704207845123 msg foo=1000\n",
+	}.run(t)
+}
+
+func TestHandler_RelativeTime(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%t %m %a", RelativeTime: true})
+	start := h.start
+
+	rec := slog.NewRecord(start.Add(3214*time.Millisecond), slog.LevelInfo, "msg", 0)
+	rec.AddAttrs(slog.Time("at", time.Date(2024, 01, 02, 15, 04, 05, 0, time.UTC)))
+	AssertNoError(t, h.WithGroup("g").Handle(context.Background(), rec))
+	AssertEqual(t, "+00:03.214 msg g.at=2024-01-02 15:04:05\n", buf.String())
+}
+
+func TestHandler_TimeDelta(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%t %m", TimeFormat: "15:04:05.000", TimeDelta: true})
+	start := time.Date(2024, 01, 02, 15, 04, 05, 0, time.UTC)
+
+	for i, d := range []time.Duration{0, 12*time.Millisecond + 400*time.Microsecond, 12*time.Millisecond + 650*time.Microsecond, 2 * time.Second, time.Second} {
+		start = start.Add(d)
+		var hh slog.Handler = h
+		if i%2 == 1 {
+			hh = h.WithAttrs([]slog.Attr{slog.Int("i", i)})
+		}
+		AssertNoError(t, hh.Handle(context.Background(), slog.NewRecord(start, slog.LevelInfo, "msg", 0)))
+	}
+	// out of order
+	AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(start.Add(-1500*time.Microsecond), slog.LevelInfo, "msg", 0)))
+	AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(start, slog.LevelInfo, "msg", 0)))
+
+	want := strings.Join([]string{
+		"15:04:05.000 msg",
+		"15:04:05.012 (+12ms) msg",
+		"15:04:05.025 (+13ms) msg",
+		"15:04:07.025 (+2s) msg",
+		"15:04:08.025 (+1s) msg",
+		"15:04:08.023 (-2ms) msg",
+		"15:04:08.025 (+2ms) msg",
+		"",
+	}, "\n")
+	AssertEqual(t, want, buf.String())
+}
+
+func TestHandler_Now(t *testing.T) {
+	now := time.Date(2024, 01, 02, 15, 04, 05, 0, time.UTC)
+	clock := func() time.Time {
+		now = now.Add(1500 * time.Millisecond)
+		return now
+	}
+
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%t %m", TimeFormat: "15:04:05.000", Now: clock})
+	AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "zero", 0)))
+	// records with a time keep it
+	AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(now.Add(time.Hour), slog.LevelInfo, "set", 0)))
+	AssertEqual(t, "15:04:08.000 zero\n16:04:08.000 set\n", buf.String())
+
+	buf.Reset()
+	h = NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%t %m", RelativeTime: true, Now: clock})
+	AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "zero", 0)))
+	AssertEqual(t, "+00:01.500 zero\n", buf.String())
+}
+
 // Handlers should not log the time field if it is zero.
 // '- If r.Time is the zero time, ignore the time.'
 // https://pkg.go.dev/log/slog@master#Handler
@@ -339,6 +469,124 @@ func TestHandler_Groups(t *testing.T) {
 	}
 }
 
+func TestHandler_GroupFormat(t *testing.T) {
+	nested := []slog.Attr{
+		slog.String("a", "1"),
+		slog.Group("http", slog.String("method", "GET"), slog.Group("headers", slog.String("accept", "json")), slog.Group("empty")),
+		slog.String("b", "2"),
+	}
+	tests := []handlerTest{
+		{
+			name:  "dotted",
+			attrs: nested,
+			want:  "msg a=1 http.method=GET http.headers.accept=json b=2\n",
+		},
+		{
+			name:  "parens",
+			opts:  HandlerOptions{GroupFormat: GroupParens},
+			attrs: nested,
+			want:  "msg a=1 http=(method=GET headers=(accept=json)) b=2\n",
+		},
+		{
+			name:        "parens with WithGroup",
+			opts:        HandlerOptions{GroupFormat: GroupParens},
+			attrs:       nested[1:2],
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithGroup("req") },
+			want:        "msg req.http=(method=GET headers=(accept=json))\n",
+		},
+		{
+			name:  "parens elides empty groups",
+			opts:  HandlerOptions{GroupFormat: GroupParens, ReplaceAttr: RemoveKeys("g.c")},
+			attrs: []slog.Attr{slog.Group("g", slog.String("c", "d")), slog.Group("e", slog.Group("f"))},
+			want:  "msg\n",
+		},
+		{
+			name:  "parens with header",
+			opts:  HandlerOptions{GroupFormat: GroupParens, HeaderFormat: "%[http.method]h %m %a"},
+			attrs: nested[1:2],
+			want:  "GET msg http=(headers=(accept=json))\n",
+		},
+		{
+			name:  "parens with multiline value",
+			opts:  HandlerOptions{GroupFormat: GroupParens},
+			attrs: []slog.Attr{slog.Group("g", slog.String("a", "1"), slog.String("body", "x\ny"))},
+			want:  "msg g=(a=1)\n=== g.body ===\nx\ny\n",
+		},
+		{
+			name:  "indent",
+			opts:  HandlerOptions{GroupFormat: GroupIndent},
+			attrs: nested,
+			want:  "msg a=1 b=2\n=== http ===\nmethod=GET\nheaders:\n  accept=json\n",
+		},
+		{
+			name:        "indent with WithAttrs",
+			opts:        HandlerOptions{GroupFormat: GroupIndent},
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithAttrs(nested[1:2]) },
+			attrs:       []slog.Attr{slog.String("c", "3")},
+			want:        "msg c=3\n=== http ===\nmethod=GET\nheaders:\n  accept=json\n",
+		},
+	}
+
+	for _, test := range tests {
+		test.opts.NoColor = true
+		if test.opts.HeaderFormat == "" {
+			test.opts.HeaderFormat = "%m %a"
+		}
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestHandler_Separators(t *testing.T) {
+	attrs := []slog.Attr{
+		slog.String("a", "1"),
+		slog.Group("http", slog.String("method", "GET"), slog.String("path", "/")),
+	}
+	tests := []handlerTest{
+		{
+			name:        "group separator",
+			opts:        HandlerOptions{GroupSeparator: "::"},
+			attrs:       attrs,
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithGroup("req") },
+			want:        "msg req::a=1 req::http::method=GET req::http::path=/\n",
+		},
+		{
+			name:        "header keys use dots",
+			opts:        HandlerOptions{GroupSeparator: "/", HeaderFormat: "%[req.http.method]h %m %a"},
+			attrs:       attrs,
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithGroup("req") },
+			want:        "GET msg req/a=1 req/http/path=/\n",
+		},
+		{
+			name:  "key value separator",
+			opts:  HandlerOptions{KeyValueSeparator: ": "},
+			attrs: attrs,
+			want:  "msg a: 1 http.method: GET http.path: /\n",
+		},
+		{
+			name:  "key value separator with parens",
+			opts:  HandlerOptions{KeyValueSeparator: ":", GroupFormat: GroupParens},
+			attrs: attrs,
+			want:  "msg a:1 http:(method:GET path:/)\n",
+		},
+		{
+			name:  "multiline",
+			opts:  HandlerOptions{GroupSeparator: "/"},
+			attrs: []slog.Attr{slog.Group("g", slog.String("body", "x\ny"))},
+			want:  "msg\n=== g/body ===\nx\ny\n",
+		},
+	}
+
+	for _, test := range tests {
+		test.opts.NoColor = true
+		if test.opts.HeaderFormat == "" {
+			test.opts.HeaderFormat = "%m %a"
+		}
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+}
+
 func TestHandler_WithAttr(t *testing.T) {
 	testTime := time.Date(2024, 01, 02, 15, 04, 05, 123456789, time.UTC)
 
@@ -471,6 +719,30 @@ func TestHandler_WithGroup(t *testing.T) {
 			attrs: []slog.Attr{slog.String("baz", "foo")},
 			want:  "INF withGroup and withAttrs bar=baz group1.foo=bar group1.baz=foo\n",
 		},
+		{
+			name: "empty withGroup",
+			handlerFunc: func(h slog.Handler) slog.Handler {
+				return h.WithGroup("group1").WithGroup("")
+			},
+			attrs: []slog.Attr{slog.String("foo", "bar")},
+			want:  "INF empty withGroup group1.foo=bar\n",
+		},
+		{
+			name: "inline group in withGroup",
+			handlerFunc: func(h slog.Handler) slog.Handler {
+				return h.WithGroup("group1")
+			},
+			attrs: []slog.Attr{slog.Group("", slog.String("foo", "bar"))},
+			want:  "INF inline group in withGroup group1.foo=bar\n",
+		},
+		{
+			name: "withGroup keeps multiline context",
+			handlerFunc: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.String("foo", "line one\nline two")}).WithGroup("group1")
+			},
+			attrs: []slog.Attr{slog.String("bar", "baz")},
+			want:  "INF withGroup keeps multiline context group1.bar=baz\n=== foo ===\nline one\nline two\n",
+		},
 	}
 
 	for _, test := range tests {
@@ -864,6 +1136,36 @@ func TestHandler_TruncateSourcePath(t *testing.T) {
 			attrs: []slog.Attr{slog.Any("source", &relSource)},
 			want:  "INF source=blue/green/yellow/main.go:23",
 		},
+		{
+			name:  "trim prefix",
+			opts:  HandlerOptions{SourceTrimPrefixes: []string{"/opt/", "/var/proj/"}},
+			attrs: []slog.Attr{slog.Any("source", &absSource)},
+			want:  "INF source=red/blue/green/yellow/main.go:23",
+		},
+		{
+			name:  "trim prefix then truncate",
+			opts:  HandlerOptions{SourceTrimPrefixes: []string{"/var/proj/red/blue/"}, TruncateSourcePath: 3},
+			attrs: []slog.Attr{slog.Any("source", &absSource)},
+			want:  "INF source=green/yellow/main.go:23",
+		},
+		{
+			name:  "trim prefix, fewer segments than truncate",
+			opts:  HandlerOptions{SourceTrimPrefixes: []string{"/var/proj/red/blue/"}, TruncateSourcePath: 4},
+			attrs: []slog.Attr{slog.Any("source", &absSource)},
+			want:  "INF source=green/yellow/main.go:23",
+		},
+		{
+			name:  "trim prefix in cwd",
+			opts:  HandlerOptions{SourceTrimPrefixes: []string{"/usr/share/"}},
+			attrs: []slog.Attr{slog.Any("source", &relSource)},
+			want:  "INF source=proj/red/blue/green/yellow/main.go:23",
+		},
+		{
+			name:  "no matching prefix",
+			opts:  HandlerOptions{SourceTrimPrefixes: []string{"/opt/"}},
+			attrs: []slog.Attr{slog.Any("source", &relSource)},
+			want:  "INF source=red/blue/green/yellow/main.go:23",
+		},
 	}
 
 	for _, tt := range tests {
@@ -1214,6 +1516,30 @@ func TestHandler_HeaderFormat(t *testing.T) {
 			attrs: []slog.Attr{slog.String("foo", "bar")},
 			want:  "INF        bar > with headers\n",
 		},
+		{
+			name:  "fixed width header centered",
+			opts:  HandlerOptions{HeaderFormat: "%l %[foo]^10h > %m %a", NoColor: true},
+			attrs: []slog.Attr{slog.String("foo", "bar")},
+			want:  "INF    bar     > with headers\n",
+		},
+		{
+			name:  "fixed width header centered with pad char",
+			opts:  HandlerOptions{HeaderFormat: "%l %[foo]^10.h > %m %a", NoColor: true},
+			attrs: []slog.Attr{slog.String("foo", "bar")},
+			want:  "INF ...bar.... > with headers\n",
+		},
+		{
+			name:  "fixed width header right aligned with pad char",
+			opts:  HandlerOptions{HeaderFormat: "%l %[foo]-6*h %[bar]5_h > %m %a", NoColor: true},
+			attrs: []slog.Attr{slog.String("foo", "bar"), slog.String("bar", "baz")},
+			want:  "INF ***bar baz__ > with headers\n",
+		},
+		{
+			name:  "fixed width header missing attr with pad char",
+			opts:  HandlerOptions{HeaderFormat: "%l %[missing]^6.h > %m %a", NoColor: true},
+			attrs: []slog.Attr{slog.String("foo", "bar")},
+			want:  "INF ...... > with headers foo=bar\n",
+		},
 		{
 			name:  "fixed width header truncated",
 			opts:  HandlerOptions{HeaderFormat: "%l %[foo]3h > %m %a", NoColor: true},
@@ -1363,6 +1689,64 @@ func TestHandler_HeaderFormat(t *testing.T) {
 	}
 }
 
+func TestHandler_HeaderPadding(t *testing.T) {
+	theme := Theme{Name: "test", Header: ToANSICode(Bold)}
+	red := styled("red", ToANSICode(Red))
+	tests := []handlerTest{
+		{
+			name:  "colored right aligned",
+			opts:  HandlerOptions{Theme: theme, HeaderFormat: "%[foo]-6h|"},
+			attrs: []slog.Attr{slog.String("foo", "bar")},
+			want:  styled("   bar", theme.Header) + styled("|", theme.Header) + "\n",
+		},
+		{
+			name:  "colored centered",
+			opts:  HandlerOptions{Theme: theme, HeaderFormat: "%[foo]^6.h|"},
+			attrs: []slog.Attr{slog.String("foo", "bar")},
+			want:  styled(".bar..", theme.Header) + styled("|", theme.Header) + "\n",
+		},
+		{
+			name:  "value with escape sequences",
+			opts:  HandlerOptions{NoColor: true, HeaderFormat: "%[foo]-6h|%[bar]5h|"},
+			attrs: []slog.Attr{slog.String("foo", red), slog.String("bar", red)},
+			want:  "   " + red + "|" + red + "  |\n",
+		},
+		{
+			name:  "truncated value with escape sequences",
+			opts:  HandlerOptions{NoColor: true, HeaderFormat: "%[foo]2h|"},
+			attrs: []slog.Attr{slog.String("foo", red)},
+			want:  string(ToANSICode(Red)) + "re" + string(ResetMod) + "|\n",
+		},
+		{
+			name:  "multibyte characters",
+			opts:  HandlerOptions{NoColor: true, HeaderFormat: "%[foo]-4h|%[bar]2h|"},
+			attrs: []slog.Attr{slog.String("foo", "µs"), slog.String("bar", "ñandú")},
+			want:  "  µs|ña|\n",
+		},
+		{
+			name:  "truncation marker",
+			opts:  HandlerOptions{NoColor: true, HeaderFormat: "%[path]8h|%[foo]-8h|%[bar]3h|", TruncationMarker: "…"},
+			attrs: []slog.Attr{slog.String("path", "/usr/local/bin"), slog.String("foo", "bar"), slog.String("bar", "bar")},
+			want:  "/usr/lo…|     bar|bar|\n",
+		},
+		{
+			name:  "truncation marker wider than the field",
+			opts:  HandlerOptions{NoColor: true, HeaderFormat: "%[foo]4h|%[bar]2h|", TruncationMarker: "..."},
+			attrs: []slog.Attr{slog.String("foo", "foobar"), slog.String("bar", "foobar")},
+			want:  "f...|..|\n",
+		},
+		{
+			name:  "truncation marker with escape sequences",
+			opts:  HandlerOptions{NoColor: true, HeaderFormat: "%[foo]3h|", TruncationMarker: "…"},
+			attrs: []slog.Attr{slog.String("foo", red+"dish")},
+			want:  string(ToANSICode(Red)) + "re" + string(ResetMod) + "…|\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, tt.run)
+	}
+}
+
 type handlerTest struct {
 	name        string
 	opts        HandlerOptions
@@ -1417,6 +1801,11 @@ func TestThemes(t *testing.T) {
 	for _, theme := range []Theme{
 		NewDefaultTheme(),
 		NewBrightTheme(),
+		NewMonoTheme(),
+		NewColorblindTheme(),
+		NewSolarizedTheme(),
+		NewDraculaTheme(),
+		NewNordTheme(),
 	} {
 		t.Run(theme.Name, func(t *testing.T) {
 			tests := []struct {
@@ -1503,11 +1892,14 @@ func TestThemes(t *testing.T) {
 				// put together the expected log line
 
 				var levelStyle ANSIMod
+				sourceStyle := theme.Source
 				switch {
 				case tt.lvl >= slog.LevelError:
 					levelStyle = theme.LevelError
+					sourceStyle = theme.SourceError
 				case tt.lvl >= slog.LevelWarn:
 					levelStyle = theme.LevelWarn
+					sourceStyle = theme.SourceWarn
 				case tt.lvl >= slog.LevelInfo:
 					levelStyle = theme.LevelInfo
 				default:
@@ -1537,7 +1929,7 @@ func TestThemes(t *testing.T) {
 					" " +
 					styled("http", theme.Header) +
 					" " +
-					styled(sourceField, theme.Source) +
+					styled(sourceField, sourceStyle) +
 					" " +
 					styled(">", theme.Header) +
 					" " +
@@ -1578,3 +1970,1104 @@ func TestThemes(t *testing.T) {
 		})
 	}
 }
+
+type closeRecorder struct {
+	bytes.Buffer
+	closed int
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestHandler_Flush(t *testing.T) {
+	dst := bytes.Buffer{}
+	w := bufio.NewWriter(&dst)
+	h := NewHandler(w, &HandlerOptions{NoColor: true, HeaderFormat: "%m"})
+	AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "buffered", 0)))
+	AssertEqual(t, "", dst.String())
+
+	AssertNoError(t, h.WithGroup("g").(*Handler).Flush())
+	AssertEqual(t, "buffered\n", dst.String())
+
+	// writers without a Flush method are ignored
+	AssertNoError(t, NewHandler(io.Discard, nil).Flush())
+}
+
+func TestHandler_Close(t *testing.T) {
+	w := &closeRecorder{}
+	h := NewHandler(w, nil)
+	AssertNoError(t, h.WithAttrs([]slog.Attr{slog.String("foo", "bar")}).(*Handler).Close())
+	AssertEqual(t, 1, w.closed)
+
+	// stdout and stderr are never closed
+	AssertNoError(t, NewHandler(os.Stderr, nil).Close())
+	_, err := os.Stderr.Write(nil)
+	AssertNoError(t, err)
+
+	// closing a tee closes each handler
+	w2 := &closeRecorder{}
+	tee := NewTeeHandler(NewHandler(w, nil), slog.NewTextHandler(w, nil), NewHandler(w2, nil))
+	AssertNoError(t, tee.(io.Closer).Close())
+	AssertEqual(t, 2, w.closed)
+	AssertEqual(t, 1, w2.closed)
+}
+
+func TestHandler_ErrorWriter(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	l := slog.New(NewHandler(&stdout, &HandlerOptions{
+		NoColor:      true,
+		HeaderFormat: "%l %m",
+		Level:        slog.LevelDebug,
+		ErrorWriter:  &stderr,
+	}))
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn")
+	l.Error("error")
+	AssertEqual(t, "DBG debug\nINF info\n", stdout.String())
+	AssertEqual(t, "WRN warn\nERR error\n", stderr.String())
+
+	stdout.Reset()
+	stderr.Reset()
+	l = slog.New(NewHandler(&stdout, &HandlerOptions{
+		NoColor:      true,
+		HeaderFormat: "%l %m",
+		ErrorWriter:  &stderr,
+		ErrorLevel:   slog.LevelError,
+	}))
+	l.Warn("warn")
+	l.Error("error")
+	AssertEqual(t, "WRN warn\n", stdout.String())
+	AssertEqual(t, "ERR error\n", stderr.String())
+}
+
+func TestHandler_SetLevel(t *testing.T) {
+	h := NewHandler(io.Discard, nil)
+	child := h.WithAttrs([]slog.Attr{slog.String("foo", "bar")}).WithGroup("g").(*Handler)
+	ctx := context.Background()
+
+	AssertEqual(t, slog.LevelInfo, h.Level())
+	AssertEqual(t, false, child.Enabled(ctx, slog.LevelDebug))
+
+	h.SetLevel(slog.LevelDebug)
+	AssertEqual(t, slog.LevelDebug, child.Level())
+	AssertEqual(t, true, child.Enabled(ctx, slog.LevelDebug))
+
+	// a LevelVar can be swapped in, and is consulted on each call
+	lv := &slog.LevelVar{}
+	lv.Set(slog.LevelError)
+	child.SetLevel(lv)
+	AssertEqual(t, false, h.Enabled(ctx, slog.LevelWarn))
+	lv.Set(slog.LevelWarn)
+	AssertEqual(t, true, h.Enabled(ctx, slog.LevelWarn))
+
+	h.SetLevel(nil)
+	AssertEqual(t, slog.LevelInfo, h.Level())
+}
+
+func TestHandler_Allocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	h := NewHandler(io.Discard, &HandlerOptions{HeaderFormat: "%t %l %{%[foo]h >%} %m %a"}).
+		WithAttrs([]slog.Attr{slog.String("foo", "bar"), slog.Int("n", 1)}).
+		WithGroup("g").
+		WithAttrs([]slog.Attr{slog.String("baz", "buz")})
+	rec := slog.NewRecord(time.Now(), slog.LevelInfo, "hello", 0)
+	rec.AddAttrs(slog.String("a", "b"), slog.Duration("dur", time.Second))
+	ctx := context.Background()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = h.Handle(ctx, rec)
+	})
+	AssertEqual(t, 0.0, allocs)
+}
+
+func TestHandler_WithAttrs_Allocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	h := NewHandler(io.Discard, &HandlerOptions{HeaderFormat: "%t %l %{%[foo]h >%} %m %a"})
+	attrs := []slog.Attr{slog.String("req", "abc"), slog.Int("n", 1)}
+
+	// the handler, its attrs, and their encoding
+	AssertEqual(t, 3.0, testing.AllocsPerRun(100, func() { _ = h.WithAttrs(attrs) }))
+	AssertEqual(t, 0.0, testing.AllocsPerRun(100, func() { _ = h.WithAttrs(nil) }))
+
+	// the encoded attrs are shared by derived handlers
+	h2 := h.WithAttrs(attrs).(*Handler)
+	h3 := h2.WithGroup("g").(*Handler)
+	ctx := h3.encoded().context
+	AssertEqual(t, true, bytes.Contains(ctx, []byte("abc")))
+	AssertEqual(t, &ctx[0], &h2.encoded().context[0])
+
+	// the header fields are only copied for header attrs
+	AssertEqual(t, &h.headerFields[0], &h2.encoded().headerFields[0])
+	h4 := h2.WithAttrs([]slog.Attr{slog.String("foo", "bar")}).(*Handler)
+	AssertNotEqual(t, &h.headerFields[0], &h4.encoded().headerFields[0])
+	AssertEqual(t, "", h.headerFields[0].memo)
+	AssertEqual(t, true, h4.encoded().headerFields[0].memo != "")
+}
+
+func TestHandler_WithAttrs_Eager(t *testing.T) {
+	buf := bytes.Buffer{}
+	var replaced []string
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%m %a", ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+		replaced = append(replaced, a.Key)
+		return a
+	}})
+
+	// the attrs are resolved and replaced by WithAttrs, like slog's handlers do
+	vals := []int{1}
+	l := slog.New(h).With("v", vals)
+	AssertEqual(t, "v", strings.Join(replaced, ","))
+	vals[0] = 2
+	l.Info("m")
+	AssertEqual(t, "m v=[1]\n", buf.String())
+}
+
+func TestHandler_Mutex(t *testing.T) {
+	mu := &sync.Mutex{}
+	h1 := NewHandler(io.Discard, &HandlerOptions{Mutex: mu})
+	h2 := NewHandler(&bytes.Buffer{}, &HandlerOptions{Mutex: mu})
+	AssertEqual(t, mu, h1.mu)
+	AssertEqual(t, mu, h2.WithGroup("g").(*Handler).mu)
+
+	// handlers for the same file share a lock by default
+	AssertEqual(t, NewHandler(os.Stderr, nil).mu, NewHandler(os.Stderr, nil).mu)
+	AssertNotEqual(t, NewHandler(os.Stderr, nil).mu, NewHandler(os.Stdout, nil).mu)
+	AssertNotEqual(t, NewHandler(io.Discard, nil).mu, NewHandler(io.Discard, nil).mu)
+
+	// the lock is found by the descriptor, so the files aren't kept alive
+	f, err := os.Create(filepath.Join(t.TempDir(), "log"))
+	AssertNoError(t, err)
+	defer f.Close()
+	fd, ok := fileDescriptor(f)
+	AssertEqual(t, true, ok)
+	mu2 := NewHandler(f, nil).mu
+	m, _ := fileMutexes.Load(fd)
+	AssertEqual(t, mu2, m.(*sync.Mutex))
+	fileMutexes.Range(func(k, _ any) bool {
+		_, ok := k.(uintptr)
+		AssertEqual(t, true, ok)
+		return true
+	})
+
+	// concurrent writers sharing a mutex never interleave lines
+	var out bytes.Buffer
+	var wmu sync.Mutex
+	w := writerFunc(func(b []byte) (int, error) {
+		// simulate a writer which writes each line in two parts, so lines
+		// interleave if not serialized by the handlers
+		for _, part := range [][]byte{b[:len(b)/2], b[len(b)/2:]} {
+			wmu.Lock()
+			out.Write(part)
+			wmu.Unlock()
+			runtime.Gosched()
+		}
+		return len(b), nil
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		l := slog.New(NewHandler(w, &HandlerOptions{NoColor: true, HeaderFormat: "%m", Mutex: mu}))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				l.Info("0123456789")
+			}
+		}()
+	}
+	wg.Wait()
+	AssertEqual(t, strings.Repeat("0123456789\n", 200), out.String())
+}
+
+func TestHandler_Hooks(t *testing.T) {
+	type ctxKey struct{}
+	var emitted []string
+	handlerTest{
+		opts: HandlerOptions{
+			NoColor: true,
+			OnRecord: func(ctx context.Context, rec *slog.Record) {
+				rec.Message = strings.ToUpper(rec.Message)
+				rec.AddAttrs(slog.Any("req", ctx.Value(ctxKey{})))
+			},
+			OnEmit: func(line []byte) {
+				emitted = append(emitted, string(line))
+				// lines can be modified in place
+				copy(line, "ABC")
+			},
+		},
+		msg:   "hooked",
+		attrs: []slog.Attr{slog.String("multi", "line one\nline two")},
+		want:  "ABC HOOKED req=<nil>\n=== multi ===\nline one\nline two\n",
+	}.run(t)
+	AssertEqual(t, 1, len(emitted))
+	AssertEqual(t, "INF HOOKED req=<nil>\n=== multi ===\nline one\nline two\n", emitted[0])
+
+	buf := bytes.Buffer{}
+	l := slog.New(NewHandler(&buf, &HandlerOptions{
+		NoColor:      true,
+		HeaderFormat: "%m %a",
+		OnRecord: func(ctx context.Context, rec *slog.Record) {
+			rec.AddAttrs(slog.Any("req", ctx.Value(ctxKey{})))
+		},
+	}))
+	l.InfoContext(context.WithValue(context.Background(), ctxKey{}, 7), "msg")
+	AssertEqual(t, "msg req=7\n", buf.String())
+}
+
+func TestHandler_OnRecord_SharedRecord(t *testing.T) {
+	// the same record is passed to several handlers, e.g. by a fan-out handler,
+	// and each one's hook adds attrs
+	newHandler := func(buf *bytes.Buffer, key string) *Handler {
+		return NewHandler(buf, &HandlerOptions{
+			NoColor:      true,
+			HeaderFormat: "%m %a",
+			OnRecord: func(_ context.Context, rec *slog.Record) {
+				rec.AddAttrs(slog.Bool(key, true))
+			},
+		})
+	}
+	var buf1, buf2 bytes.Buffer
+	h1, h2 := newHandler(&buf1, "x"), newHandler(&buf2, "y")
+
+	rec := slog.NewRecord(time.Now(), slog.LevelInfo, "msg", 0)
+	for i := 0; i < 8; i++ {
+		rec.AddAttrs(slog.Int(strconv.Itoa(i), i))
+	}
+	AssertNoError(t, h1.Handle(context.Background(), rec))
+	AssertNoError(t, h2.Handle(context.Background(), rec))
+
+	AssertEqual(t, "msg 0=0 1=1 2=2 3=3 4=4 5=5 6=6 7=7 x=true\n", buf1.String())
+	AssertEqual(t, "msg 0=0 1=1 2=2 3=3 4=4 5=5 6=6 7=7 y=true\n", buf2.String())
+	AssertEqual(t, 8, rec.NumAttrs())
+}
+
+func TestHandler_ColorLines(t *testing.T) {
+	theme := NewDefaultTheme()
+	buf := bytes.Buffer{}
+	l := slog.New(NewHandler(&buf, &HandlerOptions{HeaderFormat: "%l %m %a", Theme: theme, ColorLines: true}))
+	l.Info("msg", "a", 1)
+	l.Error("failed", "body", "x\ny")
+
+	want := styled("INF msg a=1", theme.LevelInfo) + "\n" +
+		styled("ERR failed", theme.LevelError) + "\n" +
+		styled("=== body ===", theme.LevelError) + "\n" +
+		styled("x", theme.LevelError) + "\n" +
+		styled("y", theme.LevelError) + "\n"
+	AssertEqual(t, want, buf.String())
+
+	// no effect without colors
+	buf.Reset()
+	l = slog.New(NewHandler(&buf, &HandlerOptions{HeaderFormat: "%l %m %a", NoColor: true, ColorLines: true}))
+	l.Info("msg", "a", 1)
+	AssertEqual(t, "INF msg a=1\n", buf.String())
+}
+
+func TestHandler_ColorLines_ReusedOptions(t *testing.T) {
+	theme := NewDefaultTheme()
+	opts := &HandlerOptions{HeaderFormat: "%l %m", Theme: theme, ColorLines: true}
+
+	// the options aren't changed by NewHandler, so they can be reused
+	var buf1, buf2 bytes.Buffer
+	slog.New(NewHandler(&buf1, opts)).Error("a")
+	AssertEqual(t, false, opts.NoColor)
+	slog.New(NewHandler(&buf2, opts)).Error("b")
+
+	AssertEqual(t, styled("ERR a", theme.LevelError)+"\n", buf1.String())
+	AssertEqual(t, styled("ERR b", theme.LevelError)+"\n", buf2.String())
+}
+
+func TestHandler_Pretty(t *testing.T) {
+	attrs := []slog.Attr{
+		slog.Int("status", 200),
+		slog.Group("http", slog.String("method", "GET"), slog.Group("headers", slog.String("accept", "json")), slog.Group("empty")),
+		slog.String("body", "line1\nline2"),
+	}
+	tests := []handlerTest{
+		{
+			name:  "pretty",
+			attrs: attrs,
+			want: "INF request finished\n" +
+				"  status: 200\n" +
+				"  http:\n" +
+				"    method: GET\n" +
+				"    headers:\n" +
+				"      accept: json\n" +
+				"  body: line1\n" +
+				"    line2\n",
+		},
+		{
+			name:        "with attrs and groups",
+			attrs:       attrs[:1],
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithAttrs(attrs[1:2]).WithGroup("req") },
+			want: "INF request finished\n" +
+				"  http:\n" +
+				"    method: GET\n" +
+				"    headers:\n" +
+				"      accept: json\n" +
+				"  req.status: 200\n",
+		},
+		{
+			name:  "key value separator",
+			opts:  HandlerOptions{KeyValueSeparator: "="},
+			attrs: attrs[:2],
+			want: "INF request finished\n" +
+				"  status=200\n" +
+				"  http:\n" +
+				"    method=GET\n" +
+				"    headers:\n" +
+				"      accept=json\n",
+		},
+		{
+			name: "no attrs",
+			want: "INF request finished\n",
+		},
+		{
+			name:  "header attrs",
+			opts:  HandlerOptions{HeaderFormat: "%l %[status]h %m %a"},
+			attrs: attrs[:1],
+			want:  "INF 200 request finished\n",
+		},
+	}
+
+	for _, test := range tests {
+		test.opts.NoColor = true
+		test.opts.Pretty = true
+		if test.opts.HeaderFormat == "" {
+			test.opts.HeaderFormat = "%l %m %a"
+		}
+		test.msg = "request finished"
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestHandler_Pretty_ReusedOptions(t *testing.T) {
+	opts := &HandlerOptions{NoColor: true, Pretty: true, HeaderFormat: "%m %a"}
+
+	// the options aren't changed by NewHandler, so a second handler still
+	// uses Pretty's default separator
+	var buf1, buf2 bytes.Buffer
+	slog.New(NewHandler(&buf1, opts)).Info("msg", "k", 1)
+	AssertEqual(t, "", opts.KeyValueSeparator)
+	slog.New(NewHandler(&buf2, opts)).Info("msg", "k", 1)
+
+	AssertEqual(t, "msg\n  k: 1\n", buf1.String())
+	AssertEqual(t, "msg\n  k: 1\n", buf2.String())
+}
+
+func TestHandler_Normalize(t *testing.T) {
+	tests := []handlerTest{
+		{
+			name:  "off",
+			msg:   "a\rb",
+			attrs: []slog.Attr{slog.String("v", "c\td")},
+			want:  "a\rb v=c\td\n",
+		},
+		{
+			name:  "carriage returns",
+			opts:  HandlerOptions{NormalizeNewlines: true},
+			msg:   "done\r\n",
+			attrs: []slog.Attr{slog.String("progress", "10%\r20%"), slog.String("body", "line1\r\nline2")},
+			want:  "done progress=10%20%\n=== body ===\nline1\nline2\n",
+		},
+		{
+			name:  "tabs",
+			opts:  HandlerOptions{TabWidth: 4},
+			msg:   "a\tb",
+			attrs: []slog.Attr{slog.String("v", "c\td"), slog.String("stack", "main()\n\tmain.go:12\nab\tc\ndé\tf")},
+			want:  "a\tb v=c\td\n=== stack ===\nmain()\n    main.go:12\nab  c\ndé  f\n",
+		},
+		{
+			name:  "tabs in pretty mode",
+			opts:  HandlerOptions{TabWidth: 2, Pretty: true},
+			msg:   "a",
+			attrs: []slog.Attr{slog.String("stack", "main()\n\tmain.go:12")},
+			want:  "a\n  stack: main()\n      main.go:12\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = true
+		test.opts.HeaderFormat = "%m %a"
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestHandler_ContinuationPrefix(t *testing.T) {
+	tests := []handlerTest{
+		{
+			name:  "single line",
+			attrs: []slog.Attr{slog.Int("n", 1)},
+			want:  "msg n=1\n",
+		},
+		{
+			name:  "multiline attrs",
+			attrs: []slog.Attr{slog.Int("n", 1), slog.String("stack", "main()\n\tmain.go:12")},
+			want:  "msg n=1\n│ === stack ===\n│ main()\n│ \tmain.go:12\n",
+		},
+		{
+			name:  "pretty",
+			opts:  HandlerOptions{Pretty: true},
+			attrs: []slog.Attr{slog.Int("n", 1), slog.Group("g", slog.Int("m", 2))},
+			want:  "msg\n│   n: 1\n│   g:\n│     m: 2\n",
+		},
+		{
+			name:  "color",
+			opts:  HandlerOptions{Theme: Theme{Name: "test", Continuation: ToANSICode(Faint)}},
+			attrs: []slog.Attr{slog.String("body", "a\nb")},
+			want:  "msg\n" + styled("│ ", ToANSICode(Faint)) + "=== body ===\n" + styled("│ ", ToANSICode(Faint)) + "a\n" + styled("│ ", ToANSICode(Faint)) + "b\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = test.opts.Theme.Name == ""
+		test.opts.ContinuationPrefix = "│ "
+		test.opts.HeaderFormat = "%m %a"
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestHandler_RepeatHeader(t *testing.T) {
+	tests := []handlerTest{
+		{
+			name:  "single line",
+			attrs: []slog.Attr{slog.Int("n", 1)},
+			want:  "15:04:05 INF msg n=1\n",
+		},
+		{
+			name:  "multiline attrs",
+			attrs: []slog.Attr{slog.Int("n", 1), slog.String("stack", "main()\n\tmain.go:12")},
+			want:  "15:04:05 INF msg n=1\n15:04:05 INF === stack ===\n15:04:05 INF main()\n15:04:05 INF \tmain.go:12\n",
+		},
+		{
+			name: "multiline message",
+			msg:  "a\nb",
+			want: "15:04:05 INF a\n15:04:05 INF b\n",
+		},
+		{
+			name:  "continuation prefix",
+			opts:  HandlerOptions{ContinuationPrefix: "│ ", Pretty: true},
+			attrs: []slog.Attr{slog.Int("n", 1)},
+			want:  "15:04:05 INF msg\n15:04:05 INF │   n: 1\n",
+		},
+		{
+			name:  "no header",
+			opts:  HandlerOptions{HeaderFormat: "%m %a"},
+			attrs: []slog.Attr{slog.String("body", "a\nb")},
+			want:  "msg\n=== body ===\na\nb\n",
+		},
+		{
+			name:  "attrs before message",
+			opts:  HandlerOptions{HeaderFormat: "%l %a %m"},
+			attrs: []slog.Attr{slog.String("body", "a\nb")},
+			want:  "INF msg\nINF === body ===\nINF a\nINF b\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = true
+		test.opts.RepeatHeader = true
+		test.opts.TimeFormat = "15:04:05"
+		if test.opts.HeaderFormat == "" {
+			test.opts.HeaderFormat = "%t %l %m %a"
+		}
+		if test.msg == "" {
+			test.msg = "msg"
+		}
+		test.time = time.Date(2024, 01, 02, 15, 04, 05, 0, time.UTC)
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestHandler_MaxAttrs(t *testing.T) {
+	attrs := []slog.Attr{slog.Int("a", 1), slog.Int("b", 2), slog.Int("c", 3), slog.Int("d", 4)}
+	tests := []handlerTest{
+		{
+			name:  "under",
+			opts:  HandlerOptions{MaxAttrs: 4},
+			attrs: attrs,
+			want:  "msg a=1 b=2 c=3 d=4\n",
+		},
+		{
+			name:  "over",
+			opts:  HandlerOptions{MaxAttrs: 2},
+			attrs: attrs,
+			want:  "msg a=1 b=2 …(+2 more)\n",
+		},
+		{
+			name:  "groups",
+			opts:  HandlerOptions{MaxAttrs: 2},
+			attrs: []slog.Attr{slog.Int("a", 1), slog.Group("g", slog.Int("b", 2), slog.Int("c", 3)), slog.Group("h", slog.Int("d", 4))},
+			want:  "msg a=1 g.b=2 …(+2 more)\n",
+		},
+		{
+			name:        "with attrs",
+			opts:        HandlerOptions{MaxAttrs: 3},
+			attrs:       attrs[2:],
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithAttrs(attrs[:2]) },
+			want:        "msg a=1 b=2 c=3 …(+1 more)\n",
+		},
+		{
+			name:        "with too many attrs",
+			opts:        HandlerOptions{MaxAttrs: 1},
+			attrs:       attrs[2:],
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithAttrs(attrs[:2]) },
+			want:        "msg a=1 …(+3 more)\n",
+		},
+		{
+			name:  "header attrs don't count",
+			opts:  HandlerOptions{MaxAttrs: 1, HeaderFormat: "%[a]h %m %a"},
+			attrs: attrs[:3],
+			want:  "1 msg b=2 …(+1 more)\n",
+		},
+		{
+			name:  "pretty",
+			opts:  HandlerOptions{MaxAttrs: 1, Pretty: true},
+			attrs: attrs,
+			want:  "msg\n  a: 1\n  …(+3 more)\n",
+		},
+		{
+			name:  "color",
+			opts:  HandlerOptions{MaxAttrs: 1, Theme: Theme{Name: "test", Header: ToANSICode(Faint)}},
+			attrs: attrs[:2],
+			want:  "msg a=1 " + styled("…(+1 more)", ToANSICode(Faint)) + "\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = test.opts.Theme.Name == ""
+		if test.opts.HeaderFormat == "" {
+			test.opts.HeaderFormat = "%m %a"
+		}
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestHandler_RecordAttrsFirst(t *testing.T) {
+	tests := []handlerTest{
+		{
+			name:        "default",
+			attrs:       []slog.Attr{slog.Int("c", 3)},
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithAttrs([]slog.Attr{slog.Int("a", 1), slog.Int("b", 2)}) },
+			want:        "msg a=1 b=2 c=3\n",
+		},
+		{
+			name:        "record first",
+			opts:        HandlerOptions{RecordAttrsFirst: true},
+			attrs:       []slog.Attr{slog.Int("c", 3)},
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithAttrs([]slog.Attr{slog.Int("a", 1), slog.Int("b", 2)}) },
+			want:        "msg c=3 a=1 b=2\n",
+		},
+		{
+			name:  "groups",
+			opts:  HandlerOptions{RecordAttrsFirst: true},
+			attrs: []slog.Attr{slog.Int("c", 3)},
+			handlerFunc: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.Int("a", 1)}).WithGroup("g").WithAttrs([]slog.Attr{slog.Int("b", 2)})
+			},
+			want: "msg g.c=3 a=1 g.b=2\n",
+		},
+		{
+			name:        "compact",
+			opts:        HandlerOptions{RecordAttrsFirst: true, Compact: true},
+			attrs:       []slog.Attr{slog.Int("c", 3)},
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithAttrs([]slog.Attr{slog.Int("a", 1)}) },
+			want:        "msg c=3,a=1\n",
+		},
+		{
+			name:        "max attrs",
+			opts:        HandlerOptions{RecordAttrsFirst: true, MaxAttrs: 2},
+			attrs:       []slog.Attr{slog.Int("c", 3)},
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithAttrs([]slog.Attr{slog.Int("a", 1), slog.Int("b", 2)}) },
+			want:        "msg c=3 a=1 …(+1 more)\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = true
+		test.opts.HeaderFormat = "%m %a"
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+
+	// the context attrs follow the record's attrs too
+	buf := bytes.Buffer{}
+	l := slog.New(NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%m %a", AddContextAttrs: true, RecordAttrsFirst: true}))
+	l.With("a", 1).InfoContext(ContextWithAttrs(context.Background(), slog.String("req", "abc")), "msg", "c", 3)
+	AssertEqual(t, "msg c=3 a=1 req=abc\n", buf.String())
+}
+
+func TestHandler_AttrsGroupFilter(t *testing.T) {
+	attrs := []slog.Attr{
+		slog.Int("status", 200),
+		slog.Group("http", slog.String("method", "GET"), slog.Group("req", slog.String("path", "/"))),
+		slog.String("user", "bob"),
+	}
+	tests := []handlerTest{
+		{
+			name:  "filter",
+			attrs: attrs,
+			want:  "INF http.method=GET http.req.path=/ msg status=200 user=bob\n",
+		},
+		{
+			name:  "nested filter",
+			opts:  HandlerOptions{HeaderFormat: "%l %[http.req]a %m %a"},
+			attrs: attrs,
+			want:  "INF http.req.path=/ msg status=200 http.method=GET user=bob\n",
+		},
+		{
+			name:  "several filters",
+			opts:  HandlerOptions{HeaderFormat: "%l %{[%[http]a]%} %m %{(%[db]a)%} %a"},
+			attrs: attrs,
+			want:  "INF [http.method=GET http.req.path=/] msg status=200 user=bob\n",
+		},
+		{
+			name:        "with group",
+			attrs:       attrs[:1],
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithGroup("http") },
+			want:        "INF http.status=200 msg\n",
+		},
+		{
+			name:        "with attrs",
+			attrs:       attrs[2:],
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithAttrs(attrs[:2]) },
+			want:        "INF http.method=GET http.req.path=/ msg status=200 user=bob\n",
+		},
+		{
+			name:  "group separator",
+			opts:  HandlerOptions{GroupSeparator: "/", HeaderFormat: "%l %[http.req]a %m %a"},
+			attrs: attrs,
+			want:  "INF http/req/path=/ msg status=200 http/method=GET user=bob\n",
+		},
+		{
+			name:  "parens",
+			opts:  HandlerOptions{GroupFormat: GroupParens},
+			attrs: attrs,
+			want:  "INF http=(method=GET req=(path=/)) msg status=200 user=bob\n",
+		},
+		{
+			name:  "pretty",
+			opts:  HandlerOptions{Pretty: true},
+			attrs: attrs[:2],
+			want:  "INF msg\n  status: 200\n  http:\n    method: GET\n    req:\n      path: /\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = true
+		if test.opts.HeaderFormat == "" {
+			test.opts.HeaderFormat = "%l %[http]a %m %a"
+		}
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestHandler_JoinedErrors(t *testing.T) {
+	joined := errors.Join(
+		errors.New("a failed"),
+		nil,
+		fmt.Errorf("b: %w", errors.Join(errors.New("b1"), errors.New("b2"))),
+		errors.Join(errors.New("c1"), errors.New("c2")),
+	)
+	tests := []handlerTest{
+		{
+			name:  "joined",
+			attrs: []slog.Attr{slog.Any("err", joined), slog.Int("n", 1)},
+			want:  "msg n=1\n=== err ===\n- a failed\n- b: b1\n  b2\n- - c1\n  - c2\n",
+		},
+		{
+			name:  "single",
+			attrs: []slog.Attr{slog.Any("err", errors.Join(errors.New("only")))},
+			want:  "msg err=only\n",
+		},
+		{
+			name:  "logfmt",
+			opts:  HandlerOptions{Logfmt: true, HeaderFormat: "msg=%m %a"},
+			attrs: []slog.Attr{slog.Any("err", errors.Join(errors.New("a"), errors.New("b")))},
+			want:  `msg=msg err="- a\n- b"` + "\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = true
+		if test.opts.HeaderFormat == "" {
+			test.opts.HeaderFormat = "%m %a"
+		}
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestHandler_ErrorVerb(t *testing.T) {
+	theme := NewDefaultTheme()
+	tests := []handlerTest{
+		{
+			name:  "err",
+			attrs: []slog.Attr{slog.Int("n", 1), slog.Any("err", errors.New("boom"))},
+			want:  "INF boom msg n=1\n",
+		},
+		{
+			name:  "error",
+			attrs: []slog.Attr{slog.String("error", "boom"), slog.Int("n", 1)},
+			want:  "INF boom msg n=1\n",
+		},
+		{
+			name:  "missing",
+			attrs: []slog.Attr{slog.Int("n", 1)},
+			want:  "INF msg n=1\n",
+		},
+		{
+			name:  "preference",
+			attrs: []slog.Attr{slog.String("error", "second"), slog.String("err", "first")},
+			want:  "INF first msg error=second\n",
+		},
+		{
+			name:  "less preferred",
+			attrs: []slog.Attr{slog.String("err", "first"), slog.String("error", "second")},
+			want:  "INF first msg error=second\n",
+		},
+		{
+			name:  "error keys",
+			opts:  HandlerOptions{ErrorKeys: []string{"cause"}},
+			attrs: []slog.Attr{slog.String("err", "boom"), slog.String("cause", "why")},
+			want:  "INF why msg err=boom\n",
+		},
+		{
+			name:        "with attrs",
+			attrs:       []slog.Attr{slog.Int("n", 1)},
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithAttrs([]slog.Attr{slog.String("err", "boom")}) },
+			want:        "INF boom msg n=1\n",
+		},
+		{
+			name:  "width",
+			opts:  HandlerOptions{HeaderFormat: "%l %6e %m %a"},
+			attrs: []slog.Attr{slog.String("err", "boom")},
+			want:  "INF boom   msg\n",
+		},
+		{
+			name:  "joined",
+			attrs: []slog.Attr{slog.Any("err", errors.Join(errors.New("a"), nil, errors.Join(errors.New("b1"), errors.New("b2"))))},
+			want:  "INF a; b1; b2 msg\n",
+		},
+		{
+			name:  "joined width",
+			opts:  HandlerOptions{HeaderFormat: "%l %6e %m %a"},
+			attrs: []slog.Attr{slog.Any("err", errors.Join(errors.New("a"), errors.New("b")))},
+			want:  "INF a; b   msg\n",
+		},
+		{
+			name:  "color",
+			opts:  HandlerOptions{Theme: theme, HeaderFormat: "%e %a"},
+			attrs: []slog.Attr{slog.String("err", "boom")},
+			want:  styled("boom", theme.AttrValueError) + "\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = test.opts.Theme.Name == ""
+		if test.opts.HeaderFormat == "" {
+			test.opts.HeaderFormat = "%l %e %m %a"
+		}
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestHandler_HeaderFallbackKeys(t *testing.T) {
+	tests := []handlerTest{
+		{
+			name:  "first",
+			attrs: []slog.Attr{slog.String("request_id", "r1"), slog.Int("n", 1)},
+			want:  "INF r1 msg n=1\n",
+		},
+		{
+			name:  "fallback",
+			attrs: []slog.Attr{slog.String("correlation_id", "c1"), slog.Int("n", 1)},
+			want:  "INF c1 msg n=1\n",
+		},
+		{
+			name:  "preference",
+			attrs: []slog.Attr{slog.String("correlation_id", "c1"), slog.String("trace_id", "t1"), slog.String("request_id", "r1")},
+			want:  "INF r1 msg correlation_id=c1 trace_id=t1\n",
+		},
+		{
+			name:  "none",
+			attrs: []slog.Attr{slog.Int("n", 1)},
+			want:  "INF msg n=1\n",
+		},
+		{
+			name:  "groups",
+			opts:  HandlerOptions{HeaderFormat: "%l %[http.id|id]h %m %a"},
+			attrs: []slog.Attr{slog.String("id", "top"), slog.Group("http", slog.String("id", "h1"))},
+			want:  "INF h1 msg id=top\n",
+		},
+		{
+			name: "empty keys",
+			opts: HandlerOptions{HeaderFormat: "%l %[|]h %m"},
+			want: "INF %!h(MISSING_HEADER_NAME) msg\n",
+		},
+		{
+			// the attr from WithAttrs is printed with the other attrs
+			name:  "with attrs displaced",
+			attrs: []slog.Attr{slog.String("request_id", "r1")},
+			handlerFunc: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.String("trace_id", "t1")})
+			},
+			want: "INF r1 msg trace_id=t1\n",
+		},
+		{
+			name:  "with attrs preferred",
+			attrs: []slog.Attr{slog.String("trace_id", "t1")},
+			handlerFunc: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.String("request_id", "r1")})
+			},
+			want: "INF r1 msg trace_id=t1\n",
+		},
+		{
+			name:  "with attrs overridden",
+			attrs: []slog.Attr{slog.String("trace_id", "t2")},
+			handlerFunc: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.String("trace_id", "t1")})
+			},
+			want: "INF t2 msg\n",
+		},
+		{
+			name: "chained with attrs displaced",
+			handlerFunc: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.String("trace_id", "t1")}).WithAttrs([]slog.Attr{slog.String("request_id", "r1")})
+			},
+			want: "INF r1 msg trace_id=t1\n",
+		},
+		{
+			name:  "pretty with attrs displaced",
+			opts:  HandlerOptions{HeaderFormat: "%l %[request_id|trace_id|correlation_id]h %m %a", Pretty: true},
+			attrs: []slog.Attr{slog.String("request_id", "r1")},
+			handlerFunc: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.String("trace_id", "t1")})
+			},
+			want: "INF r1 msg\n  trace_id: t1\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = true
+		if test.opts.HeaderFormat == "" {
+			test.opts.HeaderFormat = "%l %[request_id|trace_id|correlation_id]h %m %a"
+		}
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestHandler_OmitZero(t *testing.T) {
+	zeros := []slog.Attr{
+		slog.String("s", ""),
+		slog.Int("i", 0),
+		slog.Uint64("u", 0),
+		slog.Float64("f", 0),
+		slog.Bool("b", false),
+		slog.Duration("d", 0),
+		slog.Time("t", time.Time{}),
+		slog.Any("a", nil),
+		slog.Any("sl", []int(nil)),
+		slog.Any("p", (*int)(nil)),
+		slog.Any("m", map[string]int(nil)),
+		slog.Any("e", error((*os.PathError)(nil))),
+		slog.Group("g", slog.Int("i", 0)),
+	}
+	tests := []handlerTest{
+		{
+			name:  "zeros",
+			opts:  HandlerOptions{OmitZero: true},
+			attrs: append(zeros, slog.Int("n", 1)),
+			want:  "msg n=1\n",
+		},
+		{
+			name:  "empty but not nil",
+			opts:  HandlerOptions{OmitZero: true},
+			attrs: []slog.Attr{slog.Any("sl", []int{}), slog.Any("m", map[string]int{})},
+			want:  "msg sl=[] m=map[]\n",
+		},
+		{
+			name:  "off",
+			attrs: zeros[:3],
+			want:  "msg s= i=0 u=0\n",
+		},
+		{
+			name:  "headers",
+			opts:  HandlerOptions{OmitZero: true, HeaderFormat: "%{[%[s]h]%} %m %a"},
+			attrs: zeros[:1],
+			want:  "msg\n",
+		},
+		{
+			name:        "with attrs",
+			opts:        HandlerOptions{OmitZero: true},
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithAttrs(zeros) },
+			want:        "msg\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = true
+		if test.opts.HeaderFormat == "" {
+			test.opts.HeaderFormat = "%m %a"
+		}
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestHandler_Compact(t *testing.T) {
+	attrs := []slog.Attr{slog.Int("a", 1), slog.Group("g", slog.Int("b", 2), slog.Int("c", 3)), slog.String("d", "x y")}
+	tests := []handlerTest{
+		{
+			name:  "compact",
+			attrs: attrs,
+			want:  "INF msg a=1,g.b=2,g.c=3,d=x y\n",
+		},
+		{
+			name:  "parens",
+			opts:  HandlerOptions{GroupFormat: GroupParens},
+			attrs: attrs,
+			want:  "INF msg a=1,g=(b=2,c=3),d=x y\n",
+		},
+		{
+			name:        "with attrs",
+			attrs:       attrs[2:],
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithAttrs(attrs[:1]) },
+			want:        "INF msg a=1,d=x y\n",
+		},
+		{
+			name:  "chained with attrs",
+			attrs: []slog.Attr{slog.Int("c", 3)},
+			handlerFunc: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs(attrs[:1]).WithAttrs([]slog.Attr{slog.Int("b", 2)}).WithGroup("g").WithAttrs([]slog.Attr{slog.Int("x", 1)})
+			},
+			want: "INF msg a=1,b=2,g.x=1,g.c=3\n",
+		},
+		{
+			name:  "commas quoted",
+			attrs: []slog.Attr{slog.String("d", "x,y=z"), slog.Group("g", slog.String("e", "1,2")), slog.String("f", "x y")},
+			want:  `INF msg d="x,y=z",g.e="1,2",f=x y` + "\n",
+		},
+		{
+			name:        "commas quoted in with attrs",
+			attrs:       []slog.Attr{slog.Int("n", 1)},
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithAttrs([]slog.Attr{slog.String("d", "x,y")}) },
+			want:        `INF msg d="x,y",n=1` + "\n",
+		},
+		{
+			name:  "commas in highlighted values quoted",
+			opts:  HandlerOptions{HighlightAttrValues: true},
+			attrs: []slog.Attr{slog.String("d", "x,y")},
+			want:  `INF msg d="x,y"` + "\n",
+		},
+		{
+			name:  "multiline",
+			attrs: []slog.Attr{slog.String("body", "a\nb"), slog.Int("n", 1)},
+			want:  "INF msg n=1\n=== body ===\na\nb\n",
+		},
+		{
+			name:  "logfmt",
+			opts:  HandlerOptions{Logfmt: true, HeaderFormat: "level=%L %a"},
+			attrs: attrs[:2],
+			want:  "level=INFO a=1 g.b=2 g.c=3\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = true
+		test.opts.Compact = true
+		if test.opts.HeaderFormat == "" {
+			test.opts.HeaderFormat = "%l %m %a"
+		}
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestHandler_KeyAliases(t *testing.T) {
+	aliases := map[string]string{"http.request.method": "method", "user_identifier": "user", "body": "b"}
+	attrs := []slog.Attr{
+		slog.Group("http", slog.Group("request", slog.String("method", "GET"), slog.String("path", "/"))),
+		slog.String("user_identifier", "bob"),
+	}
+	tests := []handlerTest{
+		{
+			name:  "aliases",
+			attrs: attrs,
+			want:  "msg method=GET http.request.path=/ user=bob\n",
+		},
+		{
+			name:        "with group",
+			attrs:       []slog.Attr{slog.String("method", "GET")},
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithGroup("http").WithGroup("request") },
+			want:        "msg method=GET\n",
+		},
+		{
+			name:  "group separator",
+			opts:  HandlerOptions{GroupSeparator: "/"},
+			attrs: attrs,
+			want:  "msg method=GET http/request/path=/ user=bob\n",
+		},
+		{
+			name:  "multiline",
+			attrs: []slog.Attr{slog.String("body", "a\nb")},
+			want:  "msg\n=== b ===\na\nb\n",
+		},
+		{
+			name: "replace attr sees the canonical key",
+			opts: HandlerOptions{ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == "user_identifier" {
+					a.Value = slog.StringValue("alice")
+				}
+				return a
+			}},
+			attrs: attrs[1:],
+			want:  "msg user=alice\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = true
+		test.opts.KeyAliases = aliases
+		test.opts.HeaderFormat = "%m %a"
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestHandler_KeyFormats(t *testing.T) {
+	formats := map[string]string{"latency": "%.2fms", "addr": "%q", "http.status": "%03d", "elapsed": "%v"}
+	tests := []handlerTest{
+		{
+			name:  "formats",
+			attrs: []slog.Attr{slog.Float64("latency", 1.5), slog.String("addr", "a b"), slog.Int("count", 2)},
+			want:  "msg latency=1.50ms addr=\"a b\" count=2\n",
+		},
+		{
+			name:  "in group",
+			attrs: []slog.Attr{slog.Group("http", slog.Int("status", 42)), slog.Int("status", 42)},
+			want:  "msg http.status=042 status=42\n",
+		},
+		{
+			name:        "with group",
+			opts:        HandlerOptions{GroupSeparator: "/"},
+			attrs:       []slog.Attr{slog.Int("status", 7)},
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithGroup("http") },
+			want:        "msg http/status=007\n",
+		},
+		{
+			name:  "durations",
+			attrs: []slog.Attr{slog.Duration("elapsed", 1500*time.Millisecond)},
+			want:  "msg elapsed=1.5s\n",
+		},
+		{
+			name:  "bad verb",
+			attrs: []slog.Attr{slog.String("latency", "slow")},
+			want:  "msg latency=%!f(string=sl)ms\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = true
+		test.opts.KeyFormats = formats
+		test.opts.HeaderFormat = "%m %a"
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+}
@@ -0,0 +1,79 @@
+package console
+
+import (
+	"log/slog"
+	"regexp"
+	"slices"
+)
+
+// HighlightRule styles the parts of messages, and optionally attribute values, which
+// match Pattern.  See HandlerOptions.HighlightRules.
+type HighlightRule struct {
+	Pattern *regexp.Regexp
+	Style   ANSIMod
+}
+
+// writeHighlightedValue writes value like writeColoredValue, or like writeAttrValue
+// if it's the value of an attr, and then applies the HighlightRules to it.
+func (e *encoder) writeHighlightedValue(buf *buffer, value slog.Value, style ANSIMod, attr bool) {
+	if len(e.h.opts.HighlightRules) == 0 || e.h.opts.NoColor || e.h.opts.Logfmt || (attr && e.h.opts.TextQuoting) {
+		if attr {
+			e.writeAttrValue(buf, value, style)
+		} else {
+			e.writeColoredValue(buf, value, style)
+		}
+		return
+	}
+	e.withColor(buf, style, func() {
+		l := len(*buf)
+		e.writeValue(buf, value)
+		if attr {
+			e.quoteValueFrom(buf, l)
+		}
+		e.highlightFrom(buf, l, style)
+	})
+}
+
+// highlightFrom wraps the matches of the HighlightRules in the text written to buf
+// since offset with the rules' styles, restoring style after each match.  Where
+// matches overlap, the one which starts first wins, and on ties, the earlier rule.
+func (e *encoder) highlightFrom(buf *buffer, offset int, style ANSIMod) {
+	text := (*buf)[offset:]
+	var spans [][3]int // start, end, rule
+	for i, rule := range e.h.opts.HighlightRules {
+		if rule.Pattern == nil {
+			continue
+		}
+		for _, m := range rule.Pattern.FindAllIndex(text, -1) {
+			if m[1] > m[0] {
+				spans = append(spans, [3]int{m[0], m[1], i})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return
+	}
+	slices.SortStableFunc(spans, func(a, b [3]int) int {
+		if a[0] != b[0] {
+			return a[0] - b[0]
+		}
+		return a[2] - b[2]
+	})
+
+	e.scratch = append(e.scratch[:0], text...)
+	*buf = (*buf)[:offset]
+	last := 0
+	for _, s := range spans {
+		if s[0] < last {
+			// overlaps the previous match
+			continue
+		}
+		buf.Append(e.scratch[last:s[0]])
+		buf.AppendString(string(e.h.opts.HighlightRules[s[2]].Style))
+		buf.Append(e.scratch[s[0]:s[1]])
+		buf.AppendString(string(ResetMod))
+		buf.AppendString(string(style))
+		last = s[1]
+	}
+	buf.Append(e.scratch[last:])
+}
@@ -0,0 +1,42 @@
+package console
+
+import (
+	"bytes"
+	"log/slog"
+	"regexp"
+	"testing"
+)
+
+func TestHandler_HighlightRules(t *testing.T) {
+	theme := NewDefaultTheme()
+	red := ToANSICode(Red)
+	under := ToANSICode(Underline)
+	rules := []HighlightRule{
+		{Pattern: regexp.MustCompile(`connection refused`), Style: red},
+		{Pattern: regexp.MustCompile(`order-\d+`), Style: under},
+		{Pattern: regexp.MustCompile(`refused by order-1`), Style: under}, // overlaps, loses
+		{Pattern: regexp.MustCompile(`x*`)},                               // empty matches are ignored
+		{},
+	}
+
+	log := func(opts HandlerOptions, msg string, args ...any) string {
+		buf := bytes.Buffer{}
+		opts.HeaderFormat = "%m %a"
+		opts.Theme = theme
+		opts.HighlightRules = rules
+		slog.New(NewHandler(&buf, &opts)).Info(msg, args...)
+		return buf.String()
+	}
+
+	msgStyle := string(theme.Message)
+	reset := string(ResetMod)
+	want := msgStyle + "dial: " + string(red) + "connection refused" + reset + msgStyle +
+		" by " + string(under) + "order-1" + reset + msgStyle + " and " + string(under) + "order-22" + reset + msgStyle + reset +
+		" " + styled("id=", theme.AttrKey) + "order-3\n"
+	AssertEqual(t, want, log(HandlerOptions{}, "dial: connection refused by order-1 and order-22", "id", "order-3"))
+
+	want = styled("ok", theme.Message) + " " + styled("id=", theme.AttrKey) + string(under) + "order-3" + reset + "\n"
+	AssertEqual(t, want, log(HandlerOptions{HighlightAttrValues: true}, "ok", "id", "order-3"))
+
+	AssertEqual(t, "dial: connection refused\n", log(HandlerOptions{NoColor: true}, "dial: connection refused"))
+}
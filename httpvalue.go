@@ -0,0 +1,75 @@
+package console
+
+import (
+	"net/http"
+)
+
+// writeRequest writes a compact summary of the request, like
+// "GET http://example.com/api [Accept: application/json]".  See
+// HandlerOptions.RenderHTTP.
+func (e *encoder) writeRequest(buf *buffer, req *http.Request) {
+	e.writeColoredString(buf, req.Method, ToANSICode(Bold))
+	buf.AppendByte(' ')
+	if req.URL != nil {
+		buf.AppendString(req.URL.String())
+	} else {
+		buf.AppendString(req.RequestURI)
+	}
+	e.writeHTTPHeaders(buf, req.Header)
+}
+
+// writeResponse writes a compact summary of the response, like
+// "404 Not Found GET http://example.com/api", with the status in the style of the
+// level matching its class: server errors like errors, client errors like warnings.
+func (e *encoder) writeResponse(buf *buffer, resp *http.Response) {
+	style := e.h.opts.Theme.LevelInfo
+	switch {
+	case resp.StatusCode >= 500:
+		style = e.h.opts.Theme.LevelError
+	case resp.StatusCode >= 400:
+		style = e.h.opts.Theme.LevelWarn
+	}
+	status := resp.Status
+	if status == "" {
+		status = http.StatusText(resp.StatusCode)
+		e.withColor(buf, style, func() {
+			buf.AppendInt(int64(resp.StatusCode))
+			buf.AppendByte(' ')
+			buf.AppendString(status)
+		})
+	} else {
+		e.writeColoredString(buf, status, style)
+	}
+	if resp.Request != nil {
+		buf.AppendByte(' ')
+		req := *resp.Request
+		// the headers of the response are more interesting
+		req.Header = nil
+		e.writeRequest(buf, &req)
+	}
+	e.writeHTTPHeaders(buf, resp.Header)
+}
+
+// writeHTTPHeaders writes the HandlerOptions.HTTPHeaders present in h, like
+// " [Content-Type: application/json]".
+func (e *encoder) writeHTTPHeaders(buf *buffer, h http.Header) {
+	first := true
+	for _, name := range e.h.opts.HTTPHeaders {
+		v := h.Get(name)
+		if v == "" {
+			continue
+		}
+		if first {
+			buf.AppendString(" [")
+			first = false
+		} else {
+			buf.AppendString(", ")
+		}
+		e.writeColoredString(buf, http.CanonicalHeaderKey(name)+":", e.h.opts.Theme.AttrKey)
+		buf.AppendByte(' ')
+		buf.AppendString(v)
+	}
+	if !first {
+		buf.AppendByte(']')
+	}
+}
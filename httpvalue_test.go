@@ -0,0 +1,63 @@
+package console
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_RenderHTTP(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://example.com/api?q=1", nil)
+	req.Header.Set("Accept", "application/json")
+	resp := &http.Response{
+		Status:     "404 Not Found",
+		StatusCode: 404,
+		Header:     http.Header{"Content-Type": {"text/plain"}},
+		Request:    req,
+	}
+	theme := NewDefaultTheme()
+
+	tests := []handlerTest{
+		{
+			name:  "request",
+			attrs: []slog.Attr{slog.Any("req", req)},
+			want:  "msg req=GET http://example.com/api?q=1\n",
+		},
+		{
+			name:  "request headers",
+			opts:  HandlerOptions{HTTPHeaders: []string{"accept", "X-Missing"}},
+			attrs: []slog.Attr{slog.Any("req", req)},
+			want:  "msg req=GET http://example.com/api?q=1 [Accept: application/json]\n",
+		},
+		{
+			name:  "response",
+			opts:  HandlerOptions{HTTPHeaders: []string{"Accept", "Content-Type"}},
+			attrs: []slog.Attr{slog.Any("resp", resp)},
+			want:  "msg resp=404 Not Found GET http://example.com/api?q=1 [Content-Type: text/plain]\n",
+		},
+		{
+			name:  "response without status text",
+			attrs: []slog.Attr{slog.Any("resp", &http.Response{StatusCode: 503})},
+			want:  "msg resp=503 Service Unavailable\n",
+		},
+		{
+			name:  "nil",
+			attrs: []slog.Attr{slog.Any("req", (*http.Request)(nil))},
+			want:  "msg req=<nil>\n",
+		},
+		{
+			name:  "color",
+			opts:  HandlerOptions{Theme: theme},
+			attrs: []slog.Attr{slog.Any("resp", &http.Response{Status: "500 Internal Server Error", StatusCode: 500})},
+			want:  styled("msg", theme.Message) + " " + styled("resp=", theme.AttrKey) + styled("500 Internal Server Error", theme.LevelError) + "\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = test.opts.Theme.Name == ""
+		test.opts.RenderHTTP = true
+		test.opts.HeaderFormat = "%m %a"
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+}
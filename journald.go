@@ -0,0 +1,60 @@
+package console
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// IsJournald reports whether the process's stdout or stderr is connected to the
+// systemd journal.  Like sd_journal_stream_fd(3) describes, it compares the device
+// and inode numbers systemd sets in the JOURNAL_STREAM environment variable with
+// those of stdout and stderr, since child processes inherit the variable even if
+// their output is redirected elsewhere.  It's always false on systems other than
+// Unix.  It can be used to enable HandlerOptions.JournaldPrefix:
+//
+//	console.NewHandler(os.Stderr, &console.HandlerOptions{JournaldPrefix: console.IsJournald()})
+func IsJournald() bool {
+	return isJournalStream(os.Getenv("JOURNAL_STREAM"), os.Stdout, os.Stderr)
+}
+
+// isJournalStream reports whether any of the files is the journal stream described
+// by env, the value of JOURNAL_STREAM, formatted as "device:inode".
+func isJournalStream(env string, files ...*os.File) bool {
+	devStr, inoStr, ok := strings.Cut(env, ":")
+	if !ok {
+		return false
+	}
+	dev, err := strconv.ParseUint(devStr, 10, 64)
+	if err != nil {
+		return false
+	}
+	ino, err := strconv.ParseUint(inoStr, 10, 64)
+	if err != nil {
+		return false
+	}
+	for _, f := range files {
+		if d, i, ok := fileID(f); ok && d == dev && i == ino {
+			return true
+		}
+	}
+	return false
+}
+
+// journaldPriority returns the sd-daemon priority prefix for the level.  Levels
+// between INFO and WARN map to NOTICE.
+func journaldPriority(l slog.Level) string {
+	switch {
+	case l >= slog.LevelError:
+		return "<3>" // LOG_ERR
+	case l >= slog.LevelWarn:
+		return "<4>" // LOG_WARNING
+	case l > slog.LevelInfo:
+		return "<5>" // LOG_NOTICE
+	case l == slog.LevelInfo:
+		return "<6>" // LOG_INFO
+	default:
+		return "<7>" // LOG_DEBUG
+	}
+}
@@ -0,0 +1,73 @@
+package console
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsJournald(t *testing.T) {
+	t.Setenv("JOURNAL_STREAM", "")
+	AssertEqual(t, false, IsJournald())
+	// inherited by a process whose output was redirected elsewhere
+	t.Setenv("JOURNAL_STREAM", "8:12345")
+	AssertEqual(t, false, IsJournald())
+
+	dir := t.TempDir()
+	stream, err := os.Create(filepath.Join(dir, "stream"))
+	AssertNoError(t, err)
+	defer stream.Close()
+	other, err := os.Create(filepath.Join(dir, "other"))
+	AssertNoError(t, err)
+	defer other.Close()
+	dev, ino, ok := fileID(stream)
+	if !ok {
+		t.Skip("device and inode numbers aren't available")
+	}
+	env := fmt.Sprintf("%d:%d", dev, ino)
+	AssertEqual(t, true, isJournalStream(env, other, stream))
+	AssertEqual(t, false, isJournalStream(env, other))
+	AssertEqual(t, false, isJournalStream("bogus", stream))
+	AssertEqual(t, false, isJournalStream(fmt.Sprintf("%d:x", dev), stream))
+}
+
+func TestJournaldPriority(t *testing.T) {
+	AssertEqual(t, "<3>", journaldPriority(slog.LevelError+4))
+	AssertEqual(t, "<3>", journaldPriority(slog.LevelError))
+	AssertEqual(t, "<4>", journaldPriority(slog.LevelWarn))
+	AssertEqual(t, "<5>", journaldPriority(slog.LevelInfo+2))
+	AssertEqual(t, "<6>", journaldPriority(slog.LevelInfo))
+	AssertEqual(t, "<7>", journaldPriority(slog.LevelDebug))
+	AssertEqual(t, "<7>", journaldPriority(slog.LevelDebug-4))
+}
+
+func TestHandler_JournaldPrefix(t *testing.T) {
+	tests := []handlerTest{
+		{
+			name: "single line",
+			lvl:  slog.LevelWarn,
+			want: "<4>WRN msg a=1\n",
+		},
+		{
+			name:  "multiline",
+			lvl:   slog.LevelError,
+			attrs: []slog.Attr{slog.String("body", "x\ny")},
+			want:  "<3>ERR msg a=1\n<3>=== body ===\n<3>x\n<3>y\n",
+		},
+		{
+			// colors are disabled
+			name: "color",
+			opts: HandlerOptions{Theme: NewDefaultTheme()},
+			want: "<6>INF msg a=1\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.JournaldPrefix = true
+		test.opts.HeaderFormat = "%l %m %a"
+		test.msg = "msg"
+		test.attrs = append([]slog.Attr{slog.Int("a", 1)}, test.attrs...)
+		t.Run(test.name, test.run)
+	}
+}
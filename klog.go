@@ -0,0 +1,65 @@
+package console
+
+import "log/slog"
+
+// KlogHeaderFormat is a HeaderFormat which reproduces the header of klog and glog,
+// the loggers of Kubernetes and many Google projects, like
+// "I0102 15:04:05.000000 file.go:123] msg", so tools which parse that header work on
+// this handler's output too.  It's meant to be used with KlogTimeFormat, KlogLevels,
+// and AddSource, with the source truncated to the file name: see KlogOptions.
+//
+// Unlike klog, the header doesn't have a thread ID, and the attributes are printed
+// after the message, as usual.
+const KlogHeaderFormat = "%l%t %s] %m %a"
+
+// KlogTimeFormat is the TimeFormat of klog's header, the month and day, and the time
+// with microseconds, like "0102 15:04:05.000000".
+const KlogTimeFormat = "0102 15:04:05.000000"
+
+// KlogOptions returns the options which print records with klog's header, like
+// "I0102 15:04:05.000000 file.go:123] msg": KlogHeaderFormat, KlogTimeFormat,
+// KlogLevels, and the source, truncated to the file name.  The options can be changed
+// before they're passed to NewHandler, e.g. to set the Level, or to chain another
+// ReplaceAttr function after KlogLevels with ChainReplaceAttr.
+func KlogOptions() *HandlerOptions {
+	return &HandlerOptions{
+		AddSource:          true,
+		TruncateSourcePath: 1,
+		TimeFormat:         KlogTimeFormat,
+		HeaderFormat:       KlogHeaderFormat,
+		ReplaceAttr:        KlogLevels(),
+	}
+}
+
+// KlogSeverity returns the letter klog prints for the severity of the level: "I"
+// for info, "W" for warning, "E" for error, and "F" for fatal, i.e. levels
+// slog.LevelError+4 and up.  klog has no debug severity, since its verbose logs are
+// info logs, so levels below info are "I" too.
+func KlogSeverity(l slog.Level) string {
+	switch {
+	case l >= slog.LevelError+4:
+		return "F"
+	case l >= slog.LevelError:
+		return "E"
+	case l >= slog.LevelWarn:
+		return "W"
+	default:
+		return "I"
+	}
+}
+
+// KlogLevels returns a ReplaceAttr function which prints the record's level as its
+// KlogSeverity letter.  The letter is still printed in the level's style.  Like
+// all ReplaceAttr functions, it also sees the top level attributes with the key
+// slog.LevelKey, so their slog.Level values are printed as letters too.
+func KlogLevels() ReplaceAttrFunc {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) > 0 || a.Key != slog.LevelKey {
+			return a
+		}
+		if l, ok := a.Value.Any().(slog.Level); ok {
+			a.Value = slog.StringValue(KlogSeverity(l))
+		}
+		return a
+	}
+}
@@ -0,0 +1,89 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestKlogOptions(t *testing.T) {
+	buf := bytes.Buffer{}
+	opts := KlogOptions()
+	opts.Level = slog.LevelDebug
+	opts.NoColor = true
+	h := NewHandler(&buf, opts)
+
+	testTime := time.Date(2024, 1, 2, 15, 4, 5, 123456789, time.UTC)
+	pc, _, line, _ := runtime.Caller(0)
+
+	tests := []struct {
+		lvl  slog.Level
+		want string
+	}{
+		{slog.LevelDebug, "I"},
+		{slog.LevelInfo, "I"},
+		{slog.LevelWarn, "W"},
+		{slog.LevelError, "E"},
+		{slog.LevelError + 4, "F"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want+levelName(tt.lvl, true), func(t *testing.T) {
+			buf.Reset()
+			rec := slog.NewRecord(testTime, tt.lvl, "msg", pc)
+			rec.AddAttrs(slog.Int("n", 1))
+			AssertNoError(t, h.Handle(context.Background(), rec))
+			want := fmt.Sprintf("%s0102 15:04:05.123456 klog_test.go:%d] msg n=1\n", tt.want, line)
+			AssertEqual(t, want, buf.String())
+		})
+	}
+}
+
+func TestKlogSeverity(t *testing.T) {
+	tests := map[slog.Level]string{
+		slog.LevelDebug - 4: "I",
+		slog.LevelDebug:     "I",
+		slog.LevelInfo:      "I",
+		slog.LevelInfo + 2:  "I",
+		slog.LevelWarn:      "W",
+		slog.LevelError:     "E",
+		slog.LevelError + 3: "E",
+		slog.LevelError + 4: "F",
+		slog.LevelError + 8: "F",
+	}
+	for l, want := range tests {
+		AssertEqual(t, want, KlogSeverity(l))
+	}
+}
+
+func TestKlogLevels(t *testing.T) {
+	tests := []handlerTest{
+		{
+			name: "level replaced",
+			opts: HandlerOptions{ReplaceAttr: KlogLevels(), HeaderFormat: "%l %m %a", NoColor: true},
+			msg:  "level replaced",
+			lvl:  slog.LevelWarn,
+			want: "W level replaced\n",
+		},
+		{
+			name:  "level attrs",
+			opts:  HandlerOptions{ReplaceAttr: KlogLevels(), HeaderFormat: "%l %m %a", NoColor: true},
+			msg:   "level attrs",
+			attrs: []slog.Attr{slog.Any("level", slog.LevelError), slog.Group("g", slog.Any("level", slog.LevelError))},
+			want:  "I level attrs level=E g.level=ERROR\n",
+		},
+		{
+			name: "styled",
+			opts: HandlerOptions{ReplaceAttr: KlogLevels(), HeaderFormat: "%l %m", Theme: NewDefaultTheme()},
+			msg:  "styled",
+			lvl:  slog.LevelError,
+			want: styled("E", NewDefaultTheme().LevelError) + " " + styled("styled", NewDefaultTheme().Message) + "\n",
+		},
+	}
+	for _, tt := range tests {
+		tt.run(t)
+	}
+}
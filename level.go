@@ -0,0 +1,74 @@
+package console
+
+import (
+	"log/slog"
+	"strconv"
+)
+
+// The names of the levels in this range are precomputed, so custom levels, like
+// "DBG+1", don't have to be formatted for each record.
+const (
+	minCachedLevel = slog.LevelDebug - 16
+	maxCachedLevel = slog.LevelError + 16
+)
+
+// levelNames holds the precomputed level names, the full names first, and then the
+// abbreviated ones.
+var levelNames = func() (names [2][maxCachedLevel - minCachedLevel + 1]string) {
+	for l := minCachedLevel; l <= maxCachedLevel; l++ {
+		names[0][l-minCachedLevel] = formatLevelName(l, false)
+		names[1][l-minCachedLevel] = formatLevelName(l, true)
+	}
+	return names
+}()
+
+// levelName returns the name of the level, like "INF", or "INFO" if not
+// abbreviated.  Levels between the standard levels are printed relative to the
+// level below them, like "INF+2".
+func levelName(l slog.Level, abbreviated bool) string {
+	if l < minCachedLevel || l > maxCachedLevel {
+		return formatLevelName(l, abbreviated)
+	}
+	if abbreviated {
+		return levelNames[1][l-minCachedLevel]
+	}
+	return levelNames[0][l-minCachedLevel]
+}
+
+func formatLevelName(l slog.Level, abbreviated bool) string {
+	var str string
+	var delta int
+	switch {
+	case l >= slog.LevelError:
+		str = "ERR"
+		if !abbreviated {
+			str = "ERROR"
+		}
+		delta = int(l - slog.LevelError)
+	case l >= slog.LevelWarn:
+		str = "WRN"
+		if !abbreviated {
+			str = "WARN"
+		}
+		delta = int(l - slog.LevelWarn)
+	case l >= slog.LevelInfo:
+		str = "INF"
+		if !abbreviated {
+			str = "INFO"
+		}
+		delta = int(l - slog.LevelInfo)
+	default:
+		str = "DBG"
+		if !abbreviated {
+			str = "DEBUG"
+		}
+		delta = int(l - slog.LevelDebug)
+	}
+	if delta > 0 {
+		str += "+"
+	}
+	if delta != 0 {
+		str += strconv.Itoa(delta)
+	}
+	return str
+}
@@ -0,0 +1,34 @@
+package console
+
+import (
+	"fmt"
+	"log/slog"
+	"testing"
+)
+
+func TestLevelName(t *testing.T) {
+	tests := []struct {
+		lvl        slog.Level
+		abbr, full string
+	}{
+		{slog.LevelInfo, "INF", "INFO"},
+		{slog.LevelError + 15, "ERR+15", "ERROR+15"},
+		{slog.LevelWarn - 1, "INF+3", "INFO+3"},
+		{slog.LevelDebug - 1, "DBG-1", "DEBUG-1"},
+		{minCachedLevel - 1, "DBG-17", "DEBUG-17"},
+		{maxCachedLevel + 1, "ERR+17", "ERROR+17"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(int(test.lvl)), func(t *testing.T) {
+			AssertEqual(t, test.abbr, levelName(test.lvl, true))
+			AssertEqual(t, test.full, levelName(test.lvl, false))
+		})
+	}
+}
+
+func TestLevelName_Allocs(t *testing.T) {
+	allocs := testing.AllocsPerRun(10, func() {
+		_ = levelName(slog.LevelDebug+1, true)
+	})
+	AssertEqual(t, 0.0, allocs)
+}
@@ -0,0 +1,158 @@
+package console
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// levelRegistry holds the levels set with SetLevelFor and SetPackageLevel.  It's
+// shared by a Handler and all the handlers derived from it.
+type levelRegistry struct {
+	mu       sync.RWMutex
+	names    map[string]slog.Leveler
+	packages map[string]slog.Leveler
+	// numPackages is the number of package levels, so records don't have
+	// to look up their package if there aren't any
+	numPackages atomic.Int32
+	// numNames is the number of logger name levels
+	numNames atomic.Int32
+}
+
+// SetLevelFor sets the minimum level of the records logged by the loggers named
+// name, and the loggers nested under them, overriding the level of the handler.  For
+// example, after SetLevelFor("http", slog.LevelWarn), the loggers named "http" and
+// "http.client" only log warnings and errors, unless a more specific name has its
+// own level.  See [Named].  If level is nil, the name's level is removed.  Like
+// SetLevel, the levels are shared by all the handlers derived from the same root
+// handler.
+func (h *Handler) SetLevelFor(name string, level slog.Leveler) {
+	h.levels.set(&h.levels.names, &h.levels.numNames, name, level)
+}
+
+// SetPackageLevel sets the minimum level of the records logged from the Go package
+// with the import path pkg, and the packages under it, like SetLevelFor does for
+// logger names.  For example, after SetPackageLevel("github.com/acme/app/db",
+// slog.LevelDebug), debug records logged from the db package, and its subpackages,
+// are printed.  The package of a record is the package of its source, so records
+// without a source are logged at the handler's level.
+func (h *Handler) SetPackageLevel(pkg string, level slog.Leveler) {
+	h.levels.set(&h.levels.packages, &h.levels.numPackages, pkg, level)
+}
+
+// WithGroupLevel returns a new Handler, like WithGroup(name), whose records are
+// logged if they're at or above level, rather than the handler's level, so a noisy
+// subsystem can have a higher threshold than the rest of the application, without a
+// separate handler:
+//
+//	db := slog.New(h.WithGroupLevel("db", slog.LevelWarn))
+//
+// The level applies to the handlers derived from the new one, until another call to
+// WithGroupLevel.  Like the handler's level, it's overridden by the levels set with
+// SetLevelFor, SetPackageLevel, and WithMinLevel.  If level is nil, the handler's
+// level is used again.
+func (h *Handler) WithGroupLevel(name string, level slog.Leveler) *Handler {
+	h2 := *h.WithGroup(name).(*Handler)
+	h2.groupLevel = level
+	return &h2
+}
+
+func (r *levelRegistry) set(m *map[string]slog.Leveler, n *atomic.Int32, key string, level slog.Leveler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if level == nil {
+		delete(*m, key)
+	} else {
+		if *m == nil {
+			*m = map[string]slog.Leveler{}
+		}
+		(*m)[key] = level
+	}
+	n.Store(int32(len(*m)))
+}
+
+// lookup returns the level of the longest key in m which is a prefix of s, ending
+// at a separator.
+func (r *levelRegistry) lookup(m map[string]slog.Leveler, s string, sep byte) (slog.Level, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for prefix := s; prefix != ""; {
+		if l, ok := m[prefix]; ok {
+			return l.Level(), true
+		}
+		idx := strings.LastIndexByte(prefix, sep)
+		if idx < 0 {
+			break
+		}
+		prefix = prefix[:idx]
+	}
+	return 0, false
+}
+
+// minPackageLevel returns the lowest of the package levels.
+func (r *levelRegistry) minPackageLevel() (slog.Level, bool) {
+	if r.numPackages.Load() == 0 {
+		return 0, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var min slog.Level
+	found := false
+	for _, l := range r.packages {
+		if ll := l.Level(); !found || ll < min {
+			min, found = ll, true
+		}
+	}
+	return min, found
+}
+
+// nameLevel returns the minimum level of the records of the handler's logger,
+// which is the level of its name, if set, or the level of its group, if set with
+// WithGroupLevel, or the handler's level.
+func (h *Handler) nameLevel() slog.Level {
+	if h.name != "" && h.levels.numNames.Load() > 0 {
+		if l, ok := h.levels.lookup(h.levels.names, h.name, '.'); ok {
+			return l
+		}
+	}
+	if h.groupLevel != nil {
+		return h.groupLevel.Level()
+	}
+	return h.Level()
+}
+
+// packageEnabled reports whether the record should be logged, according to the
+// level of the package it was logged from.  Enabled let the record through if any
+// package level allows it, so its level is checked again here.
+func (h *Handler) packageEnabled(ctx context.Context, rec slog.Record) bool {
+	if h.levels.numPackages.Load() == 0 {
+		return true
+	}
+	if _, ok := MinLevelFromContext(ctx); ok {
+		// the context's level overrides the others, and was checked by Enabled
+		return true
+	}
+	if rec.PC == 0 {
+		// records without a source have no package
+		return rec.Level >= h.nameLevel()
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{rec.PC}).Next()
+	if l, ok := h.levels.lookup(h.levels.packages, packagePath(frame.Function), '/'); ok {
+		return rec.Level >= l
+	}
+	return rec.Level >= h.nameLevel()
+}
+
+// packagePath returns the import path of the package of a function, from its fully
+// qualified name, like "github.com/acme/app/db.(*Conn).Query".  The runtime escapes
+// the dots in the last element of the path, like "gopkg.in/yaml%2ev3.Unmarshal".
+func packagePath(function string) string {
+	lastSlash := strings.LastIndexByte(function, '/')
+	if dot := strings.IndexByte(function[lastSlash+1:], '.'); dot >= 0 {
+		function = function[:lastSlash+1+dot]
+	}
+	return strings.ReplaceAll(function, "%2e", ".")
+}
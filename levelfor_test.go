@@ -0,0 +1,134 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_SetLevelFor(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %[logger]h > %m"})
+	h.SetLevelFor("http", slog.LevelWarn)
+	h.SetLevelFor("http.server", slog.LevelDebug)
+
+	root := slog.New(h)
+	client := slog.New(h.WithName("http").WithName("client"))
+	server := slog.New(h.WithName("http").WithName("server"))
+
+	root.Info("root")
+	client.Info("hidden")
+	client.Warn("client")
+	server.Debug("server")
+	AssertEqual(t, "INF > root\nWRN http.client > client\nDBG http.server > server\n", buf.String())
+
+	// the context's level overrides the name's
+	buf.Reset()
+	client.InfoContext(WithMinLevel(context.Background(), slog.LevelInfo), "traced")
+	AssertEqual(t, "INF http.client > traced\n", buf.String())
+
+	// a nil level removes the name's level
+	buf.Reset()
+	h.SetLevelFor("http", nil)
+	client.Info("shown")
+	AssertEqual(t, "INF http.client > shown\n", buf.String())
+}
+
+func TestHandler_WithGroupLevel(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %m %a"})
+
+	db := slog.New(h.WithGroupLevel("db", slog.LevelWarn))
+	db.Info("hidden")
+	db.Warn("slow", "ms", 200)
+	db.With("table", "users").Info("hidden")
+	slog.New(h).Info("root")
+	AssertEqual(t, "WRN slow db.ms=200\nINF root\n", buf.String())
+
+	// nested groups inherit the level, until it's replaced
+	buf.Reset()
+	verbose := h.WithGroupLevel("db", slog.LevelWarn).WithGroup("pool").(*Handler).WithGroupLevel("conn", slog.LevelDebug)
+	slog.New(verbose).Debug("dial", "addr", "db:5432")
+	AssertEqual(t, "DBG dial db.pool.conn.addr=db:5432\n", buf.String())
+
+	// name levels and the context's level override the group's
+	buf.Reset()
+	h.SetLevelFor("store", slog.LevelInfo)
+	named := slog.New(h.WithGroupLevel("db", slog.LevelError).WithName("store"))
+	named.Info("named")
+	db.InfoContext(WithMinLevel(context.Background(), slog.LevelInfo), "traced")
+	AssertEqual(t, "INF named logger=store\nINF traced\n", buf.String())
+
+	// a nil level restores the handler's level
+	buf.Reset()
+	slog.New(h.WithGroupLevel("db", slog.LevelWarn).WithGroupLevel("q", nil)).Info("shown")
+	AssertEqual(t, "INF shown\n", buf.String())
+
+	// the handler isn't changed, even if the group is empty
+	AssertEqual(t, false, h.WithGroupLevel("", slog.LevelError) == h)
+	AssertEqual(t, true, h.Enabled(context.Background(), slog.LevelInfo))
+}
+
+func TestHandler_SetPackageLevel(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %m"})
+	l := slog.New(h)
+
+	h.SetPackageLevel("github.com/ansel1/console-slog", slog.LevelDebug)
+	AssertEqual(t, true, h.Enabled(context.Background(), slog.LevelDebug))
+	l.Debug("pkg")
+	h.SetPackageLevel("github.com/ansel1", slog.LevelError)
+	l.Debug("still pkg")
+	h.SetPackageLevel("github.com/ansel1/console-slog", nil)
+	l.Warn("hidden")
+	l.Error("parent")
+	h.SetPackageLevel("github.com/other", slog.LevelDebug)
+	h.SetPackageLevel("github.com/ansel1", nil)
+	// records from packages without a level are logged at the handler's level
+	l.Debug("hidden")
+	l.Info("default")
+	AssertEqual(t, "DBG pkg\nDBG still pkg\nERR parent\nINF default\n", buf.String())
+}
+
+func TestHandler_SetPackageLevel_NoSource(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %m"})
+	h.SetPackageLevel("github.com/ansel1/console-slog", slog.LevelDebug)
+
+	// records without a source are logged at the handler's level
+	AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelDebug, "hidden", 0)))
+	AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "shown", 0)))
+	AssertEqual(t, "INF shown\n", buf.String())
+}
+
+func TestHandler_SetPackageLevel_OnRecord(t *testing.T) {
+	buf := bytes.Buffer{}
+	var seen []string
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %m", OnRecord: func(_ context.Context, rec *slog.Record) {
+		seen = append(seen, rec.Message)
+	}})
+	h.SetPackageLevel("github.com/ansel1/console-slog", slog.LevelError)
+
+	// the hook only sees the records which are logged
+	l := slog.New(h)
+	l.Warn("hidden")
+	l.Error("shown")
+	AssertEqual(t, "ERR shown\n", buf.String())
+	AssertEqual(t, "shown", strings.Join(seen, ","))
+}
+
+func TestPackagePath(t *testing.T) {
+	tests := map[string]string{
+		"github.com/acme/app/db.(*Conn).Query": "github.com/acme/app/db",
+		"github.com/acme/app.Run.func1":        "github.com/acme/app",
+		"main.main":                            "main",
+		"net/http.(*Client).Do":                "net/http",
+		"gopkg.in/yaml%2ev3.Unmarshal":         "gopkg.in/yaml.v3",
+	}
+	for fn, want := range tests {
+		AssertEqual(t, want, packagePath(fn))
+	}
+}
@@ -0,0 +1,97 @@
+package console
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+type levelPayload struct {
+	Level string `json:"level"`
+}
+
+type levelErrorPayload struct {
+	Error string `json:"error"`
+}
+
+// LevelHTTPHandler returns an http.Handler which reports and changes the level of h,
+// so the verbosity of a running service can be adjusted without restarting it:
+//
+//	http.Handle("/log/level", console.LevelHTTPHandler(h))
+//
+// GET responds with the current level, as JSON:
+//
+//	{"level":"INFO"}
+//
+// PUT changes the level with [Handler.SetLevel], and responds with the new level.
+// The level can be sent as a JSON body in the same form, a form encoded body, a
+// plain text body, or a "level" query parameter:
+//
+//	curl -X PUT localhost:8080/log/level -d '{"level":"debug"}'
+//	curl -X PUT localhost:8080/log/level -d level=info
+//	curl -X PUT localhost:8080/log/level -d warn
+//	curl -X PUT localhost:8080/log/level?level=error
+//
+// Levels are parsed with [slog.Level.UnmarshalText], so they are case-insensitive,
+// and may include offsets, like "INFO+2".
+func LevelHTTPHandler(h *Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			lvl, err := parseLevelRequest(r)
+			if err != nil {
+				writeLevelJSON(w, http.StatusBadRequest, levelErrorPayload{Error: err.Error()})
+				return
+			}
+			h.SetLevel(lvl)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			writeLevelJSON(w, http.StatusMethodNotAllowed, levelErrorPayload{Error: "only GET and PUT are supported"})
+			return
+		}
+		writeLevelJSON(w, http.StatusOK, levelPayload{Level: h.Level().String()})
+	})
+}
+
+// parseLevelRequest reads the level from the "level" query parameter, or from the
+// body, which may be JSON, form encoded, or just the level in plain text.
+func parseLevelRequest(r *http.Request) (slog.Level, error) {
+	var lvl slog.Level
+	text := r.URL.Query().Get("level")
+	if text == "" {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1024))
+		if err != nil {
+			return lvl, err
+		}
+		body = bytes.TrimSpace(body)
+		if len(body) > 0 && body[0] == '{' {
+			var p levelPayload
+			if err := json.Unmarshal(body, &p); err != nil {
+				return lvl, err
+			}
+			text = p.Level
+		} else if vals, err := url.ParseQuery(string(body)); err == nil && vals.Has("level") {
+			text = vals.Get("level")
+		} else {
+			text = string(body)
+		}
+	}
+	if text == "" {
+		return lvl, errors.New("missing level")
+	}
+	if err := lvl.UnmarshalText([]byte(text)); err != nil {
+		return lvl, err
+	}
+	return lvl, nil
+}
+
+func writeLevelJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,55 @@
+package console
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLevelHTTPHandler(t *testing.T) {
+	h := NewHandler(io.Discard, nil)
+	srv := httptest.NewServer(LevelHTTPHandler(h))
+	defer srv.Close()
+
+	do := func(method, url, contentType, body string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+url, strings.NewReader(body))
+		AssertNoError(t, err)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		AssertNoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		AssertNoError(t, err)
+		return resp.StatusCode, strings.TrimSpace(string(b))
+	}
+
+	tests := []struct {
+		method, url, contentType, body string
+		wantStatus                     int
+		wantBody                       string
+		wantLevel                      slog.Level
+	}{
+		{http.MethodGet, "", "", "", http.StatusOK, `{"level":"INFO"}`, slog.LevelInfo},
+		{http.MethodPut, "", "application/json", `{"level":"debug"}`, http.StatusOK, `{"level":"DEBUG"}`, slog.LevelDebug},
+		{http.MethodPut, "", "application/x-www-form-urlencoded", `level=warn`, http.StatusOK, `{"level":"WARN"}`, slog.LevelWarn},
+		{http.MethodPut, "", "application/x-www-form-urlencoded", `{"level":"info+2"}`, http.StatusOK, `{"level":"INFO+2"}`, slog.LevelInfo + 2},
+		{http.MethodPut, "", "text/plain", "error\n", http.StatusOK, `{"level":"ERROR"}`, slog.LevelError},
+		{http.MethodPut, "?level=DEBUG", "", "", http.StatusOK, `{"level":"DEBUG"}`, slog.LevelDebug},
+		{http.MethodPut, "", "", "", http.StatusBadRequest, `{"error":"missing level"}`, slog.LevelDebug},
+		{http.MethodPut, "", "", "loud", http.StatusBadRequest, `{"error":"slog: level string \"loud\": unknown name"}`, slog.LevelDebug},
+		{http.MethodPost, "", "", "", http.StatusMethodNotAllowed, `{"error":"only GET and PUT are supported"}`, slog.LevelDebug},
+	}
+
+	for _, tt := range tests {
+		status, body := do(tt.method, tt.url, tt.contentType, tt.body)
+		AssertEqual(t, tt.wantStatus, status)
+		AssertEqual(t, tt.wantBody, body)
+		AssertEqual(t, tt.wantLevel, h.Level())
+	}
+}
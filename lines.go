@@ -0,0 +1,71 @@
+package console
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxLineBytes is the size of the longest line a LineWriter keeps until its end
+// is written.  Longer lines are handled in pieces of this size.
+const maxLineBytes = 1 << 20
+
+// LineWriter is an io.Writer which handles each line written to it, without the
+// newline, as returned by NewProcessWriter, NewStdlogWriter, and NewZerologWriter.
+// Lines may be split across writes, and several lines may be written at once.  A
+// trailing incomplete line is kept until the next write, or Flush, unless it grows
+// over 1 MiB, when it's handled as it is, and the rest of it as another line.  It is
+// safe for concurrent use.
+type LineWriter struct {
+	mu     sync.Mutex
+	handle func(line []byte) error
+	// pending is an incomplete line at the end of the last write
+	pending []byte
+}
+
+// Write handles the complete lines in p, and keeps the rest until the next write.
+// Since all of p is consumed, it returns len(p), even with the first error returned
+// by the handler of a line.
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	b := p
+	if len(w.pending) > 0 {
+		w.pending = append(w.pending, p...)
+		b = w.pending
+	}
+	var err error
+	for {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			if len(b) < maxLineBytes {
+				break
+			}
+			// too long to keep
+			i = maxLineBytes
+			if herr := w.handle(b[:i]); err == nil {
+				err = herr
+			}
+			b = b[i:]
+			continue
+		}
+		if herr := w.handle(bytes.TrimSuffix(b[:i], []byte{'\r'})); err == nil {
+			err = herr
+		}
+		b = b[i+1:]
+	}
+	w.pending = append(w.pending[:0], b...)
+	return len(p), err
+}
+
+// Flush handles the incomplete line written last, if any.
+func (w *LineWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) == 0 {
+		return nil
+	}
+	err := w.handle(w.pending)
+	w.pending = w.pending[:0]
+	return err
+}
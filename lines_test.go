@@ -0,0 +1,53 @@
+package console
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := &LineWriter{handle: func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	}}
+
+	n, err := w.Write([]byte("a\r\nb\nc"))
+	AssertNoError(t, err)
+	AssertEqual(t, 6, n)
+	_, _ = w.Write([]byte("d\ne"))
+	AssertEqual(t, "a,b,cd", strings.Join(lines, ","))
+	AssertNoError(t, w.Flush())
+	AssertEqual(t, "a,b,cd,e", strings.Join(lines, ","))
+
+	// long lines are handled in pieces, instead of being kept
+	lines = nil
+	_, _ = w.Write([]byte(strings.Repeat("x", maxLineBytes-1)))
+	_, _ = w.Write([]byte("yz"))
+	AssertEqual(t, 1, len(lines))
+	AssertEqual(t, maxLineBytes, len(lines[0]))
+	AssertEqual(t, 1, len(w.pending))
+	AssertNoError(t, w.Flush())
+	AssertEqual(t, "z", lines[1])
+}
+
+func TestLineWriter_Error(t *testing.T) {
+	var lines []string
+	w := &LineWriter{handle: func(line []byte) error {
+		lines = append(lines, string(line))
+		if string(line) == "bad" {
+			return errors.New("nope")
+		}
+		return nil
+	}}
+
+	// the lines after the failed one are still handled, and none is repeated
+	p := []byte("ok\nbad\nfine\nrest")
+	n, err := w.Write(p)
+	AssertError(t, err)
+	AssertEqual(t, len(p), n)
+	AssertEqual(t, "ok,bad,fine", strings.Join(lines, ","))
+	AssertNoError(t, w.Flush())
+	AssertEqual(t, "ok,bad,fine,rest", strings.Join(lines, ","))
+}
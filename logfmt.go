@@ -0,0 +1,161 @@
+package console
+
+import (
+	"bytes"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultLogfmtHeaderFormat is the default HeaderFormat when HandlerOptions.Logfmt is set.
+const defaultLogfmtHeaderFormat = "time=%t level=%L %{source=%s%} msg=%m %a"
+
+// defaultLogfmtTimeFormat is the default TimeFormat when HandlerOptions.Logfmt is set.
+const defaultLogfmtTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// logfmtNeedsQuoting reports whether a logfmt value must be quoted: if it's empty,
+// or contains spaces, '=', '"', control characters, or invalid UTF-8.
+func logfmtNeedsQuoting(b []byte) bool {
+	if len(b) == 0 {
+		return true
+	}
+	for i := 0; i < len(b); {
+		c := b[i]
+		if c < utf8.RuneSelf {
+			if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && size == 1 {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// appendLogfmtQuoted appends s to dst as a quoted logfmt value, escaping quotes,
+// backslashes, control characters, and invalid UTF-8.
+func appendLogfmtQuoted(dst, s []byte) []byte {
+	const hex = "0123456789abcdef"
+	dst = append(dst, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRune(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				dst = append(dst, `�`...)
+			} else {
+				dst = append(dst, s[i:i+size]...)
+			}
+			i += size
+			continue
+		}
+		switch {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c == '\n':
+			dst = append(dst, '\\', 'n')
+		case c == '\r':
+			dst = append(dst, '\\', 'r')
+		case c == '\t':
+			dst = append(dst, '\\', 't')
+		case c < ' ' || c == 0x7f:
+			dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			dst = append(dst, c)
+		}
+		i++
+	}
+	return append(dst, '"')
+}
+
+// textNeedsQuoting reports whether slog.TextHandler would quote a key or value: if
+// it's empty, or contains spaces, '=', '"', control characters, invalid UTF-8, or
+// non-printable runes.
+func textNeedsQuoting(b []byte) bool {
+	if len(b) == 0 {
+		return true
+	}
+	for i := 0; i < len(b); {
+		c := b[i]
+		if c < utf8.RuneSelf {
+			if c <= ' ' || c == '=' || c == '"' {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// quoteFrom quotes the value written to buf since offset, if HandlerOptions.Logfmt
+// is set, and the value needs quoting.  With TextQuoting, it's quoted like
+// slog.TextHandler would.
+func (e *encoder) quoteFrom(buf *buffer, offset int) {
+	if !e.h.opts.Logfmt {
+		return
+	}
+	if e.h.opts.TextQuoting {
+		e.textQuoteFrom(buf, offset)
+		return
+	}
+	if logfmtNeedsQuoting((*buf)[offset:]) {
+		e.scratch = append(e.scratch[:0], (*buf)[offset:]...)
+		*buf = appendLogfmtQuoted((*buf)[:offset], e.scratch)
+	}
+}
+
+// textQuoteFrom quotes the key or value written to buf since offset like
+// slog.TextHandler, if it needs quoting.
+func (e *encoder) textQuoteFrom(buf *buffer, offset int) {
+	if textNeedsQuoting((*buf)[offset:]) {
+		e.scratch = append(e.scratch[:0], (*buf)[offset:]...)
+		*buf = strconv.AppendQuote((*buf)[:offset], string(e.scratch))
+	}
+}
+
+// quoteValueFrom quotes the attr value written to buf since offset, like quoteFrom.
+// TextQuoting applies to attr values even without Logfmt, and in Compact mode,
+// values containing the commas separating the attrs are quoted.
+func (e *encoder) quoteValueFrom(buf *buffer, offset int) {
+	if e.h.opts.TextQuoting {
+		e.textQuoteFrom(buf, offset)
+		return
+	}
+	if e.h.opts.Compact && !e.h.opts.Logfmt {
+		if bytes.IndexByte((*buf)[offset:], ',') >= 0 {
+			e.scratch = append(e.scratch[:0], (*buf)[offset:]...)
+			*buf = strconv.AppendQuote((*buf)[:offset], string(e.scratch))
+		}
+		return
+	}
+	e.quoteFrom(buf, offset)
+}
+
+// sanitizeKeyFrom replaces the characters which aren't allowed in logfmt keys in the
+// key written to buf since offset with underscores, if HandlerOptions.Logfmt is set.
+// If HandlerOptions.TextQuoting is set, the key is quoted instead, like a value.
+func (e *encoder) sanitizeKeyFrom(buf *buffer, offset int) {
+	if e.h.opts.TextQuoting {
+		e.textQuoteFrom(buf, offset)
+		return
+	}
+	if !e.h.opts.Logfmt {
+		return
+	}
+	for i, c := range (*buf)[offset:] {
+		if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			(*buf)[offset+i] = '_'
+		}
+	}
+}
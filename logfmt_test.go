@@ -0,0 +1,125 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestAppendLogfmtQuoted(t *testing.T) {
+	tests := []struct {
+		in    string
+		quote bool
+		want  string
+	}{
+		{in: "plain", want: "plain"},
+		{in: "ünïcode", want: "ünïcode"},
+		{in: "", quote: true, want: `""`},
+		{in: "two words", quote: true, want: `"two words"`},
+		{in: "a=b", quote: true, want: `"a=b"`},
+		{in: `say "hi"`, quote: true, want: `"say \"hi\""`},
+		{in: `back\slash "`, quote: true, want: `"back\\slash \""`},
+		{in: "line1\nline2\r\tx", quote: true, want: `"line1\nline2\r\tx"`},
+		{in: "bell\x07", quote: true, want: `"bell\u0007"`},
+		{in: "bad\xffutf8", quote: true, want: `"bad�utf8"`},
+	}
+	for _, tt := range tests {
+		AssertEqual(t, tt.quote, logfmtNeedsQuoting([]byte(tt.in)))
+		if tt.quote {
+			AssertEqual(t, tt.want, string(appendLogfmtQuoted(nil, []byte(tt.in))))
+		}
+	}
+}
+
+func TestHandler_Logfmt(t *testing.T) {
+	tm := time.Date(2024, 01, 02, 15, 04, 05, 123456789, time.UTC)
+	tests := []handlerTest{
+		{
+			name: "defaults",
+			msg:  "hello world",
+			attrs: []slog.Attr{
+				slog.String("plain", "value"),
+				slog.String("spaces", "a b"),
+				slog.String("empty", ""),
+				slog.String("multi", "line1\nline2"),
+				slog.Any("err", errors.New(`failed: "boom"`)),
+				slog.String("bad key", "x"),
+				slog.Group("g", slog.String("k=v", "1")),
+			},
+			want: `time=2024-01-02T15:04:05.123Z level=INFO msg="hello world" plain=value spaces="a b" empty="" multi="line1\nline2" err="failed: \"boom\"" bad_key=x g.k_v=1` + "\n",
+		},
+		{
+			name: "header format",
+			opts: HandlerOptions{HeaderFormat: "%t %[req]h %m %a", TimeFormat: time.DateTime},
+			msg:  "msg",
+			attrs: []slog.Attr{
+				slog.String("req", "a b"),
+			},
+			want: `"2024-01-02 15:04:05" "a b" msg` + "\n",
+		},
+		{
+			name: "group format ignored",
+			opts: HandlerOptions{GroupFormat: GroupIndent, KeyValueSeparator: ": "},
+			msg:  "msg",
+			attrs: []slog.Attr{
+				slog.Group("g", slog.String("a", "1")),
+			},
+			want: `time=2024-01-02T15:04:05.123Z level=INFO msg=msg g.a=1` + "\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = true
+		test.opts.Logfmt = true
+		test.time = tm
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestHandler_Logfmt_Color(t *testing.T) {
+	buf := bytes.Buffer{}
+	theme := NewDefaultTheme()
+	h := NewHandler(&buf, &HandlerOptions{Logfmt: true, HeaderFormat: "msg=%m %a", Theme: theme})
+	rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "a b", 0)
+	rec.AddAttrs(slog.String("k", "c d"))
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	want := styled("msg=", theme.Header) + styled(`"a b"`, theme.Message) + " " +
+		styled("k=", theme.AttrKey) + `"c d"` + "\n"
+	AssertEqual(t, want, buf.String())
+}
+
+func TestHandler_TextQuoting(t *testing.T) {
+	attrs := []slog.Attr{
+		slog.String("plain", "value"),
+		slog.String("spaces", "a b"),
+		slog.String("empty", ""),
+		slog.String("multi", "line1\nline2"),
+		slog.String("ctrl", "a\x00b\x7f"),
+		slog.String("backslash", `C:\dir`),
+		slog.String("unicode", "héllo\u00a0wörld"),
+		slog.String("invalid", "a\xffb"),
+		slog.Any("err", errors.New(`failed: "boom"`)),
+		slog.String("bad key", "x"),
+		slog.Group("g", slog.String("k=v", "1")),
+	}
+
+	var want bytes.Buffer
+	rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "hello world", 0)
+	rec.AddAttrs(attrs...)
+	AssertNoError(t, slog.NewTextHandler(&want, nil).Handle(context.Background(), rec))
+
+	var got bytes.Buffer
+	h := NewHandler(&got, &HandlerOptions{NoColor: true, Logfmt: true, TextQuoting: true, HeaderFormat: "level=%L msg=%m %a"})
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	AssertEqual(t, want.String(), got.String())
+
+	// without Logfmt, only the quoting changes
+	got.Reset()
+	h = NewHandler(&got, &HandlerOptions{NoColor: true, TextQuoting: true, HeaderFormat: "%l %[x]h %t %m %a", TimeFormat: "15:04 05"})
+	rec = slog.NewRecord(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), slog.LevelInfo, "hello world", 0)
+	rec.AddAttrs(slog.String("x", "y z"), slog.String("a", "b c"), slog.Group("g", slog.String("d", "")))
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	AssertEqual(t, `INF y z 03:04 05 hello world a="b c" g.d=""`+"\n", got.String())
+}
@@ -0,0 +1,114 @@
+package console
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Middleware wraps a handler with another, which adds a feature to it, like
+// sampling, or a copy of the records for another handler.  Middlewares work with
+// any slog.Handler, and compose with Wrap:
+//
+//	h := console.Wrap(console.NewHandler(os.Stderr, nil),
+//		console.Sample(console.SamplingOptions{First: 10, Tick: time.Second}),
+//		console.RateLimit(100, time.Second),
+//		console.Tee(slog.NewJSONHandler(file, nil)),
+//	)
+type Middleware func(next slog.Handler) slog.Handler
+
+// Wrap returns h wrapped by the middlewares.  The first middleware is the outermost,
+// so records pass through the middlewares in order: Wrap(h, a, b) is a(b(h)).
+func Wrap(h slog.Handler, mws ...Middleware) slog.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// Sample returns a middleware which samples records, like HandlerOptions.Sampling,
+// for any handler.  The counters are shared by the handlers derived from the
+// wrapped one.
+func Sample(opts SamplingOptions) Middleware {
+	return func(next slog.Handler) slog.Handler {
+		s := newSampler(opts)
+		return &filterHandler{next: next, allow: s.sample}
+	}
+}
+
+// RateLimit returns a middleware which passes at most n records per interval to the
+// wrapped handler, and drops the rest, so a runaway loop can't flood the console.
+// If per is 0, at most n records are passed in total.  The limit is shared by the
+// handlers derived from the wrapped one.
+func RateLimit(n int, per time.Duration) Middleware {
+	limit := uint64(max(n, 0))
+	return func(next slog.Handler) slog.Handler {
+		c := &sampleCounter{}
+		return &filterHandler{next: next, allow: func(slog.Record) bool {
+			return c.inc(time.Now(), per) <= limit
+		}}
+	}
+}
+
+// Tee returns a middleware which forwards each record to the other handlers, as well
+// as the wrapped one.  See NewTeeHandler.
+func Tee(others ...slog.Handler) Middleware {
+	return func(next slog.Handler) slog.Handler {
+		return NewTeeHandler(append([]slog.Handler{next}, others...)...)
+	}
+}
+
+// Capture returns a middleware which keeps the records the wrapped handler isn't
+// enabled for in a ring buffer, and passes them to it when a record reaches the
+// trigger level.  See NewCaptureHandler.
+func Capture(opts *CaptureOptions) Middleware {
+	return func(next slog.Handler) slog.Handler {
+		return NewCaptureHandler(next, opts)
+	}
+}
+
+// filterHandler passes the records allow returns true for to next.
+type filterHandler struct {
+	next  slog.Handler
+	allow func(rec slog.Record) bool
+}
+
+var _ slog.Handler = (*filterHandler)(nil)
+var _ io.Closer = (*filterHandler)(nil)
+
+// Enabled implements slog.Handler.
+func (f *filterHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return f.next.Enabled(ctx, l)
+}
+
+// Handle implements slog.Handler.
+func (f *filterHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if !f.allow(rec) {
+		return nil
+	}
+	return f.next.Handle(ctx, rec)
+}
+
+// WithAttrs implements slog.Handler.
+func (f *filterHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &filterHandler{next: f.next.WithAttrs(attrs), allow: f.allow}
+}
+
+// WithGroup implements slog.Handler.
+func (f *filterHandler) WithGroup(name string) slog.Handler {
+	return &filterHandler{next: f.next.WithGroup(name), allow: f.allow}
+}
+
+// Flush flushes next, if it has a Flush() error method.
+func (f *filterHandler) Flush() error {
+	return flush(f.next)
+}
+
+// Close closes next, if it implements io.Closer.
+func (f *filterHandler) Close() error {
+	if cl, ok := f.next.(io.Closer); ok {
+		return cl.Close()
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next slog.Handler) slog.Handler {
+			order = append(order, name)
+			return next
+		}
+	}
+	h := NewHandler(nil, nil)
+	AssertEqual(t, slog.Handler(h), Wrap(h))
+	Wrap(h, mw("a"), mw("b"), mw("c"))
+	// the last middleware wraps the handler first, so the first is outermost
+	AssertEqual(t, "c,b,a", strings.Join(order, ","))
+}
+
+func TestSample(t *testing.T) {
+	buf := bytes.Buffer{}
+	l := slog.New(Wrap(NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%m %a"}),
+		Sample(SamplingOptions{First: 2, Thereafter: 3}),
+	))
+	for i := 1; i <= 8; i++ {
+		l.With("i", i).Info("msg")
+	}
+	AssertEqual(t, "msg i=1\nmsg i=2\nmsg i=5\nmsg i=8\n", buf.String())
+}
+
+func TestRateLimit(t *testing.T) {
+	buf := bytes.Buffer{}
+	l := slog.New(Wrap(NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%m"}),
+		RateLimit(3, 0),
+	))
+	l.Info("a")
+	l.WithGroup("g").Info("b")
+	l.Info("c")
+	l.Info("d")
+	AssertEqual(t, "a\nb\nc\n", buf.String())
+}
+
+func TestTee_Capture(t *testing.T) {
+	var first, second bytes.Buffer
+	opts := &HandlerOptions{NoColor: true, HeaderFormat: "%l %m", RuleWidth: 10}
+	h := Wrap(NewHandler(&first, opts),
+		Tee(NewHandler(&second, &HandlerOptions{NoColor: true, HeaderFormat: "%l %m", Level: slog.LevelWarn})),
+		Capture(&CaptureOptions{Size: 2}),
+	)
+	l := slog.New(h)
+	l.Debug("d1")
+	l.Info("i1")
+	l.Warn("w1")
+	l.Error("e1")
+	AssertEqual(t, "INF i1\nWRN w1\n── captured 1 record ───\nDBG d1\n── end of captured records ───\nERR e1\n", first.String())
+	AssertEqual(t, "WRN w1\nERR e1\n", second.String())
+	AssertNoError(t, h.(interface{ Flush() error }).Flush())
+}
+
+func TestFilterHandler(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := RateLimit(1, 0)(NewHandler(&buf, &HandlerOptions{Level: slog.LevelWarn}))
+	AssertEqual(t, false, h.Enabled(context.Background(), slog.LevelInfo))
+	AssertEqual(t, true, h.Enabled(context.Background(), slog.LevelWarn))
+}
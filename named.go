@@ -0,0 +1,61 @@
+package console
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// Named returns a logger whose name is name, nested under the name of l, if any.
+// For example:
+//
+//	server := console.Named(logger, "server")
+//	router := console.Named(console.Named(server, "http"), "router")
+//	router.Info("ready") // logger name is "server.http.router"
+//
+// If l's handler is a *Handler, the name is printed by the %N verb of the
+// HeaderFormat, or as an attribute with the key HandlerOptions.LoggerNameKey.
+// Otherwise, the name is just added to l as a "logger" attribute, and isn't nested.
+func Named(l *slog.Logger, name string) *slog.Logger {
+	if h, ok := l.Handler().(*Handler); ok {
+		return slog.New(h.WithName(name))
+	}
+	return l.With(slog.String(defaultLoggerNameKey, name))
+}
+
+// WithName returns a new Handler whose logger name is name, nested under the
+// name of h, if any, with a ".".  See [Named].
+func (h *Handler) WithName(name string) *Handler {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	if h.name != "" {
+		name = h.name + "." + name
+	}
+	h2.name = name
+	h2.nameStyle = loggerNameStyle(h.opts, name)
+	return &h2
+}
+
+// Name returns the logger name of the handler.  See [Named].
+func (h *Handler) Name() string {
+	return h.name
+}
+
+// loggerNameStyle returns the style for the logger name: the style in
+// opts.LoggerNameStyles with the longest key matching the name, or the theme's
+// LoggerName style.
+func loggerNameStyle(opts HandlerOptions, name string) ANSIMod {
+	for prefix := name; prefix != ""; {
+		if style, ok := opts.LoggerNameStyles[prefix]; ok {
+			return style
+		}
+		idx := strings.LastIndexByte(prefix, '.')
+		if idx < 0 {
+			break
+		}
+		prefix = prefix[:idx]
+	}
+	return opts.Theme.LoggerName
+}
@@ -0,0 +1,80 @@
+package console
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+func TestNamed(t *testing.T) {
+	buf := bytes.Buffer{}
+	l := slog.New(NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %{[%N]%} %m %a"}))
+
+	l.Info("root")
+	server := Named(l, "server")
+	server.Info("started")
+	Named(Named(server.With("foo", "bar"), "http"), "router").Info("ready")
+	Named(server, " ").WithGroup("g").Info("blank", "a", 1)
+
+	want := "INF root\n" +
+		"INF [server] started\n" +
+		"INF [server.http.router] ready foo=bar\n" +
+		"INF [server] blank g.a=1\n"
+	AssertEqual(t, want, buf.String())
+	AssertEqual(t, "server", server.Handler().(*Handler).Name())
+}
+
+func TestNamed_AsAttr(t *testing.T) {
+	buf := bytes.Buffer{}
+	l := slog.New(NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%m %a", LoggerNameKey: "name"}))
+	Named(l, "db").WithGroup("g").Info("msg", "a", 1)
+	AssertEqual(t, "msg name=db g.a=1\n", buf.String())
+
+	// the name attr can be used as a header
+	buf.Reset()
+	l = slog.New(NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%[logger]6h| %m"}))
+	Named(l, "db").Info("msg")
+	AssertEqual(t, "db    | msg\n", buf.String())
+
+	// other handlers just get an attribute
+	buf.Reset()
+	Named(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{ReplaceAttr: RemoveKeys(slog.TimeKey)})), "db").Info("msg")
+	AssertEqual(t, "level=INFO msg=msg logger=db\n", buf.String())
+}
+
+func TestNamed_ReplaceAttr(t *testing.T) {
+	buf := bytes.Buffer{}
+	l := slog.New(NewHandler(&buf, &HandlerOptions{
+		NoColor:      true,
+		HeaderFormat: "%N: %m",
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == "logger" {
+				a.Value = slog.StringValue("<" + a.Value.String() + ">")
+			}
+			return a
+		},
+	}))
+	Named(l, "db").Info("msg")
+	AssertEqual(t, "<db>: msg\n", buf.String())
+}
+
+func TestNamed_Styles(t *testing.T) {
+	theme := NewDefaultTheme()
+	opts := HandlerOptions{
+		Theme: theme,
+		LoggerNameStyles: map[string]ANSIMod{
+			"server":      ToANSICode(Red),
+			"server.http": ToANSICode(Green),
+		},
+	}
+	AssertEqual(t, theme.LoggerName, loggerNameStyle(opts, "db"))
+	AssertEqual(t, ToANSICode(Red), loggerNameStyle(opts, "server"))
+	AssertEqual(t, ToANSICode(Red), loggerNameStyle(opts, "server.grpc"))
+	AssertEqual(t, ToANSICode(Green), loggerNameStyle(opts, "server.http.router"))
+	AssertEqual(t, theme.LoggerName, loggerNameStyle(opts, "serverless"))
+
+	buf := bytes.Buffer{}
+	l := slog.New(NewHandler(&buf, &HandlerOptions{HeaderFormat: "%N %m", Theme: theme, LoggerNameStyles: opts.LoggerNameStyles}))
+	Named(Named(l, "server"), "http").Info("msg")
+	AssertEqual(t, styled("server.http", ToANSICode(Green))+" "+styled("msg", theme.Message)+"\n", buf.String())
+}
@@ -0,0 +1,241 @@
+package console
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultQueueSize is the default NonBlockingOptions.QueueSize.
+const defaultQueueSize = 1024
+
+// DropPolicy selects which records a NonBlockingWriter drops when its queue is full.
+type DropPolicy int
+
+const (
+	// DropNewest drops the records written while the queue is full.
+	DropNewest DropPolicy = iota
+	// DropOldest drops the oldest record in the queue to make room for each new one.
+	DropOldest
+)
+
+// NonBlockingOptions are options for a NonBlockingWriter.
+type NonBlockingOptions struct {
+	// QueueSize is the maximum number of writes queued.  If 0, 1024 is used.
+	QueueSize int
+
+	// DropPolicy selects which records are dropped when the queue is full.
+	DropPolicy DropPolicy
+
+	// NoticeInterval is the interval between the notices of dropped records
+	// while the queue stays busy.  If 0, one second is used.
+	NoticeInterval time.Duration
+}
+
+// NonBlockingWriter is an io.WriteCloser which queues writes, and writes them to
+// another writer in the background, so Write never blocks, even if the other writer
+// is slow, like a pipe nobody is reading.  When the queue is full, writes are
+// dropped, according to the DropPolicy, and a notice of the number of records
+// dropped, like "console: dropped 12 log records", is written once the queue is
+// empty again, or every NoticeInterval while it isn't:
+//
+//	w := console.NewNonBlockingWriter(os.Stderr, nil)
+//	defer w.Close()
+//	logger := slog.New(console.NewHandler(w, nil))
+//
+// Each write is queued as a whole, so when used as the writer for a Handler, each
+// write is one record.  Flush waits until the queue is empty.  Write errors of the
+// other writer are returned by the next call to Flush or Close.  It is safe for
+// concurrent use.
+type NonBlockingWriter struct {
+	out  io.Writer
+	opts NonBlockingOptions
+
+	mu sync.Mutex
+	// idle is signaled when the queue is empty, and nothing is being written
+	idle *sync.Cond
+	// queue is a ring of n writes, starting at head
+	queue   [][]byte
+	head, n int
+	// spare are buffers of written records, for reuse
+	spare   [][]byte
+	dropped int
+	writing bool
+	closed  bool
+	err     error
+
+	wake chan struct{}
+	done chan struct{}
+}
+
+var _ io.WriteCloser = (*NonBlockingWriter)(nil)
+
+// NewNonBlockingWriter returns a NonBlockingWriter writing to out.  If opts is nil,
+// the default options are used.  It starts a goroutine, which is stopped by Close.
+func NewNonBlockingWriter(out io.Writer, opts *NonBlockingOptions) *NonBlockingWriter {
+	if opts == nil {
+		opts = new(NonBlockingOptions)
+	}
+	o := *opts
+	if o.QueueSize <= 0 {
+		o.QueueSize = defaultQueueSize
+	}
+	if o.NoticeInterval <= 0 {
+		o.NoticeInterval = time.Second
+	}
+	w := &NonBlockingWriter{
+		out:   out,
+		opts:  o,
+		queue: make([][]byte, o.QueueSize),
+		wake:  make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	w.idle = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+// Write queues p, or drops it if the queue is full.  It never blocks on the
+// other writer, and only fails if the writer is closed.
+func (w *NonBlockingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if w.n == len(w.queue) {
+		w.dropped++
+		if w.opts.DropPolicy == DropNewest {
+			return len(p), nil
+		}
+		w.spare = append(w.spare, w.queue[w.head])
+		w.queue[w.head] = nil
+		w.head = (w.head + 1) % len(w.queue)
+		w.n--
+	}
+
+	var b []byte
+	if len(w.spare) > 0 {
+		b = w.spare[len(w.spare)-1]
+		w.spare = w.spare[:len(w.spare)-1]
+	}
+	w.queue[(w.head+w.n)%len(w.queue)] = append(b[:0], p...)
+	w.n++
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// run writes the queued records until the writer is closed.
+func (w *NonBlockingWriter) run() {
+	defer close(w.done)
+	lastNotice := time.Now()
+	for range w.wake {
+		w.mu.Lock()
+		for {
+			var b []byte
+			if w.n > 0 {
+				b = w.queue[w.head]
+				w.queue[w.head] = nil
+				w.head = (w.head + 1) % len(w.queue)
+				w.n--
+			}
+			// notices are written once the queue is empty, or every
+			// NoticeInterval while it isn't
+			var notice string
+			if w.dropped > 0 && (w.n == 0 || time.Since(lastNotice) >= w.opts.NoticeInterval) {
+				notice = w.notice()
+				lastNotice = time.Now()
+			}
+			if b == nil && notice == "" {
+				break
+			}
+
+			w.writing = true
+			w.mu.Unlock()
+			var err error
+			if b != nil {
+				_, err = w.out.Write(b)
+			}
+			if notice != "" && err == nil {
+				_, err = io.WriteString(w.out, notice)
+			}
+			w.mu.Lock()
+			w.writing = false
+
+			if err != nil && w.err == nil {
+				w.err = err
+			}
+			if b != nil && len(w.spare) < len(w.queue) {
+				w.spare = append(w.spare, b)
+			}
+		}
+		closed := w.closed
+		w.idle.Broadcast()
+		w.mu.Unlock()
+		if closed {
+			return
+		}
+	}
+}
+
+// notice returns the notice of the records dropped since the last notice, and
+// resets the count.  Must be called with the mutex held.
+func (w *NonBlockingWriter) notice() string {
+	noun := " log records\n"
+	if w.dropped == 1 {
+		noun = " log record\n"
+	}
+	notice := "console: dropped " + strconv.Itoa(w.dropped) + noun
+	w.dropped = 0
+	return notice
+}
+
+// Dropped returns the number of records dropped since the last notice was written.
+func (w *NonBlockingWriter) Dropped() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+// Flush waits until all the queued records are written, and then flushes the
+// other writer, if it has a Flush() error method.
+func (w *NonBlockingWriter) Flush() error {
+	w.mu.Lock()
+	for w.n > 0 || w.writing {
+		w.idle.Wait()
+	}
+	err := w.err
+	w.err = nil
+	w.mu.Unlock()
+	return errors.Join(err, flush(w.out))
+}
+
+// Close writes the queued records, and the notice of dropped records, if any, stops
+// the background goroutine, and closes the other writer, like Handler.Close.
+func (w *NonBlockingWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	<-w.done
+
+	w.mu.Lock()
+	err := w.err
+	w.err = nil
+	w.mu.Unlock()
+	return errors.Join(err, closeWriter(w.out))
+}
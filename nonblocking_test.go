@@ -0,0 +1,92 @@
+package console
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// gatedWriter blocks writes until its gate is opened.
+type gatedWriter struct {
+	gate    chan struct{}
+	started chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+	buf     bytes.Buffer
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{gate: make(chan struct{}), started: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestNonBlockingWriter(t *testing.T) {
+	for _, tt := range []struct {
+		policy DropPolicy
+		want   string
+	}{
+		{DropNewest, "0\n1\n2\nconsole: dropped 3 log records\n"},
+		{DropOldest, "0\n4\n5\nconsole: dropped 3 log records\n"},
+	} {
+		out := newGatedWriter()
+		w := NewNonBlockingWriter(out, &NonBlockingOptions{QueueSize: 2, DropPolicy: tt.policy})
+		l := slog.New(NewHandler(w, &HandlerOptions{NoColor: true, HeaderFormat: "%m"}))
+
+		l.Info("0")
+		// wait for the first record to block the background writer
+		<-out.started
+		for _, msg := range []string{"1", "2", "3", "4", "5"} {
+			l.Info(msg)
+		}
+		AssertEqual(t, 3, w.Dropped())
+
+		close(out.gate)
+		AssertNoError(t, w.Flush())
+		AssertEqual(t, tt.want, out.String())
+		AssertEqual(t, 0, w.Dropped())
+		AssertNoError(t, w.Close())
+	}
+}
+
+func TestNonBlockingWriter_Close(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewNonBlockingWriter(buf, nil)
+	for i := 0; i < 100; i++ {
+		_, err := io.WriteString(w, "line\n")
+		AssertNoError(t, err)
+	}
+	AssertNoError(t, w.Close())
+	AssertEqual(t, strings.Repeat("line\n", 100), buf.String())
+
+	_, err := w.Write([]byte("late\n"))
+	AssertEqual(t, io.ErrClosedPipe, err)
+	AssertNoError(t, w.Close())
+}
+
+func TestNonBlockingWriter_Errors(t *testing.T) {
+	boom := errors.New("boom")
+	w := NewNonBlockingWriter(writerFunc(func([]byte) (int, error) { return 0, boom }), nil)
+	n, err := w.Write([]byte("line\n"))
+	AssertNoError(t, err)
+	AssertEqual(t, 5, n)
+	AssertEqual(t, true, errors.Is(w.Flush(), boom))
+	AssertNoError(t, w.Flush())
+	AssertNoError(t, w.Close())
+}
@@ -0,0 +1,41 @@
+package console
+
+import (
+	"errors"
+	"io"
+)
+
+// output holds the writer of a Handler, and all the handlers derived from it, so
+// SetOutput changes it for all of them.  It's guarded by the handler's mutex.
+type output struct {
+	w io.Writer
+}
+
+// SetOutput replaces the writer of the handler, and all the handlers derived from
+// it via WithAttrs, WithGroup, WithName, and WithOptions, e.g. after the log file is rotated, or
+// while a full-screen UI takes over the terminal.  It waits for the records being
+// written to finish, so no record is split between the old and new writers.  The
+// summary of records suppressed by CollapseRepeats, if any, is written to the old
+// writer, which is then flushed, if it has a Flush() error method, but not closed.
+//
+// The lock shared with other handlers writing to the same file isn't changed.  See
+// HandlerOptions.Mutex.
+func (h *Handler) SetOutput(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	err := errors.Join(h.flushRepeats(), flush(h.out.w))
+	if h.repeats != nil {
+		// don't count records written to the new writer as repeats of
+		// the last one written to the old writer
+		h.repeats.key = h.repeats.key[:0]
+	}
+	h.out.w = w
+	return err
+}
+
+// Output returns the handler's writer.
+func (h *Handler) Output() io.Writer {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.out.w
+}
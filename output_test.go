@@ -0,0 +1,51 @@
+package console
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+func TestHandler_SetOutput(t *testing.T) {
+	var first, second bytes.Buffer
+	buffered := bufio.NewWriter(&first)
+	h := NewHandler(buffered, &HandlerOptions{NoColor: true, HeaderFormat: "%m %a", CollapseRepeats: true})
+	derived := slog.New(h.WithAttrs([]slog.Attr{slog.Int("a", 1)}))
+
+	derived.Info("one")
+	derived.Info("one")
+	AssertNoError(t, h.SetOutput(&second))
+	AssertEqual(t, io.Writer(&second), h.Output())
+	// the repeats are written to the old writer, which is flushed
+	AssertEqual(t, "one a=1\nlast message repeated 1 time\n", first.String())
+
+	derived.Info("one")
+	slog.New(h.WithOptions(func(o *HandlerOptions) { o.HeaderFormat = "[%m]" })).Info("two")
+	AssertEqual(t, "one a=1\n[two]\n", second.String())
+}
+
+func TestHandler_SetOutput_Concurrent(t *testing.T) {
+	var bufs [2]bytes.Buffer
+	h := NewHandler(&bufs[0], &HandlerOptions{NoColor: true, HeaderFormat: "%m"})
+	l := slog.New(h)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Info("line")
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		AssertNoError(t, h.SetOutput(&bufs[(i+1)%2]))
+	}
+	wg.Wait()
+
+	AssertEqual(t, 400*len("line\n"), bufs[0].Len()+bufs[1].Len())
+}
@@ -0,0 +1,30 @@
+package console
+
+import "log/slog"
+
+// prettyMarker is the value of the attribute returned by Pretty.
+type prettyMarker bool
+
+// Pretty returns an attribute which switches a single record into pretty mode,
+// or, with false, out of it, regardless of HandlerOptions.Pretty and
+// HandlerOptions.PrettyLevel.  The attribute itself isn't printed:
+//
+//	logger.Error("request failed", console.Pretty(true), "request", req)
+//
+// Only applies to attributes of the record, not to those added with With.
+func Pretty(on bool) slog.Attr {
+	return slog.Any("pretty", prettyMarker(on))
+}
+
+// resetAttrs discards the encoded attrs, so they can be encoded again.
+func (e *encoder) resetAttrs() {
+	e.attrBuf.Reset()
+	e.multilineAttrBuf.Reset()
+	clear(e.headerAttrs)
+	e.rule = false
+	e.prettyAttr = 0
+	e.numAttrs, e.omittedAttrs = 0, 0
+	for i := range e.filteredAttrs {
+		e.filteredAttrs[i].Reset()
+	}
+}
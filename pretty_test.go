@@ -0,0 +1,85 @@
+package console
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestPretty(t *testing.T) {
+	attrs := []slog.Attr{
+		slog.Int("status", 200),
+		slog.Group("http", slog.String("method", "GET")),
+	}
+	tests := []handlerTest{
+		{
+			name:  "compact",
+			attrs: attrs,
+			want:  "INF request finished status=200 http.method=GET\n",
+		},
+		{
+			name:  "pretty attr",
+			attrs: append([]slog.Attr{Pretty(true)}, attrs...),
+			want:  "INF request finished\n  status: 200\n  http:\n    method: GET\n",
+		},
+		{
+			name:  "pretty attr last",
+			attrs: append(attrs[:2:2], Pretty(true)),
+			want:  "INF request finished\n  status: 200\n  http:\n    method: GET\n",
+		},
+		{
+			name:  "pretty level",
+			opts:  HandlerOptions{PrettyLevel: slog.LevelError},
+			lvl:   slog.LevelError,
+			attrs: attrs,
+			want:  "ERR request finished\n  status: 200\n  http:\n    method: GET\n",
+		},
+		{
+			name:  "below pretty level",
+			opts:  HandlerOptions{PrettyLevel: slog.LevelError},
+			lvl:   slog.LevelWarn,
+			attrs: attrs,
+			want:  "WRN request finished status=200 http.method=GET\n",
+		},
+		{
+			name:  "pretty attr false",
+			opts:  HandlerOptions{PrettyLevel: slog.LevelError},
+			lvl:   slog.LevelError,
+			attrs: append([]slog.Attr{Pretty(false)}, attrs...),
+			want:  "ERR request finished status=200 http.method=GET\n",
+		},
+		{
+			name:  "pretty attr false in pretty mode",
+			opts:  HandlerOptions{Pretty: true},
+			attrs: append([]slog.Attr{Pretty(false)}, attrs...),
+			want:  "INF request finished status=200 http.method=GET\n",
+		},
+		{
+			name:        "with attrs",
+			attrs:       []slog.Attr{Pretty(true), slog.Int("status", 200)},
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithAttrs(attrs[1:]).WithGroup("req") },
+			want:        "INF request finished\n  http:\n    method: GET\n  req.status: 200\n",
+		},
+		{
+			name:        "with attrs in pretty mode",
+			opts:        HandlerOptions{Pretty: true},
+			attrs:       []slog.Attr{Pretty(false), slog.Int("status", 200)},
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithAttrs(attrs[1:]).WithGroup("req") },
+			want:        "INF request finished http.method=GET req.status=200\n",
+		},
+		{
+			name:  "logfmt",
+			opts:  HandlerOptions{Logfmt: true, HeaderFormat: "level=%L msg=%m %a"},
+			attrs: append([]slog.Attr{Pretty(true)}, attrs...),
+			want:  "level=INFO msg=\"request finished\" status=200 http.method=GET\n",
+		},
+	}
+
+	for _, test := range tests {
+		test.opts.NoColor = true
+		if test.opts.HeaderFormat == "" {
+			test.opts.HeaderFormat = "%l %m %a"
+		}
+		test.msg = "request finished"
+		t.Run(test.name, test.run)
+	}
+}
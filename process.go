@@ -0,0 +1,47 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// NewProcessWriter returns a writer which prints each line written to it as the
+// message of a record at level, so the output of a child process is printed like
+// the rest of the logs.  args are attributes added to each record, as in
+// slog.Logger.With, usually naming the process:
+//
+//	cmd := exec.Command("git", "fetch")
+//	stdout := console.NewProcessWriter(h, slog.LevelInfo, "cmd", "git")
+//	stderr := console.NewProcessWriter(h, slog.LevelWarn, "cmd", "git")
+//	cmd.Stdout, cmd.Stderr = stdout, stderr
+//	err := cmd.Run()
+//	stdout.Flush()
+//	stderr.Flush()
+//
+// Lines overwritten with carriage returns, like progress bars, are printed as
+// their last version.  Empty lines are dropped.
+//
+// Each line written is a record.  Records split across writes are printed once
+// they're complete.  Call the writer's Flush method to print the last line if it
+// wasn't terminated by a newline.  It is safe for concurrent use.
+func NewProcessWriter(h slog.Handler, level slog.Level, args ...any) *LineWriter {
+	if len(args) > 0 {
+		h = slog.New(h).With(args...).Handler()
+	}
+	return &LineWriter{handle: func(line []byte) error {
+		if i := bytes.LastIndexByte(bytes.TrimRight(line, "\r"), '\r'); i >= 0 {
+			line = line[i+1:]
+		}
+		line = bytes.TrimRight(line, " \t\r")
+		if len(bytes.TrimSpace(line)) == 0 {
+			return nil
+		}
+		ctx := context.Background()
+		if !h.Enabled(ctx, level) {
+			return nil
+		}
+		return h.Handle(ctx, slog.NewRecord(time.Now(), level, string(line), 0))
+	}}
+}
@@ -0,0 +1,68 @@
+package console
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestProcessWriter(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %m %a"})
+	w := NewProcessWriter(h, slog.LevelWarn, "cmd", "git")
+
+	tests := []struct {
+		name, in, want string
+	}{
+		{name: "lines", in: "first\nsecond\n", want: "WRN first cmd=git\nWRN second cmd=git\n"},
+		{name: "crlf", in: "windows\r\n", want: "WRN windows cmd=git\n"},
+		{name: "progress", in: "Receiving: 10%\rReceiving: 50%\rReceiving: 100%, done.\n", want: "WRN Receiving: 100%, done. cmd=git\n"},
+		{name: "blank lines", in: "\n \r\n", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			n, err := io.WriteString(w, tt.in)
+			AssertNoError(t, err)
+			AssertEqual(t, len(tt.in), n)
+			AssertEqual(t, tt.want, buf.String())
+		})
+	}
+
+	// the last line is printed by Flush
+	buf.Reset()
+	io.WriteString(w, "no newline")
+	AssertEqual(t, "", buf.String())
+	AssertNoError(t, w.Flush())
+	AssertEqual(t, "WRN no newline cmd=git\n", buf.String())
+}
+
+func TestProcessWriter_Level(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{Level: slog.LevelInfo, NoColor: true, HeaderFormat: "%l %m %a"})
+
+	io.WriteString(NewProcessWriter(h, slog.LevelDebug), "hidden\n")
+	io.WriteString(NewProcessWriter(h, slog.LevelInfo), "shown\n")
+	AssertEqual(t, "INF shown\n", buf.String())
+}
+
+func TestProcessWriter_Cmd(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %m %a"})
+
+	cmd := exec.Command(sh, "-c", "echo out; echo err >&2")
+	cmd.Stdout = NewProcessWriter(h, slog.LevelInfo, "cmd", "sh", "stream", "stdout")
+	cmd.Stderr = NewProcessWriter(h, slog.LevelError, "cmd", "sh", "stream", "stderr")
+	AssertNoError(t, cmd.Run())
+
+	out := buf.String()
+	AssertEqual(t, true, strings.Contains(out, "INF out cmd=sh stream=stdout\n"))
+	AssertEqual(t, true, strings.Contains(out, "ERR err cmd=sh stream=stderr\n"))
+}
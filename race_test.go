@@ -0,0 +1,7 @@
+//go:build race
+
+package console
+
+// raceEnabled reports whether the tests were built with the race detector,
+// which makes allocations that would otherwise not happen.
+const raceEnabled = true
@@ -0,0 +1,106 @@
+package console
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"time"
+)
+
+// defaultRepeatTimeout is used when CollapseRepeats is set, but RepeatTimeout isn't.
+const defaultRepeatTimeout = time.Second
+
+// repeatState tracks the last record written, so consecutive identical records
+// can be collapsed.  It's shared by a Handler and all the handlers derived from
+// it, and guarded by the handler's mutex.
+type repeatState struct {
+	// key is the last line written, minus its timestamp
+	key buffer
+	// scratch is used to assemble the key of the current line
+	scratch buffer
+	// count is the number of repeats of key which have been suppressed
+	count int
+	// out is the writer the last line was written to, and toErr
+	// whether it was HandlerOptions.ErrorWriter
+	out   io.Writer
+	toErr bool
+	timer *time.Timer
+}
+
+// suppress checks whether line and trailer, minus the bytes of line in [tsStart,tsEnd),
+// are the same as the last line written to the same writer.  If so, it returns true, and
+// the line should not be written.  Otherwise, it writes the summary of the previous run
+// of repeats, if any, and returns false.
+//
+// Must be called with the handler's mutex held.
+func (h *Handler) suppress(line, trailer []byte, tsStart, tsEnd int, toErr bool, out io.Writer) (bool, error) {
+	r := h.repeats
+	tsStart, tsEnd = min(tsStart, len(line)), min(tsEnd, len(line))
+	r.scratch = append(append(r.scratch[:0], line[:tsStart]...), line[tsEnd:]...)
+	r.scratch = append(r.scratch, trailer...)
+
+	if r.toErr == toErr && bytes.Equal(r.scratch, r.key) {
+		r.count++
+		if r.timer == nil {
+			r.timer = time.AfterFunc(h.opts.RepeatTimeout, h.flushRepeatsAsync)
+		} else {
+			r.timer.Reset(h.opts.RepeatTimeout)
+		}
+		return true, nil
+	}
+
+	err := h.flushRepeats()
+	r.key, r.scratch = r.scratch, r.key
+	r.out, r.toErr = out, toErr
+	return false, err
+}
+
+// repeatSummary returns the summary line for the suppressed repeats, if any, and
+// resets the count.  Must be called with the handler's mutex held.
+func (h *Handler) repeatSummary() []byte {
+	r := h.repeats
+	if r.count == 0 {
+		return nil
+	}
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+
+	var b buffer
+	msg := "last message repeated " + strconv.Itoa(r.count) + " times"
+	if r.count == 1 {
+		msg = "last message repeated 1 time"
+	}
+	if h.opts.NoColor || h.opts.Theme.Header == "" {
+		b.AppendString(msg)
+	} else {
+		b.AppendString(string(h.opts.Theme.Header))
+		b.AppendString(msg)
+		b.AppendString(string(ResetMod))
+	}
+	b.AppendByte('\n')
+	r.count = 0
+	return b
+}
+
+// flushRepeats writes the summary of any suppressed repeats.  Must be called with
+// the handler's mutex held.
+func (h *Handler) flushRepeats() error {
+	if h.repeats == nil {
+		return nil
+	}
+	if summary := h.repeatSummary(); summary != nil {
+		// subsequent identical lines should be printed again, since
+		// the summary separates them from the original
+		h.repeats.key = h.repeats.key[:0]
+		_, err := h.repeats.out.Write(summary)
+		return err
+	}
+	return nil
+}
+
+func (h *Handler) flushRepeatsAsync() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_ = h.flushRepeats()
+}
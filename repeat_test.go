@@ -0,0 +1,117 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestHandler_CollapseRepeats(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{
+		NoColor:         true,
+		CollapseRepeats: true,
+		RepeatTimeout:   time.Hour,
+		TimeFormat:      time.TimeOnly,
+	})
+	start := time.Date(2024, 01, 02, 15, 04, 05, 0, time.UTC)
+
+	log := func(h slog.Handler, i int, lvl slog.Level, msg string, attrs ...slog.Attr) {
+		rec := slog.NewRecord(start.Add(time.Duration(i)*time.Second), lvl, msg, 0)
+		rec.AddAttrs(attrs...)
+		AssertNoError(t, h.Handle(context.Background(), rec))
+	}
+
+	log(h, 0, slog.LevelInfo, "retrying", slog.Int("n", 1))
+	log(h, 1, slog.LevelInfo, "retrying", slog.Int("n", 1))
+	log(h, 2, slog.LevelInfo, "retrying", slog.Int("n", 1))
+	// different attrs
+	log(h, 3, slog.LevelInfo, "retrying", slog.Int("n", 2))
+	// different level
+	log(h, 4, slog.LevelWarn, "retrying", slog.Int("n", 2))
+	// derived handlers compare against the same last line
+	log(h.WithAttrs([]slog.Attr{slog.Int("n", 2)}), 5, slog.LevelWarn, "retrying")
+	AssertNoError(t, h.Flush())
+	// after a flush, the line is printed again
+	log(h, 6, slog.LevelWarn, "retrying", slog.Int("n", 2))
+
+	want := strings.Join([]string{
+		"15:04:05 INF retrying n=1",
+		"last message repeated 2 times",
+		"15:04:08 INF retrying n=2",
+		"15:04:09 WRN retrying n=2",
+		"last message repeated 1 time",
+		"15:04:11 WRN retrying n=2",
+		"",
+	}, "\n")
+	AssertEqual(t, want, buf.String())
+}
+
+func TestHandler_CollapseRepeats_Timeout(t *testing.T) {
+	buf := &syncBuffer{}
+	l := slog.New(NewHandler(buf, &HandlerOptions{
+		CollapseRepeats: true,
+		RepeatTimeout:   10 * time.Millisecond,
+		HeaderFormat:    "%m",
+	}))
+	theme := NewDefaultTheme()
+
+	l.Info("tick")
+	l.Info("tick")
+	l.Info("tick")
+	AssertEqual(t, styled("tick", theme.Message)+"\n", buf.String())
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(buf.String(), "repeated") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	AssertEqual(t, styled("tick", theme.Message)+"\n"+styled("last message repeated 2 times", theme.Header)+"\n", buf.String())
+}
+
+// summaryFailingWriter fails to write the summaries of repeats.
+type summaryFailingWriter struct {
+	bytes.Buffer
+}
+
+func (w *summaryFailingWriter) Write(p []byte) (int, error) {
+	if bytes.Contains(p, []byte("repeated")) {
+		return 0, errors.New("summary failed")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestHandler_CollapseRepeats_SummaryError(t *testing.T) {
+	w := &summaryFailingWriter{}
+	h := NewHandler(w, &HandlerOptions{NoColor: true, CollapseRepeats: true, RepeatTimeout: time.Hour, HeaderFormat: "%m"})
+
+	AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "a", 0)))
+	AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "a", 0)))
+
+	// the summary fails, but the next record is still written
+	err := h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "b", 0))
+	AssertError(t, err)
+	AssertEqual(t, "summary failed", err.Error())
+	AssertEqual(t, "a\nb\n", w.String())
+}
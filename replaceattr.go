@@ -0,0 +1,120 @@
+package console
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// ReplaceAttrFunc is the signature of HandlerOptions.ReplaceAttr, and of
+// [slog.HandlerOptions.ReplaceAttr].
+type ReplaceAttrFunc = func(groups []string, a slog.Attr) slog.Attr
+
+// RedactedValue is the value Redact replaces attribute values with.
+const RedactedValue = "[REDACTED]"
+
+// ChainReplaceAttr returns a ReplaceAttr function which calls each of the fns in
+// order, passing the attribute returned by each function to the next.  If a function
+// returns an empty attribute, the attribute is elided and the remaining functions
+// aren't called.  Nil functions are skipped.
+//
+//	opts := &console.HandlerOptions{
+//		ReplaceAttr: console.ChainReplaceAttr(
+//			console.RemoveKeys(slog.TimeKey),
+//			console.RenameKey("msg", "message"),
+//			console.Redact("password", "user.token"),
+//		),
+//	}
+func ChainReplaceAttr(fns ...ReplaceAttrFunc) ReplaceAttrFunc {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		for _, fn := range fns {
+			if fn == nil {
+				continue
+			}
+			a = fn(groups, a)
+			if a.Equal(slog.Attr{}) {
+				return a
+			}
+		}
+		return a
+	}
+}
+
+// attrPathMatches reports whether the attribute key, qualified by its groups and
+// joined with dots, equals path.  For example, the path "user.id" matches the key "id"
+// in the group "user".  Top level attributes, including the built-in attributes,
+// like slog.TimeKey, are matched by their key alone.
+func attrPathMatches(path string, groups []string, key string) bool {
+	if len(groups) == 0 {
+		return path == key
+	}
+	if !strings.HasSuffix(path, key) {
+		return false
+	}
+	path = path[:len(path)-len(key)]
+	for i := len(groups) - 1; i >= 0; i-- {
+		if !strings.HasSuffix(path, groups[i]+".") {
+			return false
+		}
+		path = path[:len(path)-len(groups[i])-1]
+	}
+	return path == ""
+}
+
+func matchesAny(paths []string, groups []string, key string) bool {
+	for _, p := range paths {
+		if attrPathMatches(p, groups, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveKeys returns a ReplaceAttr function which elides the attributes with the
+// given keys.  Keys of attributes in groups are qualified by the group names,
+// joined with dots, e.g. "request.headers".
+func RemoveKeys(keys ...string) ReplaceAttrFunc {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if matchesAny(keys, groups, a.Key) {
+			return slog.Attr{}
+		}
+		return a
+	}
+}
+
+// RenameKey returns a ReplaceAttr function which renames the attribute with the
+// key from to the key to.  from is qualified by the attribute's group names, joined
+// with dots, like RemoveKeys.  to is just the new key, and doesn't change the
+// attribute's group.
+func RenameKey(from, to string) ReplaceAttrFunc {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if attrPathMatches(from, groups, a.Key) {
+			a.Key = to
+		}
+		return a
+	}
+}
+
+// TimeAs returns a ReplaceAttr function which formats all time values, including
+// the record's timestamp, as strings with the given layout, instead of with
+// HandlerOptions.TimeFormat.  To only reformat some of them, call the returned
+// function from one which filters by key.
+func TimeAs(layout string) ReplaceAttrFunc {
+	return func(_ []string, a slog.Attr) slog.Attr {
+		if a.Value.Kind() == slog.KindTime {
+			a.Value = slog.StringValue(a.Value.Time().Format(layout))
+		}
+		return a
+	}
+}
+
+// Redact returns a ReplaceAttr function which replaces the values of the attributes
+// with the given keys with RedactedValue.  Keys are qualified by group names, like
+// RemoveKeys.
+func Redact(keys ...string) ReplaceAttrFunc {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if matchesAny(keys, groups, a.Key) {
+			a.Value = slog.StringValue(RedactedValue)
+		}
+		return a
+	}
+}
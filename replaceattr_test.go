@@ -0,0 +1,87 @@
+package console
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestChainReplaceAttr(t *testing.T) {
+	testTime := time.Date(2024, 01, 02, 15, 04, 05, 0, time.UTC)
+
+	tests := []handlerTest{
+		{
+			name: "remove keys",
+			opts: HandlerOptions{ReplaceAttr: RemoveKeys(slog.LevelKey, "foo", "g.bar")},
+			attrs: []slog.Attr{
+				slog.String("foo", "x"),
+				slog.String("bar", "y"),
+				slog.Group("g", slog.String("foo", "x"), slog.String("bar", "y")),
+			},
+			want: "remove keys bar=y g.foo=x\n",
+		},
+		{
+			name: "rename key",
+			opts: HandlerOptions{ReplaceAttr: RenameKey("g.h.foo", "baz")},
+			attrs: []slog.Attr{
+				slog.String("foo", "x"),
+				slog.Group("g", slog.Group("h", slog.String("foo", "y"))),
+			},
+			want: "INF rename key foo=x g.h.baz=y\n",
+		},
+		{
+			name: "time as",
+			opts: HandlerOptions{ReplaceAttr: TimeAs(time.Kitchen)},
+			time: testTime,
+			attrs: []slog.Attr{
+				slog.Time("at", testTime.Add(time.Hour)),
+			},
+			want: "3:04PM INF time as at=4:04PM\n",
+		},
+		{
+			name: "redact",
+			opts: HandlerOptions{ReplaceAttr: Redact("password", "user.token")},
+			attrs: []slog.Attr{
+				slog.String("password", "secret"),
+				slog.Group("user", slog.String("name", "bob"), slog.String("token", "secret")),
+			},
+			want: "INF redact password=[REDACTED] user.name=bob user.token=[REDACTED]\n",
+		},
+		{
+			name: "chain",
+			opts: HandlerOptions{ReplaceAttr: ChainReplaceAttr(
+				nil,
+				RenameKey("token", "password"),
+				Redact("password"),
+				RemoveKeys("foo"),
+				func(_ []string, a slog.Attr) slog.Attr {
+					if a.Key == "foo" {
+						t.Error("functions after an elided attr should not be called")
+					}
+					return a
+				},
+			)},
+			attrs: []slog.Attr{
+				slog.String("token", "secret"),
+				slog.String("foo", "bar"),
+			},
+			want: "INF chain password=[REDACTED]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt.opts.NoColor = true
+		tt.msg = tt.name
+		t.Run(tt.name, tt.run)
+	}
+}
+
+func TestAttrPathMatches(t *testing.T) {
+	AssertEqual(t, true, attrPathMatches("foo", nil, "foo"))
+	AssertEqual(t, false, attrPathMatches("foo", []string{"g"}, "foo"))
+	AssertEqual(t, true, attrPathMatches("g.foo", []string{"g"}, "foo"))
+	AssertEqual(t, true, attrPathMatches("g.h.foo", []string{"g", "h"}, "foo"))
+	AssertEqual(t, false, attrPathMatches("xg.h.foo", []string{"g", "h"}, "foo"))
+	AssertEqual(t, false, attrPathMatches("g.foo", []string{"g", "h"}, "foo"))
+	AssertEqual(t, false, attrPathMatches("g.xfoo", []string{"g"}, "foo"))
+}
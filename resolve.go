@@ -0,0 +1,71 @@
+package console
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+)
+
+// defaultMaxResolveDepth is the default for HandlerOptions.MaxResolveDepth.
+const defaultMaxResolveDepth = 32
+
+// cycleValue replaces values which can't be resolved because of a cycle, or
+// because they exceeded HandlerOptions.MaxResolveDepth.
+var cycleValue = slog.StringValue("!CYCLE")
+
+// resolve is like slog.Value.Resolve, but detects LogValuers which return themselves,
+// directly or via a group they are already being expanded in, and limits the length
+// of chains of LogValuers to HandlerOptions.MaxResolveDepth.  Those values are
+// replaced with "!CYCLE".
+//
+// If the value resolved to a group, the last LogValuer in the chain is also returned,
+// so encodeAttr can guard against it while encoding the group's attributes.
+func (e *encoder) resolve(v slog.Value) (slog.Value, slog.LogValuer) {
+	if v.Kind() != slog.KindLogValuer {
+		return v, nil
+	}
+	for i := 0; i < e.h.opts.MaxResolveDepth; i++ {
+		lv := v.LogValuer()
+		if e.expanding(lv) {
+			return cycleValue, nil
+		}
+		v = logValue(lv)
+		if v.Kind() != slog.KindLogValuer {
+			return v, lv
+		}
+		if sameValuer(lv, v.LogValuer()) {
+			return cycleValue, nil
+		}
+	}
+	return cycleValue, nil
+}
+
+// expanding reports whether lv is the LogValuer of one of the groups being encoded.
+func (e *encoder) expanding(lv slog.LogValuer) bool {
+	for _, g := range e.valuers {
+		if sameValuer(g, lv) {
+			return true
+		}
+	}
+	return false
+}
+
+// logValue calls lv.LogValue, recovering from panics like slog.Value.Resolve does.
+func logValue(lv slog.LogValuer) (v slog.Value) {
+	defer func() {
+		if r := recover(); r != nil {
+			v = slog.AnyValue(fmt.Errorf("LogValue panicked: %v", r))
+		}
+	}()
+	return lv.LogValue()
+}
+
+// sameValuer reports whether a and b are equal.  LogValuers which aren't comparable,
+// like funcs, or structs containing slices, are never equal.
+func sameValuer(a, b slog.LogValuer) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Type() != vb.Type() || !va.Comparable() {
+		return false
+	}
+	return va.Equal(vb)
+}
@@ -0,0 +1,79 @@
+package console
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+// selfValuer returns itself.
+type selfValuer struct{ n int }
+
+func (v selfValuer) LogValue() slog.Value { return slog.AnyValue(v) }
+
+// groupValuer returns a group containing itself.
+type groupValuer struct{ name string }
+
+func (v *groupValuer) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("name", v.name), slog.Any("self", v))
+}
+
+// chainValuer returns a new valuer each time, which can't be compared.
+type chainValuer struct{ depth []int }
+
+func (v chainValuer) LogValue() slog.Value {
+	return slog.AnyValue(chainValuer{depth: append(v.depth, len(v.depth))})
+}
+
+// nestValuer returns a group containing a new valuer, nesting forever.
+type nestValuer func() int
+
+func (v nestValuer) LogValue() slog.Value {
+	return slog.GroupValue(slog.Any("n", nestValuer(func() int { return v() + 1 })))
+}
+
+// countValuer resolves to its count after n more steps.
+type countValuer int
+
+func (v countValuer) LogValue() slog.Value {
+	if v == 0 {
+		return slog.StringValue("done")
+	}
+	return slog.AnyValue(v - 1)
+}
+
+type panicValuer struct{}
+
+func (panicValuer) LogValue() slog.Value { panic("boom") }
+
+func TestHandler_ResolveCycles(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  HandlerOptions
+		attrs []any
+		want  string
+	}{
+		{name: "self", attrs: []any{"v", selfValuer{1}}, want: "msg v=!CYCLE\n"},
+		{name: "group", attrs: []any{"v", &groupValuer{name: "a"}}, want: "msg v.name=a v.self=!CYCLE\n"},
+		{name: "chain", attrs: []any{"v", chainValuer{}}, want: "msg v=!CYCLE\n"},
+		{name: "nested groups", opts: HandlerOptions{MaxResolveDepth: 3}, attrs: []any{"v", nestValuer(func() int { return 0 })}, want: "msg v.n.n.n=!CYCLE\n"},
+		{name: "within depth", attrs: []any{"v", countValuer(10)}, want: "msg v=done\n"},
+		{name: "exceeds depth", opts: HandlerOptions{MaxResolveDepth: 5}, attrs: []any{"v", countValuer(10)}, want: "msg v=!CYCLE\n"},
+		{name: "panic", attrs: []any{"v", panicValuer{}}, want: "msg v=LogValue panicked: boom\n"},
+		{
+			name:  "same valuer in sibling groups",
+			attrs: []any{"a", &groupValuer{name: "x"}, "b", slog.GroupValue(slog.String("c", "d"))},
+			want:  "msg a.name=x a.self=!CYCLE b.c=d\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			opts := tt.opts
+			opts.NoColor = true
+			opts.HeaderFormat = "%m %a"
+			slog.New(NewHandler(&buf, &opts)).Info("msg", tt.attrs...)
+			AssertEqual(t, tt.want, buf.String())
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package console
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp added to the names of rotated files.  It
+// sorts lexically in chronological order.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotatingFileOptions are options for a RotatingFileWriter.
+// A zero RotatingFileOptions never rotates the file.
+type RotatingFileOptions struct {
+	// MaxSize is the maximum size of the log file in bytes.  If a write would
+	// grow the file beyond MaxSize, the file is rotated first.  If 0, the file
+	// is not rotated based on size.
+	MaxSize int64
+
+	// MaxAge is the maximum amount of time the writer will write to the same file.
+	// Once the file has been open longer than MaxAge, it is rotated before the next
+	// write.  If 0, the file is not rotated based on time.
+	MaxAge time.Duration
+
+	// MaxBackups is the maximum number of rotated files to keep.  The oldest
+	// are deleted first.  If 0, all rotated files are kept.
+	MaxBackups int
+
+	// Compress gzips rotated files.
+	Compress bool
+
+	// FileMode is the permission bits used when creating log files.
+	// If 0, 0644 is used.
+	FileMode os.FileMode
+}
+
+// RotatingFileWriter is an io.WriteCloser which writes to a file, and rotates it
+// when it gets too large or too old.  Rotated files are renamed by inserting a
+// timestamp between the name and the extension of the file, e.g. "app.log" is
+// renamed to "app-2024-01-02T15-04-05.000.log", and a new "app.log" is created.
+//
+// Each call to Write is written to the same file, so when used as the writer for a
+// Handler, records are never split across files.  Rotated files are compressed, and
+// old backups removed, in the background, so writes aren't blocked meanwhile.  A
+// write which triggers a rotation only fails if no file could be opened afterwards;
+// the other errors of rotating, compressing, and removing backups are returned by
+// Close.  It is safe for concurrent use.
+type RotatingFileWriter struct {
+	path string
+	opts RotatingFileOptions
+	now  func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	// errs are the errors of rotations triggered by Write, and of the background
+	// work, which are returned by Close
+	errs []error
+
+	// bg waits for the background work, which bgMu serializes
+	bg   sync.WaitGroup
+	bgMu sync.Mutex
+}
+
+var _ io.WriteCloser = (*RotatingFileWriter)(nil)
+
+// NewRotatingFileWriter opens the file at path for appending, creating it if
+// necessary, and returns a writer which rotates it according to the given options.
+// If opts is nil, the default options are used.
+func NewRotatingFileWriter(path string, opts *RotatingFileOptions) (*RotatingFileWriter, error) {
+	if opts == nil {
+		opts = new(RotatingFileOptions)
+	}
+	if opts.FileMode == 0 {
+		opts.FileMode = 0o644
+	}
+	w := &RotatingFileWriter{
+		path: path,
+		opts: *opts,
+		now:  time.Now,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write implements io.Writer.
+func (w *RotatingFileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if w.shouldRotate(len(p)) {
+		if err := w.rotate(); err != nil {
+			if w.file == nil {
+				return 0, err
+			}
+			// the record is still written, to the old file or the new one
+			w.errs = append(w.errs, err)
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, renames it, and opens a new one, regardless of
+// its size or age.
+func (w *RotatingFileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Close closes the current file, and waits for the compression and removal of the
+// backups to finish.  It returns the errors of closing the file, and those of the
+// rotations triggered by Write since the last call to Close.  Subsequent writes
+// return os.ErrClosed.
+func (w *RotatingFileWriter) Close() error {
+	w.mu.Lock()
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.mu.Unlock()
+
+	// the background work reports its errors under the lock
+	w.bg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err = errors.Join(append(w.errs, err)...)
+	w.errs = nil
+	return err
+}
+
+func (w *RotatingFileWriter) shouldRotate(n int) bool {
+	if w.size == 0 {
+		// never rotate an empty file, even if a single write is too large
+		return false
+	}
+	if w.opts.MaxSize > 0 && w.size+int64(n) > w.opts.MaxSize {
+		return true
+	}
+	return w.opts.MaxAge > 0 && w.now().Sub(w.opened) >= w.opts.MaxAge
+}
+
+func (w *RotatingFileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, w.opts.FileMode)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	w.opened = w.now()
+	return nil
+}
+
+func (w *RotatingFileWriter) rotate() error {
+	closeErr := w.file.Close()
+	w.file = nil
+
+	backup := w.backupName()
+	if err := os.Rename(w.path, backup); err != nil {
+		// keep writing to the current file, rather than failing every later write
+		return errors.Join(closeErr, err, w.open())
+	}
+
+	if err := w.open(); err != nil {
+		return errors.Join(closeErr, err)
+	}
+
+	if w.opts.Compress || w.opts.MaxBackups > 0 {
+		w.bg.Add(1)
+		go w.finishRotation(backup)
+	}
+	return closeErr
+}
+
+// finishRotation compresses the backup, and removes the old backups, in the
+// background.  Their errors are kept for Close.
+func (w *RotatingFileWriter) finishRotation(backup string) {
+	defer w.bg.Done()
+	w.bgMu.Lock()
+	var errs []error
+	if w.opts.Compress {
+		errs = append(errs, compressFile(backup))
+	}
+	errs = append(errs, w.removeOldBackups())
+	w.bgMu.Unlock()
+
+	if err := errors.Join(errs...); err != nil {
+		w.mu.Lock()
+		w.errs = append(w.errs, err)
+		w.mu.Unlock()
+	}
+}
+
+// backupName returns the path to rename the log file to.  If a backup with the
+// current time already exists, from an earlier rotation in the same millisecond, the
+// time is advanced a millisecond at a time until the name is free, so backups are
+// never overwritten and still sort in the order they were rotated.
+func (w *RotatingFileWriter) backupName() string {
+	dir, prefix, ext := w.backupParts()
+	t := w.now()
+	for {
+		backup := filepath.Join(dir, prefix+t.Format(backupTimeFormat)+ext)
+		if !fileExists(backup) && !fileExists(backup+".gz") {
+			return backup
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// backupParts returns the directory of the log file, and the prefix and extension
+// shared by all the backups of the log file.
+func (w *RotatingFileWriter) backupParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(w.path)
+	name := filepath.Base(w.path)
+	ext = filepath.Ext(name)
+	return dir, strings.TrimSuffix(name, ext) + "-", ext
+}
+
+func (w *RotatingFileWriter) removeOldBackups() error {
+	if w.opts.MaxBackups <= 0 {
+		return nil
+	}
+	dir, prefix, ext := w.backupParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var backups []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		// only consider files whose names contain a valid timestamp, so other
+		// files which happen to share the prefix are left alone
+		ts := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)[len(prefix):]
+		if _, err := time.Parse(backupTimeFormat, ts); err == nil {
+			backups = append(backups, name)
+		}
+	}
+	if len(backups) <= w.opts.MaxBackups {
+		return nil
+	}
+	slices.Sort(backups)
+	var errs []error
+	for _, name := range backups[:len(backups)-w.opts.MaxBackups] {
+		errs = append(errs, os.Remove(filepath.Join(dir, name)))
+	}
+	return errors.Join(errs...)
+}
+
+// compressFile gzips the file at path to path+".gz", and removes the original.
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(path + ".gz")
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	_ = src.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,163 @@
+package console
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func readDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	AssertNoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	slices.Sort(names)
+	return names
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	AssertNoError(t, err)
+	return string(b)
+}
+
+func TestRotatingFileWriter_Size(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w, err := NewRotatingFileWriter(path, &RotatingFileOptions{MaxSize: 10, MaxBackups: 2})
+	AssertNoError(t, err)
+	defer w.Close()
+
+	now := time.Date(2024, 01, 02, 15, 04, 05, 0, time.UTC)
+	w.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	for _, s := range []string{"one\n", "two\n", "three\n", "four\n", "five\n", "six\n"} {
+		_, err := io.WriteString(w, s)
+		AssertNoError(t, err)
+	}
+	// old backups are removed in the background
+	w.bg.Wait()
+
+	AssertEqual(t, "six\n", readFile(t, path))
+	names := readDir(t, dir)
+	AssertEqual(t, 3, len(names))
+	AssertEqual(t, "app-2024-01-02T15-04-08.000.log", names[0])
+	AssertEqual(t, "app-2024-01-02T15-04-10.000.log", names[1])
+	AssertEqual(t, "app.log", names[2])
+	AssertEqual(t, "three\n", readFile(t, filepath.Join(dir, names[0])))
+	AssertEqual(t, "four\nfive\n", readFile(t, filepath.Join(dir, names[1])))
+}
+
+func TestRotatingFileWriter_AgeAndCompress(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2024, 01, 02, 15, 04, 05, 0, time.UTC)
+
+	// existing content is appended to
+	AssertNoError(t, os.WriteFile(path, []byte("old\n"), 0o644))
+
+	w, err := NewRotatingFileWriter(path, &RotatingFileOptions{MaxAge: time.Hour, Compress: true})
+	AssertNoError(t, err)
+	w.now = func() time.Time { return now }
+	w.opened = now
+
+	h := NewHandler(w, &HandlerOptions{NoColor: true, HeaderFormat: "%m"})
+	slog.New(h).Info("first")
+	now = now.Add(time.Hour)
+	slog.New(h).Info("second")
+	AssertNoError(t, h.Close())
+
+	names := readDir(t, dir)
+	AssertEqual(t, 2, len(names))
+	AssertEqual(t, "app-2024-01-02T16-04-05.000.log.gz", names[0])
+	AssertEqual(t, "second\n", readFile(t, path))
+
+	f, err := os.Open(filepath.Join(dir, names[0]))
+	AssertNoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	AssertNoError(t, err)
+	b, err := io.ReadAll(gz)
+	AssertNoError(t, err)
+	AssertEqual(t, "old\nfirst\n", string(b))
+
+	_, err = w.Write([]byte("closed"))
+	AssertError(t, err)
+}
+
+func TestRotatingFileWriter_SameMillisecond(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2024, 01, 02, 15, 04, 05, 0, time.UTC)
+
+	w, err := NewRotatingFileWriter(path, nil)
+	AssertNoError(t, err)
+	defer w.Close()
+	w.now = func() time.Time { return now }
+
+	for _, s := range []string{"a\n", "b\n", "c\n"} {
+		_, err = w.Write([]byte(s))
+		AssertNoError(t, err)
+		AssertNoError(t, w.Rotate())
+	}
+
+	// backups rotated in the same millisecond don't overwrite each other
+	names := readDir(t, dir)
+	AssertEqual(t, 4, len(names))
+	AssertEqual(t, "a\n", readFile(t, filepath.Join(dir, "app-2024-01-02T15-04-05.000.log")))
+	AssertEqual(t, "b\n", readFile(t, filepath.Join(dir, "app-2024-01-02T15-04-05.001.log")))
+	AssertEqual(t, "c\n", readFile(t, filepath.Join(dir, "app-2024-01-02T15-04-05.002.log")))
+}
+
+func TestRotatingFileWriter_RenameError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w, err := NewRotatingFileWriter(path, nil)
+	AssertNoError(t, err)
+	defer w.Close()
+	_, err = w.Write([]byte("a\n"))
+	AssertNoError(t, err)
+
+	// the rename fails, since the file is gone
+	AssertNoError(t, os.Remove(path))
+	AssertError(t, w.Rotate())
+
+	// but the writer still works
+	_, err = w.Write([]byte("b\n"))
+	AssertNoError(t, err)
+	AssertEqual(t, "b\n", readFile(t, path))
+}
+
+func TestRotatingFileWriter_WriteErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w, err := NewRotatingFileWriter(path, &RotatingFileOptions{MaxSize: 3, MaxBackups: 1})
+	AssertNoError(t, err)
+	_, err = w.Write([]byte("a\n"))
+	AssertNoError(t, err)
+
+	// the rotation fails, but the record which triggered it is still written
+	AssertNoError(t, os.Remove(path))
+	n, err := w.Write([]byte("b\n"))
+	AssertNoError(t, err)
+	AssertEqual(t, 2, n)
+	AssertEqual(t, "b\n", readFile(t, path))
+
+	// the error is returned by Close, once
+	AssertError(t, w.Close())
+	AssertNoError(t, w.Close())
+}
@@ -0,0 +1,100 @@
+package console
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// defaultRuleWidth is the width of rules if the terminal width isn't known.
+const defaultRuleWidth = 80
+
+// ruleMarker is the value of the attribute returned by Rule.
+type ruleMarker struct{}
+
+// Rule returns an attribute which turns the record it's added to into a rule, like
+// [Handler.WriteRule], labeled with the record's message.  The record's other
+// attributes are ignored.  This lets code which only has a *slog.Logger print rules:
+//
+//	logger.Info("Phase 2", console.Rule())
+//
+// Handlers other than *Handler just print the attribute.
+func Rule() slog.Attr {
+	return slog.Any("rule", ruleMarker{})
+}
+
+// terminalWidth returns the width of the terminal, from the COLUMNS environment
+// variable, or 80.
+func terminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return defaultRuleWidth
+}
+
+// WriteRule writes a horizontal rule, spanning HandlerOptions.RuleWidth columns,
+// with an optional label, like:
+//
+//	── Phase 2 ─────────────────────────────────────
+//
+// Rules are handy for delimiting test cases, or the phases of a CLI tool.  The rule
+// is styled with the Theme's Header style, and the label with the Message style.
+func (h *Handler) WriteRule(label string) error {
+	enc := newEncoder(h)
+	defer enc.free()
+	enc.encodeRule(label)
+	enc.buf.AppendByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	err := h.flushRepeats()
+	if h.repeats != nil {
+		// the rule separates the lines before and after it
+		h.repeats.key = h.repeats.key[:0]
+	}
+	if _, werr := enc.buf.WriteTo(h.out.w); werr != nil {
+		err = werr
+	}
+	return err
+}
+
+// writeDateRule writes the rule of HandlerOptions.DateRules to out, if the date of t
+// differs from the date of the previous record.  Must be called with the mutex held.
+func (h *Handler) writeDateRule(out io.Writer, t time.Time) error {
+	y, m, d := t.Date()
+	day := int64(y)*10000 + int64(m)*100 + int64(d)
+	if h.lastDate.Swap(day) == day {
+		return nil
+	}
+	enc := newEncoder(h)
+	defer enc.free()
+	enc.encodeRule(t.Format(time.DateOnly))
+	enc.buf.AppendByte('\n')
+	_, err := enc.buf.WriteTo(out)
+	return err
+}
+
+// encodeRule encodes a rule with the label into buf, without a newline.
+func (e *encoder) encodeRule(label string) {
+	width := e.h.opts.RuleWidth
+	if label == "" {
+		e.writeRuleLine(width)
+		return
+	}
+	e.writeRuleLine(2)
+	e.buf.AppendByte(' ')
+	e.writeColoredString(&e.buf, label, e.h.opts.Theme.Message)
+	e.buf.AppendByte(' ')
+	e.writeRuleLine(max(width-4-utf8.RuneCountInString(label), 3))
+}
+
+func (e *encoder) writeRuleLine(n int) {
+	e.withColor(&e.buf, e.h.opts.Theme.Header, func() {
+		for ; n > 0; n-- {
+			e.buf.AppendString("─")
+		}
+	})
+}
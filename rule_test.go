@@ -0,0 +1,91 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTerminalWidth(t *testing.T) {
+	t.Setenv("COLUMNS", "")
+	AssertEqual(t, 80, terminalWidth())
+	t.Setenv("COLUMNS", "120")
+	AssertEqual(t, 120, terminalWidth())
+	t.Setenv("COLUMNS", "junk")
+	AssertEqual(t, 80, terminalWidth())
+}
+
+func TestHandler_WriteRule(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, RuleWidth: 20})
+
+	AssertNoError(t, h.WriteRule("Phase 2"))
+	AssertNoError(t, h.WriteRule(""))
+	AssertNoError(t, h.WriteRule("a very long label for a rule"))
+	want := "── Phase 2 ─────────\n" +
+		strings.Repeat("─", 20) + "\n" +
+		"── a very long label for a rule ───\n"
+	AssertEqual(t, want, buf.String())
+
+	buf.Reset()
+	theme := NewDefaultTheme()
+	h = NewHandler(&buf, &HandlerOptions{Theme: theme, RuleWidth: 10})
+	AssertNoError(t, h.WriteRule("x"))
+	AssertEqual(t, styled("──", theme.Header)+" "+styled("x", theme.Message)+" "+styled("─────", theme.Header)+"\n", buf.String())
+}
+
+func TestHandler_RuleAttr(t *testing.T) {
+	buf := bytes.Buffer{}
+	l := slog.New(NewHandler(&buf, &HandlerOptions{NoColor: true, RuleWidth: 20, HeaderFormat: "%l %m %a"}))
+	l.Info("before", "a", 1)
+	l.Info("Phase 2", Rule(), "ignored", "x\ny")
+	l.Info("after")
+	AssertEqual(t, "INF before a=1\n── Phase 2 ─────────\nINF after\n", buf.String())
+}
+
+func TestHandler_WriteRule_Repeats(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, RuleWidth: 5, HeaderFormat: "%m", CollapseRepeats: true})
+	l := slog.New(h)
+	l.Info("msg")
+	l.Info("msg")
+	AssertNoError(t, h.WriteRule(""))
+	l.Info("msg")
+	AssertEqual(t, "msg\nlast message repeated 1 time\n─────\nmsg\n", buf.String())
+}
+
+func TestHandler_DateRules(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, RuleWidth: 20, TimeFormat: time.Kitchen, HeaderFormat: "%t %m", DateRules: true})
+	derived := h.WithAttrs([]slog.Attr{slog.Int("a", 1)}).WithGroup("g")
+	day := time.Date(2024, 5, 7, 23, 59, 0, 0, time.UTC)
+	for _, r := range []struct {
+		h slog.Handler
+		t time.Time
+	}{
+		{h, day},
+		{derived, day.Add(30 * time.Second)},
+		{h, day.Add(2 * time.Minute)},
+		{derived, day.Add(2 * time.Minute)},
+		{h, day.Add(48 * time.Hour)},
+		// records without a time don't change the date
+		{h, time.Time{}},
+		{h, day.Add(48 * time.Hour)},
+	} {
+		AssertNoError(t, r.h.Handle(context.Background(), slog.NewRecord(r.t, slog.LevelInfo, "msg", 0)))
+	}
+	want := "── 2024-05-07 ──────\n" +
+		"11:59PM msg\n" +
+		"11:59PM msg\n" +
+		"── 2024-05-08 ──────\n" +
+		"12:01AM msg\n" +
+		"12:01AM msg\n" +
+		"── 2024-05-09 ──────\n" +
+		"11:59PM msg\n" +
+		"msg\n" +
+		"11:59PM msg\n"
+	AssertEqual(t, want, buf.String())
+}
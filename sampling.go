@@ -0,0 +1,161 @@
+package console
+
+import (
+	"log/slog"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// samplerBuckets is the number of counters a sampler tracks.  Records are
+// assigned to counters by hashing their level and sampling key, so keys may
+// occasionally share a counter.
+const samplerBuckets = 4096
+
+// SamplingOptions configure record sampling, which limits how many similar records
+// are logged, so chatty loops don't flood the console.
+//
+// Records are grouped by level and key (by default, the record's message).  Within
+// each Tick, the First records of each group are logged, and after that only every
+// Thereafter-th record is logged.  For example, with First: 3, Thereafter: 10, the
+// 1st, 2nd, 3rd, 13th, 23rd, etc records with the same message are logged.
+//
+// Levels can have their own policies, and records at or above PassLevel are
+// always logged, so, for example, debug records can be sampled while warnings
+// and errors are never dropped:
+//
+//	Sampling: &console.SamplingOptions{
+//		First:     10,
+//		Tick:      time.Second,
+//		PassLevel: slog.LevelWarn,
+//		Levels: map[slog.Level]console.LevelSampling{
+//			slog.LevelDebug: {First: 1, Thereafter: 100},
+//		},
+//	}
+type SamplingOptions struct {
+	// First is the number of records of each group logged in each Tick before sampling starts.
+	First int
+
+	// Thereafter logs every Nth record of each group after the first First records.
+	// If 0, all records after First are dropped, until the next Tick.
+	Thereafter int
+
+	// Tick is the interval after which the counters are reset.
+	// If 0, the counters are never reset.
+	Tick time.Duration
+
+	// Key returns the key used to group records.  If nil, records are grouped
+	// by their message.
+	Key func(rec slog.Record) string
+
+	// Levels sets the sampling policy of records of specific levels, replacing
+	// First, Thereafter and Tick.  Records of other levels use First, Thereafter
+	// and Tick.
+	Levels map[slog.Level]LevelSampling
+
+	// PassLevel, if set, is the level at or above which records are never sampled.
+	// It takes precedence over Levels.
+	PassLevel slog.Leveler
+}
+
+// LevelSampling is the sampling policy of a level.  See [SamplingOptions].  For
+// example, {First: 5, Tick: time.Second} limits a level to 5 similar records
+// per second.
+type LevelSampling struct {
+	// First is the number of records of each group logged in each Tick before sampling starts.
+	First int
+
+	// Thereafter logs every Nth record of each group after the first First records.
+	// If 0, all records after First are dropped, until the next Tick.
+	Thereafter int
+
+	// Tick is the interval after which the counters are reset.
+	// If 0, the counters are never reset.
+	Tick time.Duration
+}
+
+type sampleCounter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+}
+
+// inc increments the counter, and returns the new count.  If the counter's tick
+// has elapsed, the counter is reset first.
+func (c *sampleCounter) inc(t time.Time, tick time.Duration) uint64 {
+	now := t.UnixNano()
+	resetAt := c.resetAt.Load()
+	if resetAt > now {
+		return c.count.Add(1)
+	}
+
+	c.count.Store(1)
+	newResetAt := int64(math.MaxInt64)
+	if tick > 0 {
+		newResetAt = now + tick.Nanoseconds()
+	}
+	if !c.resetAt.CompareAndSwap(resetAt, newResetAt) {
+		// lost a race with another goroutine resetting the counter
+		return c.count.Add(1)
+	}
+	return 1
+}
+
+// sampler decides which records are logged.  It's shared by a Handler and all the
+// handlers derived from it.
+type sampler struct {
+	opts     SamplingOptions
+	counters [samplerBuckets]sampleCounter
+}
+
+func newSampler(opts SamplingOptions) *sampler {
+	if opts.Key == nil {
+		opts.Key = func(rec slog.Record) string { return rec.Message }
+	}
+	return &sampler{opts: opts}
+}
+
+// policy returns the sampling policy of the level.
+func (s *sampler) policy(l slog.Level) LevelSampling {
+	if p, ok := s.opts.Levels[l]; ok {
+		return p
+	}
+	return LevelSampling{First: s.opts.First, Thereafter: s.opts.Thereafter, Tick: s.opts.Tick}
+}
+
+// sample reports whether the record should be logged.
+func (s *sampler) sample(rec slog.Record) bool {
+	if s.opts.PassLevel != nil && rec.Level >= s.opts.PassLevel.Level() {
+		return true
+	}
+	p := s.policy(rec.Level)
+	c := &s.counters[sampleHash(rec.Level, s.opts.Key(rec))%samplerBuckets]
+
+	t := rec.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	n := c.inc(t, p.Tick)
+
+	first := uint64(max(p.First, 0))
+	if n <= first {
+		return true
+	}
+	return p.Thereafter > 0 && (n-first)%uint64(p.Thereafter) == 0
+}
+
+// sampleHash is an inlined FNV-1a hash of the level and key, which
+// avoids the allocations of hash/fnv.
+func sampleHash(l slog.Level, key string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	h := uint32(offset32)
+	h ^= uint32(byte(l))
+	h *= prime32
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= prime32
+	}
+	return h
+}
@@ -0,0 +1,113 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_Sampling(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{
+		NoColor:      true,
+		HeaderFormat: "%m %a",
+		Sampling:     &SamplingOptions{First: 2, Thereafter: 3, Tick: time.Second},
+	})
+	start := time.Date(2024, 01, 02, 15, 04, 05, 0, time.UTC)
+
+	log := func(h slog.Handler, tm time.Time, msg string, i int) {
+		rec := slog.NewRecord(tm, slog.LevelInfo, msg, 0)
+		rec.AddAttrs(slog.Int("i", i))
+		AssertNoError(t, h.Handle(context.Background(), rec))
+	}
+
+	for i := 1; i <= 8; i++ {
+		log(h, start, "loop", i)
+		// sampling is per message
+		log(h, start, "other", i)
+	}
+	// derived handlers share the counters
+	log(h.WithAttrs([]slog.Attr{slog.String("foo", "bar")}), start, "loop", 9)
+	log(h, start, "loop", 10)
+	// counters reset each tick
+	log(h, start.Add(time.Second), "loop", 11)
+
+	want := strings.Join([]string{
+		"loop i=1", "other i=1",
+		"loop i=2", "other i=2",
+		"loop i=5", "other i=5",
+		"loop i=8", "other i=8",
+		"loop i=11",
+		"",
+	}, "\n")
+	AssertEqual(t, want, buf.String())
+}
+
+func TestHandler_Sampling_Key(t *testing.T) {
+	buf := bytes.Buffer{}
+	l := slog.New(NewHandler(&buf, &HandlerOptions{
+		NoColor:      true,
+		HeaderFormat: "%m %a",
+		Sampling: &SamplingOptions{
+			First: 1,
+			Key: func(rec slog.Record) string {
+				var key string
+				rec.Attrs(func(a slog.Attr) bool {
+					if a.Key == "user" {
+						key = a.Value.String()
+					}
+					return true
+				})
+				return key
+			},
+		},
+	}))
+
+	for i := 0; i < 3; i++ {
+		l.Info(fmt.Sprint("msg", i), "user", "bob")
+		l.Info(fmt.Sprint("msg", i), "user", "alice")
+	}
+	AssertEqual(t, "msg0 user=bob\nmsg0 user=alice\n", buf.String())
+}
+
+func TestHandler_Sampling_Levels(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{
+		NoColor:      true,
+		HeaderFormat: "%l %m %a",
+		Sampling: &SamplingOptions{
+			First:     1,
+			PassLevel: slog.LevelWarn,
+			Levels: map[slog.Level]LevelSampling{
+				slog.LevelDebug: {First: 2, Thereafter: 0, Tick: time.Second},
+			},
+		},
+		Level: slog.LevelDebug,
+	})
+	start := time.Date(2024, 01, 02, 15, 04, 05, 0, time.UTC)
+
+	for i := 1; i <= 3; i++ {
+		for _, lvl := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError} {
+			rec := slog.NewRecord(start, lvl, "loop", 0)
+			rec.AddAttrs(slog.Int("i", i))
+			AssertNoError(t, h.Handle(context.Background(), rec))
+		}
+	}
+	// the debug policy's tick resets its counters
+	rec := slog.NewRecord(start.Add(time.Second), slog.LevelDebug, "loop", 0)
+	rec.AddAttrs(slog.Int("i", 4))
+	AssertNoError(t, h.Handle(context.Background(), rec))
+
+	want := strings.Join([]string{
+		"DBG loop i=1", "INF loop i=1", "WRN loop i=1", "ERR loop i=1",
+		"DBG loop i=2", "WRN loop i=2", "ERR loop i=2",
+		"WRN loop i=3", "ERR loop i=3",
+		"DBG loop i=4",
+		"",
+	}, "\n")
+	AssertEqual(t, want, buf.String())
+}
@@ -0,0 +1,65 @@
+package console
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+)
+
+// parseSlogtestLines parses the output of a handler configured with
+// slogtestOptions.  Each line is a series of space separated key=value
+// pairs, and dotted keys are nested into sub maps, the same as groups.
+func parseSlogtestLines(t *testing.T, b []byte) []map[string]any {
+	t.Helper()
+	var results []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSuffix(b, []byte("\n")), []byte("\n")) {
+		m := map[string]any{}
+		for _, field := range strings.Fields(string(line)) {
+			key, val, ok := strings.Cut(field, "=")
+			if !ok {
+				t.Fatalf("malformed field %q in line %q", field, line)
+			}
+			if key == slog.TimeKey {
+				tm, err := time.Parse(time.RFC3339Nano, val)
+				if err != nil {
+					t.Fatalf("malformed time %q: %v", val, err)
+				}
+				m[key] = tm
+				continue
+			}
+			keys := strings.Split(key, ".")
+			sub := m
+			for _, k := range keys[:len(keys)-1] {
+				next, ok := sub[k].(map[string]any)
+				if !ok {
+					next = map[string]any{}
+					sub[k] = next
+				}
+				sub = next
+			}
+			sub[keys[len(keys)-1]] = val
+		}
+		results = append(results, m)
+	}
+	return results
+}
+
+var slogtestOptions = HandlerOptions{
+	NoColor:      true,
+	TimeFormat:   time.RFC3339Nano,
+	HeaderFormat: "%{time=%t%} level=%L msg=%m %a",
+}
+
+func TestSlogtest(t *testing.T) {
+	var buf bytes.Buffer
+	opts := slogtestOptions
+	err := slogtest.TestHandler(NewHandler(&buf, &opts), func() []map[string]any {
+		return parseSlogtestLines(t, buf.Bytes())
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,53 @@
+package console
+
+import (
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// StackKey is the key of the attribute holding the stack traces added by
+// HandlerOptions.AddStackOnLevel.
+const StackKey = "stack"
+
+// maxStackDepth is the maximum number of frames in the stack traces added by
+// HandlerOptions.AddStackOnLevel.
+const maxStackDepth = 64
+
+// stackAttr returns an attribute with the stack trace of the goroutine, starting
+// at the frame which logged the record, if it's found in the stack, or else the
+// whole stack.  Each frame is printed like the frames of panics, on two lines:
+//
+//	main.run
+//		/src/app/main.go:42
+func stackAttr(pc uintptr) slog.Attr {
+	var pcs [maxStackDepth]uintptr
+	// skip runtime.Callers and stackAttr
+	n := runtime.Callers(2, pcs[:])
+	stack := pcs[:n]
+	for i, p := range stack {
+		if p == pc {
+			stack = stack[i:]
+			break
+		}
+	}
+
+	var sb strings.Builder
+	frames := runtime.CallersFrames(stack)
+	for {
+		frame, more := frames.Next()
+		if sb.Len() > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(frame.Function)
+		sb.WriteString("\n\t")
+		sb.WriteString(frame.File)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(frame.Line))
+		if !more {
+			break
+		}
+	}
+	return slog.String(StackKey, sb.String())
+}
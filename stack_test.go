@@ -0,0 +1,52 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_AddStackOnLevel(t *testing.T) {
+	buf := bytes.Buffer{}
+	l := slog.New(NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %m %a", AddStackOnLevel: slog.LevelError}))
+	l.Warn("no stack")
+	AssertEqual(t, "WRN no stack\n", buf.String())
+
+	buf.Reset()
+	l.Error("failed", "a", 1)
+	out := buf.String()
+	prefix := "ERR failed a=1\n=== stack ===\ngithub.com/ansel1/console-slog.TestHandler_AddStackOnLevel\n\t"
+	if !strings.HasPrefix(out, prefix) {
+		t.Fatalf("expected stack starting at the test, got:\n%s", out)
+	}
+	AssertEqual(t, true, strings.Contains(out, "stack_test.go:"))
+	AssertEqual(t, true, strings.Contains(out, "\ntesting.tRunner\n\t"))
+	AssertEqual(t, false, strings.Contains(out, "log/slog"))
+}
+
+func TestHandler_AddStackOnLevel_SharedRecord(t *testing.T) {
+	// the same record is passed to several handlers, e.g. by a fan-out handler
+	var buf1, buf2 bytes.Buffer
+	opts := &HandlerOptions{NoColor: true, HeaderFormat: "%m %a", AddStackOnLevel: slog.LevelError}
+	h1, h2 := NewHandler(&buf1, opts), NewHandler(&buf2, opts)
+
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	rec := slog.NewRecord(time.Now(), slog.LevelError, "msg", pcs[0])
+	for i := 0; i < 8; i++ {
+		rec.AddAttrs(slog.Int(strconv.Itoa(i), i))
+	}
+	AssertNoError(t, h1.Handle(context.Background(), rec))
+	AssertNoError(t, h2.Handle(context.Background(), rec))
+
+	for _, out := range []string{buf1.String(), buf2.String()} {
+		AssertEqual(t, true, strings.HasPrefix(out, "msg 0=0 1=1 2=2 3=3 4=4 5=5 6=6 7=7\n=== stack ===\n"))
+		AssertEqual(t, false, strings.Contains(out, "BUG"))
+	}
+	AssertEqual(t, 8, rec.NumAttrs())
+}
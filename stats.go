@@ -0,0 +1,87 @@
+package console
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+// Stats are the counts of the records handled by a Handler, and all the handlers
+// derived from it.  See HandlerOptions.CollectStats.
+//
+// Stats can be published with expvar, for example:
+//
+//	expvar.Publish("logging", expvar.Func(func() any { return h.Stats() }))
+//
+// or exported as Prometheus metrics by a collector which calls Stats on each scrape.
+type Stats struct {
+	// Records is the number of records written.
+	Records uint64
+
+	// Bytes is the number of bytes written.
+	Bytes uint64
+
+	// Filtered is the number of records dropped because of the level of the package
+	// they were logged from.  See [Handler.SetPackageLevel].  Records dropped by
+	// Enabled never reach the handler, so aren't counted.
+	Filtered uint64
+
+	// Sampled is the number of records dropped by sampling.  See HandlerOptions.Sampling.
+	Sampled uint64
+
+	// Suppressed is the number of repeated records suppressed by
+	// HandlerOptions.CollapseRepeats.
+	Suppressed uint64
+
+	// WriteErrors is the number of records which couldn't be written, because the
+	// writer returned an error.
+	WriteErrors uint64
+
+	// Levels are the numbers of records written, by level.  Levels without
+	// records are omitted.
+	Levels map[slog.Level]uint64
+}
+
+// statsCollector counts the records handled by a Handler.  It's shared by a
+// Handler and all the handlers derived from it.
+type statsCollector struct {
+	records, bytes, filtered, sampled, suppressed, writeErrors atomic.Uint64
+	// levels counts records by level, from minCachedLevel to maxCachedLevel.  Records
+	// with levels outside of that range are counted at the nearest end.
+	levels [maxCachedLevel - minCachedLevel + 1]atomic.Uint64
+}
+
+// written counts a record written to the output.
+func (s *statsCollector) written(l slog.Level, n int64, err error) {
+	if err != nil {
+		s.writeErrors.Add(1)
+		return
+	}
+	s.records.Add(1)
+	s.bytes.Add(uint64(n))
+	s.levels[min(max(l, minCachedLevel), maxCachedLevel)-minCachedLevel].Add(1)
+}
+
+// Stats returns the current counts of the records handled by the handler, and all
+// the handlers derived from it.  Unless HandlerOptions.CollectStats is set, the
+// counts are all 0.
+func (h *Handler) Stats() Stats {
+	s := h.stats
+	if s == nil {
+		return Stats{}
+	}
+	stats := Stats{
+		Records:     s.records.Load(),
+		Bytes:       s.bytes.Load(),
+		Filtered:    s.filtered.Load(),
+		Sampled:     s.sampled.Load(),
+		Suppressed:  s.suppressed.Load(),
+		WriteErrors: s.writeErrors.Load(),
+		Levels:      map[slog.Level]uint64{},
+	}
+	for i := range s.levels {
+		if n := s.levels[i].Load(); n > 0 {
+			stats.Levels[slog.Level(i)+minCachedLevel] = n
+		}
+	}
+	return stats
+}
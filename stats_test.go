@@ -0,0 +1,62 @@
+package console
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+)
+
+func TestHandler_Stats(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{
+		NoColor:         true,
+		HeaderFormat:    "%l %m",
+		Level:           slog.LevelDebug,
+		CollectStats:    true,
+		CollapseRepeats: true,
+		Sampling:        &SamplingOptions{First: 2, PassLevel: slog.LevelWarn},
+	})
+	l := slog.New(h)
+	l.Debug("a")
+	l.Debug("b")
+	l.Debug("b")
+	l.Debug("b") // sampled
+	l.With("x", 1).Warn("c")
+	l.Error("d")
+	h.SetPackageLevel("github.com/ansel1/console-slog", slog.LevelError)
+	l.Info("filtered")
+	AssertNoError(t, h.Flush())
+
+	stats := h.Stats()
+	AssertEqual(t, 4, stats.Records)
+	AssertEqual(t, len("DBG a\nDBG b\nWRN c\nERR d\n"), int(stats.Bytes))
+	AssertEqual(t, 1, stats.Filtered)
+	AssertEqual(t, 1, stats.Sampled)
+	AssertEqual(t, 1, stats.Suppressed)
+	AssertEqual(t, 0, stats.WriteErrors)
+	AssertEqual(t, "map[DEBUG:2 WARN:1 ERROR:1]", fmt.Sprint(stats.Levels))
+
+	// the stats can be published with expvar
+	b, err := json.Marshal(h.Stats())
+	AssertNoError(t, err)
+	AssertEqual(t, `{"Records":4,"Bytes":24,"Filtered":1,"Sampled":1,"Suppressed":1,"WriteErrors":0,"Levels":{"DEBUG":2,"ERROR":1,"WARN":1}}`, string(b))
+}
+
+func TestHandler_Stats_Disabled(t *testing.T) {
+	h := NewHandler(&bytes.Buffer{}, &HandlerOptions{NoColor: true})
+	slog.New(h).Info("msg")
+	AssertEqual(t, "{0 0 0 0 0 0 map[]}", fmt.Sprint(h.Stats()))
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("boom") }
+
+func TestHandler_Stats_WriteErrors(t *testing.T) {
+	h := NewHandler(failingWriter{}, &HandlerOptions{NoColor: true, CollectStats: true})
+	slog.New(h).Info("msg")
+	AssertEqual(t, "{0 0 0 0 0 1 map[]}", fmt.Sprint(h.Stats()))
+}
@@ -0,0 +1,124 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// NewStdlogWriter returns a writer which prints each line written to it as a
+// record, so the output of the log package, and of libraries which take a
+// *log.Logger, like http.Server.ErrorLog, is printed like the rest of the logs:
+//
+//	h := console.NewHandler(os.Stderr, nil)
+//	log.SetOutput(console.NewStdlogWriter(h, slog.LevelInfo))
+//	log.SetFlags(0)
+//
+// The level of a line is sniffed from a level prefix, either in brackets, like
+// "[WARN] disk full", or followed by a colon, like "ERROR: connection reset".  The
+// prefix is removed from the message.  Level names are case-insensitive: "debug",
+// "info", "warn" or "warning", "error", "fatal", and "panic" are recognized, along
+// with their abbreviations, like "WRN".  Lines without a level prefix are printed
+// at defaultLevel.
+//
+// The date and time the log package prints with its default flags are removed from
+// the message, since the record has its own time, but setting the flags to 0 is
+// cheaper.  Empty lines are dropped.
+//
+// Each line written is a record.  Records split across writes are printed once
+// they're complete.  Call the writer's Flush method to print the last line if it
+// wasn't terminated by a newline.  It is safe for concurrent use.
+func NewStdlogWriter(h *Handler, defaultLevel slog.Level) *LineWriter {
+	return &LineWriter{handle: func(line []byte) error {
+		line = bytes.TrimSpace(stripStdlogTime(line))
+		if len(line) == 0 {
+			return nil
+		}
+		level, msg := sniffLevel(string(line), defaultLevel)
+		ctx := context.Background()
+		if !h.Enabled(ctx, level) {
+			return nil
+		}
+		return h.Handle(ctx, slog.NewRecord(time.Now(), level, msg, 0))
+	}}
+}
+
+// stdlogLevels are the levels of the level names sniffed from log lines.
+var stdlogLevels = map[string]slog.Level{
+	"TRACE":   slog.LevelDebug - 4,
+	"DEBUG":   slog.LevelDebug,
+	"DBG":     slog.LevelDebug,
+	"INFO":    slog.LevelInfo,
+	"INF":     slog.LevelInfo,
+	"WARN":    slog.LevelWarn,
+	"WARNING": slog.LevelWarn,
+	"WRN":     slog.LevelWarn,
+	"ERROR":   slog.LevelError,
+	"ERR":     slog.LevelError,
+	"FATAL":   slog.LevelError + 4,
+	"PANIC":   slog.LevelError + 8,
+}
+
+// sniffLevel returns the level of a line's level prefix, and the line without the
+// prefix, or defaultLevel and the line if the line has no level prefix.
+func sniffLevel(line string, defaultLevel slog.Level) (slog.Level, string) {
+	var name, rest string
+	if strings.HasPrefix(line, "[") {
+		end := strings.IndexByte(line, ']')
+		if end < 0 {
+			return defaultLevel, line
+		}
+		name, rest = line[1:end], line[end+1:]
+	} else {
+		end := strings.IndexByte(line, ':')
+		if end < 0 {
+			return defaultLevel, line
+		}
+		name, rest = line[:end], line[end+1:]
+	}
+	if l, ok := stdlogLevels[strings.ToUpper(name)]; ok {
+		return l, strings.TrimLeft(rest, " \t")
+	}
+	return defaultLevel, line
+}
+
+// stripStdlogTime removes the date and time the log package prints with its
+// LstdFlags, like "2009/01/23 01:23:23 ", or with Lmicroseconds, from the start of
+// the line.
+func stripStdlogTime(line []byte) []byte {
+	// "2009/01/23 "
+	if len(line) >= 11 && matchDigits(line[:11], "dddd/dd/dd ") {
+		line = line[11:]
+	}
+	// "01:23:23 ", or "01:23:23.123123 "
+	if len(line) >= 9 && matchDigits(line[:8], "dd:dd:dd") {
+		i := 8
+		if line[i] == '.' {
+			i++
+			for i < len(line) && line[i] >= '0' && line[i] <= '9' {
+				i++
+			}
+		}
+		if i < len(line) && line[i] == ' ' {
+			line = line[i+1:]
+		}
+	}
+	return line
+}
+
+// matchDigits reports whether b matches the pattern, where each 'd' in the pattern
+// matches a digit, and the other bytes match themselves.
+func matchDigits(b []byte, pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == 'd' {
+			if b[i] < '0' || b[i] > '9' {
+				return false
+			}
+		} else if b[i] != pattern[i] {
+			return false
+		}
+	}
+	return true
+}
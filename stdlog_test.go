@@ -0,0 +1,58 @@
+package console
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"log/slog"
+	"testing"
+)
+
+func TestStdlogWriter(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %m"})
+	w := NewStdlogWriter(h, slog.LevelInfo)
+
+	tests := []struct {
+		name, in, want string
+	}{
+		{name: "no prefix", in: "plain line\n", want: "INF plain line\n"},
+		{name: "colon", in: "ERROR: connection reset\n", want: "ERR connection reset\n"},
+		{name: "brackets", in: "[WARN] disk almost full\n", want: "WRN disk almost full\n"},
+		{name: "lower case", in: "warning: deprecated\n[error]failed\n", want: "WRN deprecated\nERR failed\n"},
+		{name: "abbreviated", in: "[WRN] slow\n", want: "WRN slow\n"},
+		{name: "fatal", in: "FATAL: bye\n", want: "ERR+4 bye\n"},
+		{name: "filtered level", in: "DEBUG: hidden\n", want: ""},
+		{name: "unknown prefix", in: "http: TLS handshake error\n[main] started\n", want: "INF http: TLS handshake error\nINF [main] started\n"},
+		{name: "std time", in: "2009/01/23 01:23:23 ERROR: failed\n2009/01/23 01:23:23.123456 ok\n", want: "ERR failed\nINF ok\n"},
+		{name: "blank lines", in: "\n  \n", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			n, err := io.WriteString(w, tt.in)
+			AssertNoError(t, err)
+			AssertEqual(t, len(tt.in), n)
+			AssertEqual(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestStdlogWriter_Logger(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %m"})
+	w := NewStdlogWriter(h, slog.LevelWarn)
+
+	// with the default flags
+	l := log.New(w, "", log.LstdFlags|log.Lmicroseconds)
+	l.Print("no level")
+	l.Printf("ERROR: %d failed", 3)
+	AssertEqual(t, "WRN no level\nERR 3 failed\n", buf.String())
+
+	// the last line is printed by Flush
+	buf.Reset()
+	io.WriteString(w, "[INFO] no newline")
+	AssertEqual(t, "", buf.String())
+	AssertNoError(t, w.Flush())
+	AssertEqual(t, "INF no newline\n", buf.String())
+}
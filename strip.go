@@ -0,0 +1,108 @@
+package console
+
+import (
+	"io"
+	"sync"
+)
+
+// csiLen returns the length of the ANSI CSI escape sequence, like the SGR sequences
+// of ANSIMod, at the start of b.  It returns 0 if b doesn't start with a CSI sequence,
+// and -1 if b ends before the sequence does.
+func csiLen(b []byte) int {
+	if len(b) == 0 || b[0] != '\x1b' {
+		return 0
+	}
+	if len(b) == 1 {
+		return -1
+	}
+	if b[1] != '[' {
+		return 0
+	}
+	// the final byte of the sequence is in the range 0x40-0x7e
+	for i := 2; i < len(b); i++ {
+		if b[i] >= 0x40 && b[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// appendStripped appends b, minus the escape sequences in it, to dst.  If b ends in
+// the middle of an escape sequence, the incomplete sequence isn't appended, and rest
+// is its offset in b.  Otherwise, rest is len(b).
+func appendStripped(dst, b []byte) (_ []byte, rest int) {
+	start := 0
+	for i := 0; i < len(b); i++ {
+		if b[i] != '\x1b' {
+			continue
+		}
+		n := csiLen(b[i:])
+		if n == 0 {
+			continue
+		}
+		dst = append(dst, b[start:i]...)
+		if n < 0 {
+			return dst, i
+		}
+		i += n - 1
+		start = i + 1
+	}
+	return append(dst, b[start:]...), len(b)
+}
+
+// StripANSI returns a copy of b without the ANSI escape sequences the Handler
+// uses for colors, e.g. to clean up colored logs saved to a file.
+func StripANSI(b []byte) []byte {
+	dst, _ := appendStripped(make([]byte, 0, len(b)), b)
+	return dst
+}
+
+// StripANSIWriter returns a writer which removes the ANSI escape sequences the
+// Handler uses for colors, and writes the rest to w.  Escape sequences may be
+// split across writes.  It's handy to tee colored output to a file:
+//
+//	out := io.MultiWriter(os.Stderr, console.StripANSIWriter(file))
+//	logger := slog.New(console.NewHandler(out, &console.HandlerOptions{ColorAlways: true}))
+func StripANSIWriter(w io.Writer) io.Writer {
+	return &stripANSIWriter{out: w}
+}
+
+// maxPendingEscape is the longest incomplete escape sequence a stripANSIWriter
+// keeps until the next write.  Longer ones, e.g. an escape byte followed by a
+// long run of digits, are written as text, so the pending bytes can't grow without
+// bound.  The sequences the Handler writes are much shorter.
+const maxPendingEscape = 64
+
+type stripANSIWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+	// pending is an incomplete escape sequence at the end of the last write
+	pending []byte
+	buf     []byte
+}
+
+func (w *stripANSIWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	b := p
+	if len(w.pending) > 0 {
+		w.pending = append(w.pending, p...)
+		b = w.pending
+	}
+	var rest int
+	w.buf, rest = appendStripped(w.buf[:0], b)
+	if len(b)-rest > maxPendingEscape {
+		// too long to be an escape sequence, so it's text
+		w.buf = append(w.buf, b[rest:]...)
+		rest = len(b)
+	}
+	w.pending = append(w.pending[:0], b[rest:]...)
+
+	if len(w.buf) > 0 {
+		if _, err := w.out.Write(w.buf); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
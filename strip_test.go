@@ -0,0 +1,66 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"plain", "plain"},
+		{"\x1b[1;31mred\x1b[0m text", "red text"},
+		{"\x1b[38;2;1;2;3mtrue\x1b[0m", "true"},
+		{"\r\x1b[2Kcleared", "\rcleared"},
+		{"esc \x1b alone", "esc \x1b alone"},
+		{"incomplete \x1b[1;3", "incomplete "},
+	}
+	for _, test := range tests {
+		AssertEqual(t, test.want, string(StripANSI([]byte(test.in))))
+	}
+}
+
+func TestStripANSIWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := StripANSIWriter(&buf)
+
+	// sequences split across writes
+	for _, s := range []string{"a\x1b", "[1;3", "1mb\x1b[0", "m", "c\n"} {
+		n, err := w.Write([]byte(s))
+		AssertNoError(t, err)
+		AssertEqual(t, len(s), n)
+	}
+	AssertEqual(t, "abc\n", buf.String())
+
+	buf.Reset()
+	h := NewHandler(w, &HandlerOptions{ColorAlways: true})
+	AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(time.Date(2024, 01, 02, 15, 04, 05, 0, time.UTC), slog.LevelWarn, "careful", 0)))
+	AssertEqual(t, "2024-01-02 15:04:05 WRN careful\n", buf.String())
+}
+
+func TestStripANSIWriter_UnterminatedEscape(t *testing.T) {
+	var buf bytes.Buffer
+	w := StripANSIWriter(&buf).(*stripANSIWriter)
+
+	// an escape byte followed by digits which never end the sequence is
+	// written as text once it's too long to be an escape sequence
+	_, err := w.Write([]byte("a\x1b[" + strings.Repeat("1", 30)))
+	AssertNoError(t, err)
+	AssertEqual(t, "a", buf.String())
+	_, err = w.Write([]byte(strings.Repeat("2", 40)))
+	AssertNoError(t, err)
+	AssertEqual(t, "a\x1b["+strings.Repeat("1", 30)+strings.Repeat("2", 40), buf.String())
+	AssertEqual(t, 0, len(w.pending))
+
+	// later sequences are still stripped
+	buf.Reset()
+	_, err = w.Write([]byte("\x1b[1mb\x1b[0m\n"))
+	AssertNoError(t, err)
+	AssertEqual(t, "b\n", buf.String())
+}
@@ -0,0 +1,114 @@
+package console
+
+import (
+	"log/slog"
+	"reflect"
+)
+
+// tableValue marks a slice of structs which should be rendered as a table.
+type tableValue struct {
+	rows any
+}
+
+// Table wraps a slice (or array) of structs, or pointers to structs, so the Handler
+// renders it as an aligned, multi-line table, with one column per exported field and
+// one line per element:
+//
+//	logger.Info("results", "users", console.Table(users))
+//
+// Since the table spans multiple lines, it is printed below the record, like any
+// other multiline attribute.  Fields tagged with `table:"-"` are skipped, and the
+// column header can be renamed with `table:"name"`.
+//
+// Values which aren't slices of structs are rendered as usual.
+func Table(rows any) slog.Value {
+	return slog.AnyValue(tableValue{rows: rows})
+}
+
+// tableRows returns v as a slice of structs, if it is one.
+func tableRows(v any) (reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return rv, false
+	}
+	et := rv.Type().Elem()
+	if et.Kind() == reflect.Pointer {
+		et = et.Elem()
+	}
+	return rv, et.Kind() == reflect.Struct
+}
+
+// writeTable writes rows as an aligned table.  Columns are separated by two spaces,
+// and the header row uses the Header style.
+func (e *encoder) writeTable(buf *buffer, rows reflect.Value) {
+	et := rows.Type().Elem()
+	ptr := et.Kind() == reflect.Pointer
+	if ptr {
+		et = et.Elem()
+	}
+
+	var cols []int
+	var names []string
+	for i := 0; i < et.NumField(); i++ {
+		f := et.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("table"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		cols = append(cols, i)
+		names = append(names, name)
+	}
+
+	// render every cell up front so the column widths are known
+	cells := make([][]string, 0, rows.Len())
+	widths := make([]int, len(cols))
+	for i, name := range names {
+		widths[i] = visibleWidth([]byte(name))
+	}
+	var cell buffer
+	for r := 0; r < rows.Len(); r++ {
+		row := rows.Index(r)
+		if ptr {
+			if row.IsNil() {
+				continue
+			}
+			row = row.Elem()
+		}
+		line := make([]string, len(cols))
+		for i, c := range cols {
+			cell.Reset()
+			e.writeValue(&cell, slog.AnyValue(row.Field(c).Interface()).Resolve())
+			line[i] = cell.String()
+			if w := visibleWidth(cell); w > widths[i] {
+				widths[i] = w
+			}
+		}
+		cells = append(cells, line)
+	}
+
+	writeRow := func(line []string, style ANSIMod) {
+		for i, s := range line {
+			if i > 0 {
+				buf.AppendString("  ")
+			}
+			e.writeColoredString(buf, s, style)
+			if i < len(line)-1 {
+				buf.Pad(widths[i]-visibleWidth([]byte(s)), ' ')
+			}
+		}
+	}
+
+	writeRow(names, e.h.opts.Theme.Header)
+	for _, line := range cells {
+		buf.AppendByte('\n')
+		writeRow(line, "")
+	}
+}
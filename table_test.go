@@ -0,0 +1,70 @@
+package console
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type tableRow struct {
+	Name    string
+	Age     int
+	Elapsed time.Duration `table:"took"`
+	secret  string
+	Skipped bool `table:"-"`
+}
+
+func TestHandler_Table(t *testing.T) {
+	rows := []tableRow{
+		{Name: "alice", Age: 30, Elapsed: time.Second, secret: "x"},
+		{Name: "bob", Age: 4, Elapsed: 1500 * time.Millisecond},
+	}
+
+	tests := []handlerTest{
+		{
+			name:  "table wrapper",
+			attrs: []slog.Attr{slog.Any("users", Table(rows)), slog.String("foo", "bar")},
+			want:  "INF table wrapper foo=bar\n=== users ===\nName   Age  took\nalice  30   1s\nbob    4    1.5s\n",
+		},
+		{
+			name:  "pointer rows",
+			attrs: []slog.Attr{slog.Any("users", Table([]*tableRow{&rows[1], nil}))},
+			want:  "INF pointer rows\n=== users ===\nName  Age  took\nbob   4    1.5s\n",
+		},
+		{
+			name:  "not a struct slice",
+			attrs: []slog.Attr{slog.Any("nums", Table([]int{1, 2}))},
+			want:  "INF not a struct slice nums=[1 2]\n",
+		},
+		{
+			name:  "slices are not tables by default",
+			attrs: []slog.Attr{slog.Any("users", rows[:1])},
+			want:  "INF slices are not tables by default users=[{alice 30 1s x false}]\n",
+		},
+		{
+			name:  "render tables",
+			opts:  HandlerOptions{RenderTables: true},
+			attrs: []slog.Attr{slog.Any("users", rows[:1])},
+			want:  "INF render tables\n=== users ===\nName   Age  took\nalice  30   1s\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt.opts.NoColor = true
+		tt.msg = tt.name
+		t.Run(tt.name, tt.run)
+	}
+}
+
+func TestHandler_Table_Colors(t *testing.T) {
+	theme := NewDefaultTheme()
+	handlerTest{
+		opts:  HandlerOptions{HeaderFormat: "%m %a"},
+		msg:   "msg",
+		attrs: []slog.Attr{slog.Any("t", Table([]struct{ A, Bee string }{{"x", "y"}}))},
+		want: styled("msg", theme.Message) + "\n" +
+			styled("=== t ===\n", theme.AttrKey) +
+			styled("A", theme.Header) + "  " + styled("Bee", theme.Header) + "\n" +
+			"x  y\n",
+	}.run(t)
+}
@@ -0,0 +1,163 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+type teeHandler struct {
+	handlers []slog.Handler
+}
+
+var _ slog.Handler = (*teeHandler)(nil)
+var _ io.Closer = (*teeHandler)(nil)
+
+// NewTeeHandler creates a handler which forwards each record to all of the given
+// handlers.  For example, to print colorized output to the console, and write JSON
+// to a file:
+//
+//	slog.New(console.NewTeeHandler(
+//		console.NewHandler(os.Stderr, nil),
+//		slog.NewJSONHandler(file, nil),
+//	))
+//
+// The tee is enabled for a level if any of the handlers is enabled for it, and
+// each record is only passed to the handlers which are enabled for its level.
+// Errors returned by the handlers are joined.
+//
+// The returned handler also implements io.Closer, and has a Flush() error method,
+// which are forwarded to any of the handlers which implement them.
+func NewTeeHandler(handlers ...slog.Handler) slog.Handler {
+	return &teeHandler{handlers: handlers}
+}
+
+// Enabled implements slog.Handler.
+func (t *teeHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, h := range t.handlers {
+		if h.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle implements slog.Handler.
+func (t *teeHandler) Handle(ctx context.Context, rec slog.Record) error {
+	var errs []error
+	for _, h := range t.handlers {
+		if !h.Enabled(ctx, rec.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, rec.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs implements slog.Handler.
+func (t *teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(t.handlers))
+	for i, h := range t.handlers {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return &teeHandler{handlers: handlers}
+}
+
+// WithGroup implements slog.Handler.
+func (t *teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(t.handlers))
+	for i, h := range t.handlers {
+		handlers[i] = h.WithGroup(name)
+	}
+	return &teeHandler{handlers: handlers}
+}
+
+// Flush flushes each of the handlers which has a Flush() error method.
+func (t *teeHandler) Flush() error {
+	var errs []error
+	for _, h := range t.handlers {
+		errs = append(errs, flush(h))
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes each of the handlers which implements io.Closer.
+func (t *teeHandler) Close() error {
+	var errs []error
+	for _, h := range t.handlers {
+		if c, ok := h.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Output is one of the destinations of a handler created by NewMultiWriterHandler.
+type Output struct {
+	// Writer is where the records are written.
+	Writer io.Writer
+
+	// NoColor disables colors for this output, regardless of HandlerOptions.NoColor
+	// and ColorAlways, e.g. for a log file.
+	NoColor bool
+
+	// Theme, if set, replaces HandlerOptions.Theme for this output.
+	Theme *Theme
+}
+
+// NewMultiWriterHandler creates a handler which writes each record to all of the
+// outputs, each with its own color settings, e.g. in color to the console, and in
+// plain text to a file:
+//
+//	console.NewMultiWriterHandler(nil,
+//		console.Output{Writer: os.Stderr},
+//		console.Output{Writer: file, NoColor: true},
+//	)
+//
+// Records are encoded once for each distinct pair of NoColor and Theme, so outputs
+// with the same settings share the encoding.  Themes are compared by pointer.  The
+// other options apply to all the outputs.  Like NewTeeHandler, the returned handler
+// also implements io.Closer, and has a Flush() error method.
+func NewMultiWriterHandler(opts *HandlerOptions, outputs ...Output) slog.Handler {
+	if opts == nil {
+		opts = &HandlerOptions{}
+	}
+	type config struct {
+		noColor bool
+		theme   *Theme
+	}
+	var configs []config
+	var writers [][]io.Writer
+outputs:
+	for _, o := range outputs {
+		c := config{noColor: o.NoColor, theme: o.Theme}
+		for i := range configs {
+			if configs[i] == c {
+				writers[i] = append(writers[i], o.Writer)
+				continue outputs
+			}
+		}
+		configs = append(configs, c)
+		writers = append(writers, []io.Writer{o.Writer})
+	}
+
+	handlers := make([]slog.Handler, len(configs))
+	for i, c := range configs {
+		o := *opts
+		if c.noColor {
+			o.NoColor, o.ColorAlways = true, false
+		}
+		if c.theme != nil {
+			o.Theme = *c.theme
+		}
+		w := writers[i][0]
+		if len(writers[i]) > 1 {
+			w = io.MultiWriter(writers[i]...)
+		}
+		handlers[i] = NewHandler(w, &o)
+	}
+	return &teeHandler{handlers: handlers}
+}
@@ -0,0 +1,78 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestTeeHandler(t *testing.T) {
+	var console, json bytes.Buffer
+	h := NewTeeHandler(
+		NewHandler(&console, &HandlerOptions{NoColor: true, Level: slog.LevelWarn, HeaderFormat: "%l %m %a"}),
+		slog.NewJSONHandler(&json, &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+			ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}),
+	)
+
+	AssertEqual(t, true, h.Enabled(context.Background(), slog.LevelDebug))
+	AssertEqual(t, false, h.Enabled(context.Background(), slog.LevelDebug-1))
+
+	l := slog.New(h).With("foo", "bar").WithGroup("g")
+	l.Debug("debug", "a", 1)
+	l.Warn("warn", "b", 2)
+
+	AssertEqual(t, "WRN warn foo=bar g.b=2\n", console.String())
+	AssertEqual(t,
+		`{"level":"DEBUG","msg":"debug","foo":"bar","g":{"a":1}}`+"\n"+
+			`{"level":"WARN","msg":"warn","foo":"bar","g":{"b":2}}`+"\n",
+		json.String())
+}
+
+func TestTeeHandler_Errors(t *testing.T) {
+	errOne, errTwo := errors.New("one"), errors.New("two")
+	h := NewTeeHandler(
+		NewHandler(writerFunc(func([]byte) (int, error) { return 0, errOne }), nil),
+		NewHandler(&bytes.Buffer{}, nil),
+		NewHandler(writerFunc(func([]byte) (int, error) { return 0, errTwo }), nil),
+	)
+	err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "msg", 0))
+	AssertEqual(t, true, errors.Is(err, errOne))
+	AssertEqual(t, true, errors.Is(err, errTwo))
+
+	AssertEqual(t, false, NewTeeHandler().Enabled(context.Background(), slog.LevelError))
+	AssertNoError(t, NewTeeHandler().Handle(context.Background(), slog.Record{}))
+}
+
+func TestNewMultiWriterHandler(t *testing.T) {
+	var console, console2, file bytes.Buffer
+	theme := Theme{Name: "test", Message: ToANSICode(Bold)}
+	plain := Theme{Name: "plain"}
+	h := NewMultiWriterHandler(&HandlerOptions{HeaderFormat: "%m %a", Theme: theme, ColorAlways: true},
+		Output{Writer: &console},
+		Output{Writer: &file, NoColor: true},
+		Output{Writer: &console2},
+	)
+	// outputs with the same settings share a handler
+	AssertEqual(t, 2, len(h.(*teeHandler).handlers))
+
+	slog.New(h).With("a", 1).Info("msg")
+	AssertEqual(t, styled("msg", theme.Message)+" a=1\n", console.String())
+	AssertEqual(t, console.String(), console2.String())
+	AssertEqual(t, "msg a=1\n", file.String())
+
+	console.Reset()
+	slog.New(NewMultiWriterHandler(&HandlerOptions{HeaderFormat: "%m", ColorAlways: true},
+		Output{Writer: &console, Theme: &plain},
+	)).Info("msg")
+	AssertEqual(t, "msg\n", console.String())
+}
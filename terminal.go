@@ -0,0 +1,73 @@
+package console
+
+import (
+	"io"
+	"log/slog"
+	"os"
+)
+
+// isTerminal reports whether w is a file attached to a terminal.  It's a
+// portable approximation, which checks if the file is a character device.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || f == nil {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// forceColor returns whether colors are forced on or off by the FORCE_COLOR
+// environment variable.  ok is false if FORCE_COLOR isn't set.
+func forceColor() (color, ok bool) {
+	switch v := os.Getenv("FORCE_COLOR"); v {
+	case "":
+		return false, false
+	case "0", "false":
+		return false, true
+	default:
+		return true, true
+	}
+}
+
+// colorForced reports whether colors are forced on by opts or the environment.
+func colorForced(opts *HandlerOptions) bool {
+	if opts != nil && opts.ColorAlways {
+		return true
+	}
+	color, _ := forceColor()
+	return color
+}
+
+// NewDefaultHandler returns a Handler writing colorized console output to w if w is a
+// terminal, or a slog.JSONHandler writing to w otherwise.  This gives services readable
+// logs during local development, and machine readable logs in production, with a
+// single line of setup:
+//
+//	slog.SetDefault(slog.New(console.NewDefaultHandler(os.Stderr, nil)))
+//
+// If colors are forced, with HandlerOptions.ColorAlways or the FORCE_COLOR environment
+// variable, the console handler is used even if w isn't a terminal.
+//
+// The JSON handler is configured with the Level, AddSource, and ReplaceAttr options.
+// The other options only apply to the console handler.
+func NewDefaultHandler(w io.Writer, opts *HandlerOptions) slog.Handler {
+	return newDefaultHandler(w, opts, isTerminal(w) || colorForced(opts))
+}
+
+func newDefaultHandler(w io.Writer, opts *HandlerOptions, tty bool) slog.Handler {
+	if tty {
+		return NewHandler(w, opts)
+	}
+	if opts == nil {
+		opts = new(HandlerOptions)
+	}
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{
+		AddSource:   opts.AddSource,
+		Level:       opts.Level,
+		ReplaceAttr: opts.ReplaceAttr,
+	})
+}
@@ -0,0 +1,79 @@
+package console
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsTerminal(t *testing.T) {
+	AssertEqual(t, false, isTerminal(&bytes.Buffer{}))
+	AssertEqual(t, false, isTerminal((*os.File)(nil)))
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "log"))
+	AssertNoError(t, err)
+	defer f.Close()
+	AssertEqual(t, false, isTerminal(f))
+}
+
+func TestNewDefaultHandler(t *testing.T) {
+	// not a terminal
+	_, ok := NewDefaultHandler(&bytes.Buffer{}, nil).(*slog.JSONHandler)
+	AssertEqual(t, true, ok)
+
+	_, ok = newDefaultHandler(&bytes.Buffer{}, nil, true).(*Handler)
+	AssertEqual(t, true, ok)
+
+	buf := bytes.Buffer{}
+	l := slog.New(newDefaultHandler(&buf, &HandlerOptions{
+		Level:       slog.LevelWarn,
+		ReplaceAttr: RemoveKeys(slog.TimeKey),
+		NoColor:     true,
+	}, false))
+	l.Info("dropped")
+	l.Warn("kept", "foo", "bar")
+	AssertEqual(t, `{"level":"WARN","msg":"kept","foo":"bar"}`+"\n", buf.String())
+}
+
+func TestHandler_ColorAlways(t *testing.T) {
+	theme := NewDefaultTheme()
+	colored := styled("msg", theme.Message) + "\n"
+
+	log := func(opts HandlerOptions) string {
+		buf := bytes.Buffer{}
+		opts.HeaderFormat = "%m"
+		slog.New(NewHandler(&buf, &opts)).Info("msg")
+		return buf.String()
+	}
+
+	AssertEqual(t, colored, log(HandlerOptions{}))
+	AssertEqual(t, "msg\n", log(HandlerOptions{NoColor: true}))
+	AssertEqual(t, colored, log(HandlerOptions{NoColor: true, ColorAlways: true}))
+	AssertEqual(t, colored, log(HandlerOptions{JournaldPrefix: true, ColorAlways: true})[3:])
+
+	t.Setenv("CI", "true")
+	AssertEqual(t, "msg\n", log(HandlerOptions{}))
+	AssertEqual(t, colored, log(HandlerOptions{ColorAlways: true}))
+
+	t.Setenv("FORCE_COLOR", "1")
+	AssertEqual(t, colored, log(HandlerOptions{}))
+	// explicit options win over the environment
+	AssertEqual(t, "msg\n", log(HandlerOptions{NoColor: true}))
+	AssertEqual(t, "<6>msg\n", log(HandlerOptions{JournaldPrefix: true}))
+
+	t.Setenv("CI", "")
+	t.Setenv("FORCE_COLOR", "0")
+	AssertEqual(t, "msg\n", log(HandlerOptions{}))
+	AssertEqual(t, colored, log(HandlerOptions{ColorAlways: true}))
+}
+
+func TestNewDefaultHandler_ColorAlways(t *testing.T) {
+	_, ok := NewDefaultHandler(&bytes.Buffer{}, &HandlerOptions{ColorAlways: true}).(*Handler)
+	AssertEqual(t, true, ok)
+
+	t.Setenv("FORCE_COLOR", "true")
+	_, ok = NewDefaultHandler(&bytes.Buffer{}, nil).(*Handler)
+	AssertEqual(t, true, ok)
+}
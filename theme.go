@@ -2,6 +2,10 @@ package console
 
 import (
 	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
 )
 
 type ANSIMod string
@@ -63,15 +67,74 @@ type Theme struct {
 	Timestamp      ANSIMod
 	Header         ANSIMod
 	Source         ANSIMod
+	SourceWarn     ANSIMod
+	SourceError    ANSIMod
 	Message        ANSIMod
 	MessageDebug   ANSIMod
 	AttrKey        ANSIMod
 	AttrValue      ANSIMod
 	AttrValueError ANSIMod
+	AttrValueTrue  ANSIMod
+	AttrValueFalse ANSIMod
 	LevelError     ANSIMod
 	LevelWarn      ANSIMod
 	LevelInfo      ANSIMod
 	LevelDebug     ANSIMod
+	LoggerName     ANSIMod
+	Continuation   ANSIMod
+	// DiffAdded and DiffRemoved style the added and removed lines of unified diffs.
+	// See Diff.
+	DiffAdded   ANSIMod
+	DiffRemoved ANSIMod
+	// levelColors are the styles of specific levels, set with WithLevelColor.  The
+	// table is never modified once set, so themes stay comparable and cheap to copy.
+	levelColors *[]levelColor
+}
+
+// levelColor is the style of a specific level, see Theme.WithLevelColor.
+type levelColor struct {
+	level slog.Level
+	style ANSIMod
+}
+
+// WithLevelColor returns a copy of the theme which styles the level with style,
+// taking precedence over LevelError, LevelWarn, LevelInfo, and LevelDebug, which
+// style the bands of levels.  It gives custom levels, like an AUDIT level (INFO+2)
+// or a TRACE level (DEBUG-4), their own colors:
+//
+//	theme := console.NewDefaultTheme().
+//		WithLevelColor(slog.LevelInfo+2, console.ToANSICode(console.Magenta))
+func (t Theme) WithLevelColor(level slog.Level, style ANSIMod) Theme {
+	var colors []levelColor
+	if t.levelColors != nil {
+		colors = *t.levelColors
+	}
+	i, found := slices.BinarySearchFunc(colors, level, func(c levelColor, l slog.Level) int {
+		return int(c.level - l)
+	})
+	colors = slices.Clone(colors)
+	if found {
+		colors[i].style = style
+	} else {
+		colors = slices.Insert(colors, i, levelColor{level: level, style: style})
+	}
+	t.levelColors = &colors
+	return t
+}
+
+// LevelColor returns the style set for the level with WithLevelColor, if any.
+func (t Theme) LevelColor(level slog.Level) (ANSIMod, bool) {
+	if t.levelColors == nil {
+		return "", false
+	}
+	colors := *t.levelColors
+	i, found := slices.BinarySearchFunc(colors, level, func(c levelColor, l slog.Level) int {
+		return int(c.level - l)
+	})
+	if !found {
+		return "", false
+	}
+	return colors[i].style, true
 }
 
 func NewDefaultTheme() Theme {
@@ -80,15 +143,23 @@ func NewDefaultTheme() Theme {
 		Timestamp:      ToANSICode(Faint),
 		Header:         ToANSICode(Faint, Bold),
 		Source:         ToANSICode(BrightBlack, Italic),
+		SourceWarn:     ToANSICode(Yellow, Italic),
+		SourceError:    ToANSICode(BrightRed, Italic),
 		Message:        ToANSICode(Bold),
 		MessageDebug:   ToANSICode(Bold),
 		AttrKey:        ToANSICode(Faint, Green),
 		AttrValue:      ToANSICode(),
 		AttrValueError: ToANSICode(Bold, Red),
+		AttrValueTrue:  ToANSICode(Green),
+		AttrValueFalse: ToANSICode(Red),
 		LevelError:     ToANSICode(Red),
 		LevelWarn:      ToANSICode(Yellow),
 		LevelInfo:      ToANSICode(Cyan),
 		LevelDebug:     ToANSICode(BrightMagenta),
+		LoggerName:     ToANSICode(Faint, Blue),
+		Continuation:   ToANSICode(Faint),
+		DiffAdded:      ToANSICode(Green),
+		DiffRemoved:    ToANSICode(Red),
 	}
 }
 
@@ -98,14 +169,259 @@ func NewBrightTheme() Theme {
 		Timestamp:      ToANSICode(Gray),
 		Header:         ToANSICode(Bold, Gray),
 		Source:         ToANSICode(Gray, Bold, Italic),
+		SourceWarn:     ToANSICode(BrightYellow, Bold, Italic),
+		SourceError:    ToANSICode(BrightRed, Bold, Italic),
 		Message:        ToANSICode(Bold, White),
 		MessageDebug:   ToANSICode(),
 		AttrKey:        ToANSICode(BrightCyan),
 		AttrValue:      ToANSICode(),
 		AttrValueError: ToANSICode(Bold, BrightRed),
+		AttrValueTrue:  ToANSICode(BrightGreen),
+		AttrValueFalse: ToANSICode(BrightRed),
 		LevelError:     ToANSICode(BrightRed),
 		LevelWarn:      ToANSICode(BrightYellow),
 		LevelInfo:      ToANSICode(BrightGreen),
 		LevelDebug:     ToANSICode(),
+		LoggerName:     ToANSICode(BrightBlue),
+		Continuation:   ToANSICode(Gray),
+		DiffAdded:      ToANSICode(BrightGreen),
+		DiffRemoved:    ToANSICode(BrightRed),
+	}
+}
+
+// NewMonoTheme returns a theme which only uses emphasis, like bold, faint,
+// italic, and underline, and no colors.  It works with any terminal palette, and
+// for users who prefer not to see colors.
+func NewMonoTheme() Theme {
+	return Theme{
+		Name:           "Mono",
+		Timestamp:      ToANSICode(Faint),
+		Header:         ToANSICode(Faint, Bold),
+		Source:         ToANSICode(Faint, Italic),
+		SourceWarn:     ToANSICode(Italic),
+		SourceError:    ToANSICode(Bold, Italic),
+		Message:        ToANSICode(Bold),
+		MessageDebug:   ToANSICode(),
+		AttrKey:        ToANSICode(Faint),
+		AttrValue:      ToANSICode(),
+		AttrValueError: ToANSICode(Bold, Underline),
+		AttrValueTrue:  ToANSICode(),
+		AttrValueFalse: ToANSICode(Bold),
+		LevelError:     ToANSICode(Bold, Underline),
+		LevelWarn:      ToANSICode(Bold),
+		LevelInfo:      ToANSICode(),
+		LevelDebug:     ToANSICode(Faint),
+		LoggerName:     ToANSICode(Italic),
+		Continuation:   ToANSICode(Faint),
+		DiffAdded:      ToANSICode(Bold),
+		DiffRemoved:    ToANSICode(Faint),
+	}
+}
+
+// NewColorblindTheme returns a theme which is safe for the most common forms of
+// color blindness, deuteranopia and protanopia.  It distinguishes levels with blue
+// and orange, from the Okabe-Ito palette, rather than red and green, and uses
+// emphasis for errors.  It requires a terminal with 256 colors.
+func NewColorblindTheme() Theme {
+	return Theme{
+		Name:           "Colorblind",
+		Timestamp:      ToANSICode(Faint),
+		Header:         ToANSICode(Faint, Bold),
+		Source:         ToANSICode(Faint, Italic),
+		SourceWarn:     ToANSICode(Italic, 38, 5, 214), // orange
+		SourceError:    ToANSICode(Italic, 38, 5, 166), // vermillion
+		Message:        ToANSICode(Bold),
+		MessageDebug:   ToANSICode(),
+		AttrKey:        ToANSICode(38, 5, 74), // sky blue
+		AttrValue:      ToANSICode(),
+		AttrValueError: ToANSICode(Bold, 38, 5, 166),            // vermillion
+		AttrValueTrue:  ToANSICode(38, 5, 25),                   // blue
+		AttrValueFalse: ToANSICode(38, 5, 166),                  // vermillion
+		LevelError:     ToANSICode(Bold, Underline, 38, 5, 166), // vermillion
+		LevelWarn:      ToANSICode(38, 5, 214),                  // orange
+		LevelInfo:      ToANSICode(38, 5, 25),                   // blue
+		LevelDebug:     ToANSICode(38, 5, 175),                  // reddish purple
+		LoggerName:     ToANSICode(38, 5, 74),                   // sky blue
+		Continuation:   ToANSICode(Faint),
+		DiffAdded:      ToANSICode(38, 5, 25),  // blue
+		DiffRemoved:    ToANSICode(38, 5, 166), // vermillion
+	}
+}
+
+// NewSolarizedTheme returns a theme using the Solarized palette, in 24-bit color.
+func NewSolarizedTheme() Theme {
+	return Theme{
+		Name:           "Solarized",
+		Timestamp:      ToANSICode(38, 2, 0x58, 0x6e, 0x75),         // base01
+		Header:         ToANSICode(Bold, 38, 2, 0x58, 0x6e, 0x75),   // base01
+		Source:         ToANSICode(Italic, 38, 2, 0x6c, 0x71, 0xc4), // violet
+		SourceWarn:     ToANSICode(Italic, 38, 2, 0xb5, 0x89, 0x00), // yellow
+		SourceError:    ToANSICode(Italic, 38, 2, 0xdc, 0x32, 0x2f), // red
+		Message:        ToANSICode(Bold, 38, 2, 0x93, 0xa1, 0xa1),   // base1
+		MessageDebug:   ToANSICode(38, 2, 0x83, 0x94, 0x96),         // base0
+		AttrKey:        ToANSICode(38, 2, 0x26, 0x8b, 0xd2),         // blue
+		AttrValue:      ToANSICode(38, 2, 0x83, 0x94, 0x96),         // base0
+		AttrValueError: ToANSICode(Bold, 38, 2, 0xdc, 0x32, 0x2f),   // red
+		AttrValueTrue:  ToANSICode(38, 2, 0x85, 0x99, 0x00),         // green
+		AttrValueFalse: ToANSICode(38, 2, 0xdc, 0x32, 0x2f),         // red
+		LevelError:     ToANSICode(38, 2, 0xdc, 0x32, 0x2f),         // red
+		LevelWarn:      ToANSICode(38, 2, 0xb5, 0x89, 0x00),         // yellow
+		LevelInfo:      ToANSICode(38, 2, 0x2a, 0xa1, 0x98),         // cyan
+		LevelDebug:     ToANSICode(38, 2, 0xd3, 0x36, 0x82),         // magenta
+		LoggerName:     ToANSICode(38, 2, 0x85, 0x99, 0x00),         // green
+		Continuation:   ToANSICode(38, 2, 0x58, 0x6e, 0x75),         // base01
+		DiffAdded:      ToANSICode(38, 2, 0x85, 0x99, 0x00),         // green
+		DiffRemoved:    ToANSICode(38, 2, 0xdc, 0x32, 0x2f),         // red
+	}
+}
+
+// NewDraculaTheme returns a theme using the Dracula palette, in 24-bit color.
+func NewDraculaTheme() Theme {
+	return Theme{
+		Name:           "Dracula",
+		Timestamp:      ToANSICode(38, 2, 0x62, 0x72, 0xa4),         // comment
+		Header:         ToANSICode(Bold, 38, 2, 0x62, 0x72, 0xa4),   // comment
+		Source:         ToANSICode(Italic, 38, 2, 0x62, 0x72, 0xa4), // comment
+		SourceWarn:     ToANSICode(Italic, 38, 2, 0xff, 0xb8, 0x6c), // orange
+		SourceError:    ToANSICode(Italic, 38, 2, 0xff, 0x55, 0x55), // red
+		Message:        ToANSICode(Bold, 38, 2, 0xf8, 0xf8, 0xf2),   // foreground
+		MessageDebug:   ToANSICode(38, 2, 0xf8, 0xf8, 0xf2),         // foreground
+		AttrKey:        ToANSICode(38, 2, 0xbd, 0x93, 0xf9),         // purple
+		AttrValue:      ToANSICode(38, 2, 0xf8, 0xf8, 0xf2),         // foreground
+		AttrValueError: ToANSICode(Bold, 38, 2, 0xff, 0x55, 0x55),   // red
+		AttrValueTrue:  ToANSICode(38, 2, 0x50, 0xfa, 0x7b),         // green
+		AttrValueFalse: ToANSICode(38, 2, 0xff, 0x55, 0x55),         // red
+		LevelError:     ToANSICode(38, 2, 0xff, 0x55, 0x55),         // red
+		LevelWarn:      ToANSICode(38, 2, 0xff, 0xb8, 0x6c),         // orange
+		LevelInfo:      ToANSICode(38, 2, 0x8b, 0xe9, 0xfd),         // cyan
+		LevelDebug:     ToANSICode(38, 2, 0xff, 0x79, 0xc6),         // pink
+		LoggerName:     ToANSICode(38, 2, 0x50, 0xfa, 0x7b),         // green
+		Continuation:   ToANSICode(38, 2, 0x62, 0x72, 0xa4),         // comment
+		DiffAdded:      ToANSICode(38, 2, 0x50, 0xfa, 0x7b),         // green
+		DiffRemoved:    ToANSICode(38, 2, 0xff, 0x55, 0x55),         // red
+	}
+}
+
+// NewNordTheme returns a theme using the Nord palette, in 24-bit color.
+func NewNordTheme() Theme {
+	return Theme{
+		Name:           "Nord",
+		Timestamp:      ToANSICode(38, 2, 0x4c, 0x56, 0x6a),         // nord3
+		Header:         ToANSICode(Bold, 38, 2, 0x4c, 0x56, 0x6a),   // nord3
+		Source:         ToANSICode(Italic, 38, 2, 0x4c, 0x56, 0x6a), // nord3
+		SourceWarn:     ToANSICode(Italic, 38, 2, 0xeb, 0xcb, 0x8b), // nord13
+		SourceError:    ToANSICode(Italic, 38, 2, 0xbf, 0x61, 0x6a), // nord11
+		Message:        ToANSICode(Bold, 38, 2, 0xec, 0xef, 0xf4),   // nord6
+		MessageDebug:   ToANSICode(38, 2, 0xd8, 0xde, 0xe9),         // nord4
+		AttrKey:        ToANSICode(38, 2, 0x81, 0xa1, 0xc1),         // nord9
+		AttrValue:      ToANSICode(38, 2, 0xd8, 0xde, 0xe9),         // nord4
+		AttrValueError: ToANSICode(Bold, 38, 2, 0xbf, 0x61, 0x6a),   // nord11
+		AttrValueTrue:  ToANSICode(38, 2, 0xa3, 0xbe, 0x8c),         // nord14
+		AttrValueFalse: ToANSICode(38, 2, 0xbf, 0x61, 0x6a),         // nord11
+		LevelError:     ToANSICode(38, 2, 0xbf, 0x61, 0x6a),         // nord11
+		LevelWarn:      ToANSICode(38, 2, 0xeb, 0xcb, 0x8b),         // nord13
+		LevelInfo:      ToANSICode(38, 2, 0x88, 0xc0, 0xd0),         // nord8
+		LevelDebug:     ToANSICode(38, 2, 0xb4, 0x8e, 0xad),         // nord15
+		LoggerName:     ToANSICode(38, 2, 0xa3, 0xbe, 0x8c),         // nord14
+		Continuation:   ToANSICode(38, 2, 0x4c, 0x56, 0x6a),         // nord3
+		DiffAdded:      ToANSICode(38, 2, 0xa3, 0xbe, 0x8c),         // nord14
+		DiffRemoved:    ToANSICode(38, 2, 0xbf, 0x61, 0x6a),         // nord11
+	}
+}
+
+// With returns a copy of the theme, with the non-empty styles of overrides, and its
+// Name, if it has one, replacing those of the theme, so a theme can be adjusted
+// without restating all its styles:
+//
+//	theme := console.NewDefaultTheme().With(console.Theme{
+//		AttrKey: console.ToANSICode(console.Blue),
+//	})
+//
+// The level colors of both themes are merged, where those of overrides win.
+func (t Theme) With(overrides Theme) Theme {
+	override := func(s *ANSIMod, o ANSIMod) {
+		if o != "" {
+			*s = o
+		}
+	}
+	if overrides.Name != "" {
+		t.Name = overrides.Name
+	}
+	override(&t.Timestamp, overrides.Timestamp)
+	override(&t.Header, overrides.Header)
+	override(&t.Source, overrides.Source)
+	override(&t.SourceWarn, overrides.SourceWarn)
+	override(&t.SourceError, overrides.SourceError)
+	override(&t.Message, overrides.Message)
+	override(&t.MessageDebug, overrides.MessageDebug)
+	override(&t.AttrKey, overrides.AttrKey)
+	override(&t.AttrValue, overrides.AttrValue)
+	override(&t.AttrValueError, overrides.AttrValueError)
+	override(&t.AttrValueTrue, overrides.AttrValueTrue)
+	override(&t.AttrValueFalse, overrides.AttrValueFalse)
+	override(&t.LevelError, overrides.LevelError)
+	override(&t.LevelWarn, overrides.LevelWarn)
+	override(&t.LevelInfo, overrides.LevelInfo)
+	override(&t.LevelDebug, overrides.LevelDebug)
+	override(&t.LoggerName, overrides.LoggerName)
+	override(&t.Continuation, overrides.Continuation)
+	override(&t.DiffAdded, overrides.DiffAdded)
+	override(&t.DiffRemoved, overrides.DiffRemoved)
+	if overrides.levelColors != nil {
+		for _, c := range *overrides.levelColors {
+			t = t.WithLevelColor(c.level, c.style)
+		}
+	}
+	return t
+}
+
+var (
+	themesMu sync.RWMutex
+	themes   = map[string]func() Theme{
+		"default":    NewDefaultTheme,
+		"bright":     NewBrightTheme,
+		"mono":       NewMonoTheme,
+		"colorblind": NewColorblindTheme,
+		"solarized":  NewSolarizedTheme,
+		"dracula":    NewDraculaTheme,
+		"nord":       NewNordTheme,
+	}
+)
+
+// RegisterTheme makes a theme available to ThemeByName.  The name is case-insensitive.
+// If a theme with the name is already registered, it's replaced.
+func RegisterTheme(name string, newTheme func() Theme) {
+	themesMu.Lock()
+	defer themesMu.Unlock()
+	themes[strings.ToLower(name)] = newTheme
+}
+
+// ThemeByName returns the built-in or registered theme with the given name, e.g.
+// "dracula".  The name is case-insensitive.  It returns false if there is no theme
+// with the name, so the theme can be selected with a flag or environment variable:
+//
+//	theme, ok := console.ThemeByName(os.Getenv("LOG_THEME"))
+//	if !ok {
+//		theme = console.NewDefaultTheme()
+//	}
+func ThemeByName(name string) (Theme, bool) {
+	themesMu.RLock()
+	newTheme, ok := themes[strings.ToLower(name)]
+	themesMu.RUnlock()
+	if !ok {
+		return Theme{}, false
+	}
+	return newTheme(), true
+}
+
+// ThemeNames returns the names of the built-in and registered themes, sorted.
+func ThemeNames() []string {
+	themesMu.RLock()
+	defer themesMu.RUnlock()
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
 	}
+	slices.Sort(names)
+	return names
 }
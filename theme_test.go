@@ -0,0 +1,167 @@
+package console
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestThemeByName(t *testing.T) {
+	for _, name := range []string{"default", "Bright", "MONO", "colorblind", "solarized", "dracula", "nord"} {
+		theme, ok := ThemeByName(name)
+		AssertEqual(t, true, ok)
+		AssertEqual(t, strings.ToLower(name), strings.ToLower(theme.Name))
+	}
+
+	_, ok := ThemeByName("nope")
+	AssertEqual(t, false, ok)
+
+	RegisterTheme("Custom", func() Theme {
+		theme := NewMonoTheme()
+		theme.Name = "Custom"
+		return theme
+	})
+	t.Cleanup(func() {
+		themesMu.Lock()
+		delete(themes, "custom")
+		themesMu.Unlock()
+	})
+	theme, ok := ThemeByName("custom")
+	AssertEqual(t, true, ok)
+	AssertEqual(t, "Custom", theme.Name)
+	AssertEqual(t, "bright,colorblind,custom,default,dracula,mono,nord,solarized", strings.Join(ThemeNames(), ","))
+}
+
+func TestHandler_BoolStyles(t *testing.T) {
+	theme := Theme{
+		Name:           "test",
+		AttrValue:      ToANSICode(Blue),
+		AttrValueTrue:  ToANSICode(Green),
+		AttrValueFalse: ToANSICode(Red),
+	}
+	handlerTest{
+		opts:  HandlerOptions{Theme: theme, HeaderFormat: "%a"},
+		attrs: []slog.Attr{slog.Bool("ok", true), slog.Bool("done", false), slog.String("s", "true")},
+		want: "ok=" + styled("true", theme.AttrValueTrue) +
+			" done=" + styled("false", theme.AttrValueFalse) +
+			" s=" + styled("true", theme.AttrValue) + "\n",
+	}.run(t)
+
+	// without the bool styles, bools use AttrValue
+	theme.AttrValueTrue, theme.AttrValueFalse = "", ""
+	handlerTest{
+		opts:  HandlerOptions{Theme: theme, HeaderFormat: "%a"},
+		attrs: []slog.Attr{slog.Bool("ok", true)},
+		want:  "ok=" + styled("true", theme.AttrValue) + "\n",
+	}.run(t)
+}
+
+func TestHandler_SourceLevelStyles(t *testing.T) {
+	theme := Theme{
+		Name:        "test",
+		Source:      ToANSICode(Faint),
+		SourceError: ToANSICode(BrightRed, Italic),
+	}
+	pc, _, line, _ := runtime.Caller(0)
+	src := fmt.Sprintf("theme_test.go:%d", line)
+	for _, tt := range []struct {
+		lvl   slog.Level
+		style ANSIMod
+	}{
+		{slog.LevelDebug, theme.Source},
+		{slog.LevelInfo, theme.Source},
+		// without SourceWarn, warnings use Source
+		{slog.LevelWarn, theme.Source},
+		{slog.LevelError, theme.SourceError},
+		{slog.LevelError + 4, theme.SourceError},
+	} {
+		t.Run(tt.lvl.String(), handlerTest{
+			opts: HandlerOptions{Theme: theme, HeaderFormat: "%s", AddSource: true},
+			lvl:  tt.lvl,
+			pc:   pc,
+			want: styled(src, tt.style) + "\n",
+		}.run)
+	}
+}
+
+func TestHandler_LevelColors(t *testing.T) {
+	theme := Theme{
+		Name:      "test",
+		LevelInfo: ToANSICode(Cyan),
+	}.WithLevelColor(slog.LevelInfo+2, ToANSICode(Magenta))
+	tests := []handlerTest{
+		{
+			name: "override",
+			lvl:  slog.LevelInfo + 2,
+			want: styled("INF+2", ToANSICode(Magenta)) + "\n",
+		},
+		{
+			name: "band",
+			lvl:  slog.LevelInfo + 1,
+			want: styled("INF+1", theme.LevelInfo) + "\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts = HandlerOptions{Theme: theme, HeaderFormat: "%l"}
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestTheme_WithLevelColor(t *testing.T) {
+	base := NewDefaultTheme()
+	theme := base.WithLevelColor(slog.LevelInfo+2, ToANSICode(Magenta)).
+		WithLevelColor(slog.LevelDebug-4, ToANSICode(Faint))
+	replaced := theme.WithLevelColor(slog.LevelInfo+2, ToANSICode(Cyan))
+
+	style, ok := replaced.LevelColor(slog.LevelInfo + 2)
+	AssertEqual(t, true, ok)
+	AssertEqual(t, ToANSICode(Cyan), style)
+	style, _ = theme.LevelColor(slog.LevelInfo + 2)
+	AssertEqual(t, ToANSICode(Magenta), style)
+	_, ok = theme.LevelColor(slog.LevelInfo)
+	AssertEqual(t, false, ok)
+
+	// themes stay comparable
+	AssertEqual(t, NewDefaultTheme(), base)
+	AssertEqual(t, theme, theme)
+	AssertNotEqual(t, base, theme)
+}
+
+func TestTheme_With(t *testing.T) {
+	base := NewDefaultTheme()
+	base = base.WithLevelColor(slog.LevelInfo+2, ToANSICode(Magenta))
+
+	theme := base.With(Theme{AttrKey: ToANSICode(Blue)}.WithLevelColor(slog.LevelDebug-4, ToANSICode(Faint)))
+	AssertEqual(t, base.Name, theme.Name)
+	AssertEqual(t, ToANSICode(Blue), theme.AttrKey)
+	AssertEqual(t, base.Message, theme.Message)
+	style, _ := theme.LevelColor(slog.LevelInfo + 2)
+	AssertEqual(t, ToANSICode(Magenta), style)
+	style, _ = theme.LevelColor(slog.LevelDebug - 4)
+	AssertEqual(t, ToANSICode(Faint), style)
+	// the base theme is unchanged
+	AssertEqual(t, NewDefaultTheme().AttrKey, base.AttrKey)
+	_, ok := base.LevelColor(slog.LevelDebug - 4)
+	AssertEqual(t, false, ok)
+
+	AssertEqual(t, "Custom", base.With(Theme{Name: "Custom"}).Name)
+
+	// every style can be overridden
+	overrides := Theme{}
+	v := reflect.ValueOf(&overrides).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Field(i); f.Kind() == reflect.String && v.Type().Field(i).Name != "Name" {
+			f.SetString("x")
+		}
+	}
+	theme = base.With(overrides)
+	v = reflect.ValueOf(theme)
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Field(i); f.Kind() == reflect.String && v.Type().Field(i).Name != "Name" {
+			AssertEqual(t, "x", f.String())
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// PrintThemeSamples writes sample records to w in each of the themes, each under a
+// rule labeled with the theme's name, to preview the themes, e.g. in a demo command
+// which lets users choose one:
+//
+//	console.PrintThemeSamples(os.Stdout, console.NewDefaultTheme(), console.NewNordTheme())
+//
+// The samples show every level, a header, the logger name, attributes of several
+// kinds, an error, and a multiline value.  If no themes are given, the built-in and
+// registered themes are printed, in the order of ThemeNames.  Colors are always
+// printed.
+func PrintThemeSamples(w io.Writer, themes ...Theme) error {
+	if len(themes) == 0 {
+		for _, name := range ThemeNames() {
+			theme, _ := ThemeByName(name)
+			themes = append(themes, theme)
+		}
+	}
+
+	t := time.Date(2024, 5, 7, 15, 4, 5, 0, time.UTC)
+	samples := []struct {
+		level slog.Level
+		msg   string
+		attrs []slog.Attr
+	}{
+		{slog.LevelDebug, "cache lookup", []slog.Attr{slog.String("key", "user:42"), slog.Bool("hit", false)}},
+		{slog.LevelInfo, "request served", []slog.Attr{slog.String("method", "GET"), slog.Int("status", 200), slog.Duration("elapsed", 12*time.Millisecond), slog.Bool("cached", true)}},
+		{slog.LevelWarn, "slow query", []slog.Attr{slog.String("table", "orders"), slog.Duration("elapsed", 1500*time.Millisecond)}},
+		{slog.LevelError, "payment failed", []slog.Attr{slog.Any("err", errors.New("connection reset by peer")), slog.Int("attempt", 3)}},
+		{slog.LevelInfo, "config loaded", []slog.Attr{slog.String("config", "listen: :8080\nworkers: 4")}},
+	}
+
+	var errs []error
+	for _, theme := range themes {
+		h := NewHandler(w, &HandlerOptions{
+			Level:        slog.LevelDebug,
+			Theme:        theme,
+			TimeFormat:   time.Kitchen,
+			HeaderFormat: "%t %l %{%[logger]h >%} %m %a",
+			RuleWidth:    60,
+			IgnoreCI:     true,
+			ColorAlways:  true,
+		})
+		errs = append(errs, h.WriteRule(theme.Name))
+		logger := h.WithAttrs([]slog.Attr{slog.String("logger", "app")})
+		for _, s := range samples {
+			rec := slog.NewRecord(t, s.level, s.msg, 0)
+			rec.AddAttrs(s.attrs...)
+			errs = append(errs, logger.Handle(context.Background(), rec))
+		}
+	}
+	return errors.Join(errs...)
+}
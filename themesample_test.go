@@ -0,0 +1,38 @@
+package console
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPrintThemeSamples(t *testing.T) {
+	buf := bytes.Buffer{}
+	AssertNoError(t, PrintThemeSamples(&buf, NewDefaultTheme(), NewNordTheme()))
+	out := buf.String()
+	for _, s := range []string{"Default", "Nord", "DBG", "INF", "WRN", "ERR", "connection reset by peer", "=== config ===", "workers: 4"} {
+		AssertEqual(t, true, strings.Contains(out, s))
+	}
+	theme := NewNordTheme()
+	AssertEqual(t, true, strings.Contains(out, styled("connection reset by peer", theme.AttrValueError)))
+	AssertEqual(t, true, strings.Contains(out, styled("true", theme.AttrValueTrue)))
+
+	// all the themes by default
+	buf.Reset()
+	AssertNoError(t, PrintThemeSamples(&buf))
+	for _, name := range ThemeNames() {
+		theme, _ := ThemeByName(name)
+		AssertEqual(t, true, strings.Contains(buf.String(), " "+styled(theme.Name, theme.Message)+" "))
+	}
+
+	// even if the environment disables them
+	t.Setenv("NO_COLOR", "1")
+	t.Setenv("FORCE_COLOR", "0")
+	buf.Reset()
+	AssertNoError(t, PrintThemeSamples(&buf, theme))
+	AssertEqual(t, true, strings.Contains(buf.String(), styled("true", theme.AttrValueTrue)))
+
+	w := writerFunc(func([]byte) (int, error) { return 0, errors.New("nope") })
+	AssertError(t, PrintThemeSamples(w, NewMonoTheme()))
+}
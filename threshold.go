@@ -0,0 +1,66 @@
+package console
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// ThresholdRule styles the numeric values of an attribute which reach a threshold,
+// so, for example, server errors and slow requests stand out, even at the INFO level:
+//
+//	ThresholdRules: []console.ThresholdRule{
+//		{Key: "status", Min: slog.IntValue(500), Style: theme.LevelError},
+//		{Key: "status", Min: slog.IntValue(400), Style: theme.LevelWarn},
+//		{Key: "http.duration", Min: slog.DurationValue(time.Second), Style: theme.LevelWarn},
+//	}
+type ThresholdRule struct {
+	// Key is the key of the attribute, qualified by its groups, joined with dots,
+	// like in RemoveKeys.
+	Key string
+	// Min is the threshold.  The rule applies to values greater than or equal to
+	// it.  Ints, uints, floats, and durations are compared by their numeric value,
+	// durations in nanoseconds.
+	Min slog.Value
+	// Style replaces the Theme's AttrValue style.
+	Style ANSIMod
+}
+
+// thresholdStyle returns the style of the first of the HandlerOptions.ThresholdRules
+// which applies to the attr.
+func (e *encoder) thresholdStyle(group string, a slog.Attr) (ANSIMod, bool) {
+	if len(e.h.opts.ThresholdRules) == 0 || e.h.opts.NoColor {
+		return "", false
+	}
+	v, ok := numericValue(a.Value)
+	if !ok {
+		return "", false
+	}
+	for _, r := range e.h.opts.ThresholdRules {
+		key := r.Key
+		if e.h.opts.GroupSeparator != "." && group != "" {
+			key = strings.ReplaceAll(key, ".", e.h.opts.GroupSeparator)
+		}
+		if !qualifiedKeyEquals(key, group, e.h.opts.GroupSeparator, a.Key) {
+			continue
+		}
+		if min, ok := numericValue(r.Min); ok && v >= min {
+			return r.Style, true
+		}
+	}
+	return "", false
+}
+
+// numericValue returns the value of numbers and durations as a float64.
+func numericValue(v slog.Value) (float64, bool) {
+	switch v.Kind() {
+	case slog.KindInt64:
+		return float64(v.Int64()), true
+	case slog.KindUint64:
+		return float64(v.Uint64()), true
+	case slog.KindFloat64:
+		return v.Float64(), true
+	case slog.KindDuration:
+		return float64(v.Duration()), true
+	}
+	return 0, false
+}
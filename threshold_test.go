@@ -0,0 +1,63 @@
+package console
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestHandler_ThresholdRules(t *testing.T) {
+	theme := NewDefaultTheme()
+	rules := []ThresholdRule{
+		{Key: "status", Min: slog.IntValue(500), Style: theme.LevelError},
+		{Key: "status", Min: slog.IntValue(400), Style: theme.LevelWarn},
+		{Key: "http.elapsed", Min: slog.DurationValue(time.Second), Style: theme.LevelWarn},
+		{Key: "ratio", Min: slog.Float64Value(0.5), Style: theme.LevelWarn},
+	}
+	key := func(k string) string { return styled(k+"=", theme.AttrKey) }
+
+	tests := []handlerTest{
+		{
+			name:  "below",
+			attrs: []slog.Attr{slog.Int("status", 200)},
+			want:  key("status") + "200\n",
+		},
+		{
+			name:  "first rule",
+			attrs: []slog.Attr{slog.Int("status", 503)},
+			want:  key("status") + styled("503", theme.LevelError) + "\n",
+		},
+		{
+			name:  "second rule",
+			attrs: []slog.Attr{slog.Uint64("status", 404)},
+			want:  key("status") + styled("404", theme.LevelWarn) + "\n",
+		},
+		{
+			name:  "duration in group",
+			attrs: []slog.Attr{slog.Group("http", slog.Duration("elapsed", 2*time.Second)), slog.Duration("elapsed", 2*time.Second)},
+			want:  key("http.elapsed") + styled("2s", theme.LevelWarn) + " " + key("elapsed") + "2s\n",
+		},
+		{
+			name:  "float",
+			attrs: []slog.Attr{slog.Float64("ratio", 0.75)},
+			want:  key("ratio") + styled("0.75", theme.LevelWarn) + "\n",
+		},
+		{
+			name:  "not a number",
+			attrs: []slog.Attr{slog.String("status", "600")},
+			want:  key("status") + "600\n",
+		},
+		{
+			name:  "group separator",
+			opts:  HandlerOptions{GroupSeparator: "/"},
+			attrs: []slog.Attr{slog.Group("http", slog.Duration("elapsed", 2*time.Second))},
+			want:  key("http/elapsed") + styled("2s", theme.LevelWarn) + "\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.Theme = theme
+		test.opts.ThresholdRules = rules
+		test.opts.HeaderFormat = "%a"
+		t.Run(test.name, test.run)
+	}
+}
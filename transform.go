@@ -0,0 +1,71 @@
+package console
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// headerTransform is a set of transformations of header values, selected by the
+// modifiers of the %h verb.
+type headerTransform uint8
+
+const (
+	transformUpper headerTransform = 1 << iota
+	transformLower
+	transformBase
+)
+
+// headerTransforms maps the modifiers of the %h verb to the transformations.
+var headerTransforms = [256]headerTransform{
+	'U': transformUpper,
+	'u': transformLower,
+	'B': transformBase,
+}
+
+// String returns the modifiers of the transformations.
+func (t headerTransform) String() string {
+	var s string
+	if t&transformUpper != 0 {
+		s += "U"
+	}
+	if t&transformLower != 0 {
+		s += "u"
+	}
+	if t&transformBase != 0 {
+		s += "B"
+	}
+	return s
+}
+
+// transformFrom applies the transformations to the value written to buf at offset.
+func (e *encoder) transformFrom(buf *buffer, offset int, t headerTransform) {
+	if t&transformBase != 0 {
+		if i := bytes.LastIndexByte((*buf)[offset:], '/'); i >= 0 {
+			n := copy((*buf)[offset:], (*buf)[offset+i+1:])
+			*buf = (*buf)[:offset+n]
+		}
+	}
+	if t&(transformUpper|transformLower) == 0 {
+		return
+	}
+	b := (*buf)[offset:]
+	for i, c := range b {
+		if c >= utf8.RuneSelf {
+			// not ASCII, so the case mapping may change the length
+			e.scratch = append(e.scratch[:0], b...)
+			*buf = (*buf)[:offset]
+			if t&transformUpper != 0 {
+				*buf = append(*buf, bytes.ToUpper(e.scratch)...)
+			} else {
+				*buf = append(*buf, bytes.ToLower(e.scratch)...)
+			}
+			return
+		}
+		switch {
+		case t&transformUpper != 0 && 'a' <= c && c <= 'z':
+			b[i] = c - 'a' + 'A'
+		case t&transformLower != 0 && 'A' <= c && c <= 'Z':
+			b[i] = c - 'A' + 'a'
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package console
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestHeaderTransforms(t *testing.T) {
+	tests := []handlerTest{
+		{
+			name:  "upper",
+			opts:  HandlerOptions{HeaderFormat: "%[logger]Uh %m"},
+			attrs: []slog.Attr{slog.String("logger", "app.db")},
+			want:  "APP.DB msg\n",
+		},
+		{
+			name:  "lower",
+			opts:  HandlerOptions{HeaderFormat: "%[logger]uh %m"},
+			attrs: []slog.Attr{slog.String("logger", "App.DB")},
+			want:  "app.db msg\n",
+		},
+		{
+			name:  "non-ascii",
+			opts:  HandlerOptions{HeaderFormat: "%[logger]Uh %m"},
+			attrs: []slog.Attr{slog.String("logger", "größe")},
+			want:  "GRÖßE msg\n",
+		},
+		{
+			name:  "basename",
+			opts:  HandlerOptions{HeaderFormat: "%[file]Bh %m"},
+			attrs: []slog.Attr{slog.String("file", "internal/db/pool.go")},
+			want:  "pool.go msg\n",
+		},
+		{
+			name:  "basename without slash",
+			opts:  HandlerOptions{HeaderFormat: "%[file]Bh %m"},
+			attrs: []slog.Attr{slog.String("file", "pool.go")},
+			want:  "pool.go msg\n",
+		},
+		{
+			name:  "combined with width",
+			opts:  HandlerOptions{HeaderFormat: "%[file]BU-10h %m"},
+			attrs: []slog.Attr{slog.String("file", "internal/db/pool.go")},
+			want:  "   POOL.GO msg\n",
+		},
+		{
+			name:        "memoized",
+			opts:        HandlerOptions{HeaderFormat: "%[logger]Uh %m"},
+			handlerFunc: func(h slog.Handler) slog.Handler { return h.WithAttrs([]slog.Attr{slog.String("logger", "app")}) },
+			want:        "APP msg\n",
+		},
+		{
+			name: "invalid",
+			opts: HandlerOptions{HeaderFormat: "%[http]Ua %m"},
+			want: "%!U(INVALID_MODIFIER)a msg\n",
+		},
+	}
+	for _, test := range tests {
+		test.opts.NoColor = true
+		test.msg = "msg"
+		t.Run(test.name, test.run)
+	}
+}
@@ -0,0 +1,66 @@
+package console
+
+import "log/slog"
+
+// WithOptions returns a new Handler writing to the same writer as h, with options
+// modified by fn, e.g. to give a subsystem its own level or HeaderFormat:
+//
+//	db := h.WithOptions(func(o *console.HandlerOptions) {
+//		o.Level = slog.LevelDebug
+//		o.HeaderFormat = "%t %l [db] %m %a"
+//	})
+//
+// fn is called with a copy of the options h was created with, before NewHandler
+// applied the defaults, and with Level set to h's current level.  If fn changes the
+// HeaderFormat, but not the Format, the Format is cleared, so the HeaderFormat is
+// used.  The new handler keeps h's attrs, groups, logger name, caller skip, and
+// the level set with WithGroupLevel, and shares h's writer, including changes made
+// with SetOutput, and h's lock, so the lines of the two handlers are never
+// interleaved.  Since the writer is shared, so is the lock: a Mutex set by fn is
+// ignored.  It also shares the levels set with SetLevelFor and SetPackageLevel.
+// Everything else is the new handler's own, like its level, which isn't changed by
+// h.SetLevel, its sampling counters and Stats, and the attrs of its EnvAttrs, which
+// are read again.  Slices and maps in the options are shared with h, so fn should
+// replace them, rather than modify them.
+func (h *Handler) WithOptions(fn func(*HandlerOptions)) *Handler {
+	opts := *h.userOpts
+	opts.Level = *h.level.Load()
+	fn(&opts)
+	if opts.HeaderFormat != h.userOpts.HeaderFormat && opts.Format == h.userOpts.Format {
+		opts.Format = nil
+	}
+	opts.Mutex = h.mu
+
+	h.mu.Lock()
+	out := h.out.w
+	h.mu.Unlock()
+	h2 := NewHandler(out, &opts)
+	h2.out = h.out
+	h2.levels = h.levels
+	h2.callerSkip = h.callerSkip
+
+	// replay WithGroup and WithAttrs
+	var groups []string
+	var next slog.Handler = h2
+	h.attrs.each(func(ha *handlerAttrs) {
+		if ha.env {
+			// NewHandler added the EnvAttrs of the new options
+			return
+		}
+		for _, g := range ha.groups[len(groups):] {
+			next = next.WithGroup(g)
+		}
+		groups = ha.groups
+		next = next.WithAttrs(ha.attrs)
+	})
+	for _, g := range h.groups[len(groups):] {
+		next = next.WithGroup(g)
+	}
+	h2 = next.(*Handler)
+	h2.groupLevel = h.groupLevel
+
+	if h.name != "" {
+		h2 = h2.WithName(h.name)
+	}
+	return h2
+}
@@ -0,0 +1,75 @@
+package console
+
+import (
+	"bytes"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+func TestHandler_WithOptions(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %N %m %a"})
+	h.SetLevel(slog.LevelWarn)
+	parent := h.WithName("app").WithAttrs([]slog.Attr{slog.Int("a", 1)}).WithGroup("g").WithGroup("h").
+		WithAttrs([]slog.Attr{slog.Int("b", 2)}).WithGroup("i").(*Handler)
+
+	child := parent.WithOptions(func(o *HandlerOptions) {
+		AssertEqual(t, slog.LevelWarn, o.Level.Level())
+		o.Level = slog.LevelDebug
+		o.HeaderFormat = "%L [%N] %m %a"
+	})
+	AssertEqual(t, true, child.mu == h.mu)
+
+	slog.New(parent).Info("hidden")
+	slog.New(parent).Warn("parent", "c", 3)
+	slog.New(child).Debug("child", "c", 3)
+	AssertEqual(t, "WRN app parent a=1 g.h.b=2 g.h.i.c=3\nDEBUG [app] child a=1 g.h.b=2 g.h.i.c=3\n", buf.String())
+
+	// the levels are independent
+	buf.Reset()
+	h.SetLevel(slog.LevelError)
+	slog.New(child).Info("still")
+	AssertEqual(t, "INFO [app] still a=1 g.h.b=2\n", buf.String())
+}
+
+func TestHandler_WithOptions_Defaults(t *testing.T) {
+	theme := NewDefaultTheme()
+
+	// the defaults are applied again to the parent's options, as given
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{HeaderFormat: "%l %m", Theme: theme, ColorLines: true})
+	slog.New(h.WithOptions(func(o *HandlerOptions) {})).Error("b")
+	AssertEqual(t, styled("ERR b", theme.LevelError)+"\n", buf.String())
+
+	buf.Reset()
+	h = NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%m %a"})
+	slog.New(h.WithOptions(func(o *HandlerOptions) { o.Pretty = true })).Info("msg", "k", 1)
+	AssertEqual(t, "msg\n  k: 1\n", buf.String())
+
+	// a HeaderFormat set by fn replaces the parent's Format
+	buf.Reset()
+	h = NewHandler(&buf, &HandlerOptions{NoColor: true, Format: NewFormat().Level().Message()})
+	slog.New(h.WithOptions(func(o *HandlerOptions) { o.HeaderFormat = "[%l] %m" })).Info("msg")
+	AssertEqual(t, "[INF] msg\n", buf.String())
+}
+
+func TestHandler_WithOptions_Mutex(t *testing.T) {
+	h := NewHandler(&bytes.Buffer{}, nil)
+
+	// the writer is shared, so the lock is too
+	child := h.WithOptions(func(o *HandlerOptions) { o.Mutex = &sync.Mutex{} })
+	AssertEqual(t, true, child.mu == h.mu)
+	AssertEqual(t, true, child.out == h.out)
+}
+
+func TestHandler_WithOptions_GroupLevel(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %m %a"})
+	db := h.WithGroupLevel("db", slog.LevelWarn).WithAttrs([]slog.Attr{slog.Int("conn", 1)}).(*Handler)
+
+	child := db.WithOptions(func(o *HandlerOptions) {})
+	slog.New(child).Info("hidden")
+	slog.New(child).Warn("slow", "ms", 5)
+	AssertEqual(t, "WRN slow db.conn=1 db.ms=5\n", buf.String())
+}
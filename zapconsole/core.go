@@ -0,0 +1,127 @@
+// Package zapconsole adapts a console handler to zap, so services still using zap
+// print their logs like their slog-based components:
+//
+//	h := console.NewHandler(os.Stderr, nil)
+//	logger := zap.New(zapconsole.NewCore(h), zap.AddCaller())
+//
+// It's a separate module, so the console package doesn't depend on zap.
+package zapconsole
+
+import (
+	"context"
+	"log/slog"
+
+	console "github.com/ansel1/console-slog"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewCore returns a zapcore.Core which converts zap's entries to records, and
+// passes them to h.  h is usually a *console.Handler, but can be any slog.Handler.
+//
+// Fields are converted to attributes, in order: zap.Object fields, and the fields
+// after a zap.Namespace, are groups, zap.Error fields are errors, and arrays are
+// slices.  zap's levels are mapped to slog's by multiplying them by 4, so
+// zap.DebugLevel is slog.LevelDebug, zap.ErrorLevel is slog.LevelError, and
+// zap.FatalLevel is slog.LevelError+12, printed as "ERR+12".  The logger name of
+// the entry, set with zap.Logger.Named, is the logger name of the record, like
+// console.Named, if h is a *console.Handler, or a "logger" attribute otherwise.
+// The caller, if added with zap.AddCaller, is the record's source, and the stack,
+// if added with zap.AddStacktrace, is a "stacktrace" attribute.
+//
+// The levels are checked with h.Enabled, so h's level, and levels set with
+// console.Handler.SetLevelFor, apply to zap's entries too.
+func NewCore(h slog.Handler) zapcore.Core {
+	return &core{h: h}
+}
+
+type core struct {
+	h slog.Handler
+}
+
+// Level maps a zap level to a slog level.
+func Level(l zapcore.Level) slog.Level {
+	return slog.Level(l) * 4
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *core) Enabled(l zapcore.Level) bool {
+	return c.h.Enabled(context.Background(), Level(l))
+}
+
+// With implements zapcore.Core.
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	if len(fields) == 0 {
+		return c
+	}
+	enc := encodeFields(fields)
+	h := c.h
+	if len(enc.attrs) > 0 {
+		h = h.WithAttrs(enc.attrs)
+	}
+	// the fields added later go in the open namespaces
+	for _, ns := range enc.namespaces {
+		h = h.WithGroup(ns.key)
+		if len(ns.attrs) > 0 {
+			h = h.WithAttrs(ns.attrs)
+		}
+	}
+	return &core{h: h}
+}
+
+// Check implements zapcore.Core.
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	h := c.h
+	var attrs []slog.Attr
+	if ent.LoggerName != "" {
+		if ch, ok := h.(*console.Handler); ok {
+			h = ch.WithName(ent.LoggerName)
+		} else {
+			attrs = append(attrs, slog.String("logger", ent.LoggerName))
+		}
+	}
+
+	var pc uintptr
+	if ent.Caller.Defined {
+		pc = ent.Caller.PC
+	}
+	rec := slog.NewRecord(ent.Time, Level(ent.Level), ent.Message, pc)
+	rec.AddAttrs(attrs...)
+	rec.AddAttrs(encodeFields(fields).result()...)
+	if ent.Stack != "" {
+		rec.AddAttrs(slog.String("stacktrace", ent.Stack))
+	}
+	return h.Handle(context.Background(), rec)
+}
+
+// Sync implements zapcore.Core.  It flushes the handler, if it has a Flush()
+// error method, like *console.Handler.
+func (c *core) Sync() error {
+	if f, ok := c.h.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// encodeFields converts the fields to attrs.
+func encodeFields(fields []zapcore.Field) *attrEncoder {
+	enc := &attrEncoder{}
+	for _, f := range fields {
+		if f.Type == zapcore.ErrorType {
+			// keep errors as errors, so they're printed in the error style
+			if err, ok := f.Interface.(error); ok {
+				enc.add(slog.Any(f.Key, err))
+				continue
+			}
+		}
+		f.AddTo(enc)
+	}
+	return enc
+}
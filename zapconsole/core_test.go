@@ -0,0 +1,139 @@
+package zapconsole
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	console "github.com/ansel1/console-slog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func assertEqual(t *testing.T, want, got string) {
+	t.Helper()
+	if want != got {
+		t.Errorf("\nwant: %q\n got: %q", want, got)
+	}
+}
+
+type user struct {
+	name string
+	age  int
+}
+
+func (u user) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", u.name)
+	enc.AddInt("age", u.age)
+	return nil
+}
+
+func TestCore(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := console.NewHandler(&buf, &console.HandlerOptions{
+		Level:        slog.LevelDebug,
+		NoColor:      true,
+		HeaderFormat: "%l %[logger]h > %m %a",
+	})
+	logger := zap.New(NewCore(h))
+
+	tests := []struct {
+		name string
+		log  func()
+		want string
+	}{
+		{
+			name: "fields",
+			log: func() {
+				logger.Info("started", zap.String("service", "api"), zap.Int("port", 8080), zap.Bool("ok", true), zap.Float64("ratio", 0.5))
+			},
+			want: "INF > started service=api port=8080 ok=true ratio=0.5\n",
+		},
+		{
+			name: "error",
+			log:  func() { logger.Error("failed", zap.Error(errors.New("boom"))) },
+			want: "ERR > failed error=boom\n",
+		},
+		{
+			name: "object and array",
+			log: func() {
+				logger.Debug("user", zap.Object("user", user{"ann", 30}), zap.Strings("tags", []string{"a", "b"}))
+			},
+			want: "DBG > user user.name=ann user.age=30 tags=[a b]\n",
+		},
+		{
+			name: "namespace",
+			log:  func() { logger.Warn("req", zap.String("id", "1"), zap.Namespace("http"), zap.Int("status", 500)) },
+			want: "WRN > req id=1 http.status=500\n",
+		},
+		{
+			name: "with",
+			log: func() {
+				logger.With(zap.String("id", "1"), zap.Namespace("http")).Info("req", zap.Int("status", 200))
+			},
+			want: "INF > req id=1 http.status=200\n",
+		},
+		{
+			name: "named",
+			log:  func() { logger.Named("store").Named("cache").Info("miss") },
+			want: "INF store.cache > miss\n",
+		},
+		{
+			name: "levels",
+			log:  func() { logger.DPanic("oops") },
+			want: "ERR+4 > oops\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			tt.log()
+			assertEqual(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestCore_Enabled(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := console.NewHandler(&buf, &console.HandlerOptions{Level: slog.LevelWarn, NoColor: true, HeaderFormat: "%l %m"})
+	logger := zap.New(NewCore(h))
+
+	logger.Info("hidden")
+	logger.Warn("shown")
+	assertEqual(t, "WRN shown\n", buf.String())
+
+	h.SetLevel(slog.LevelDebug)
+	buf.Reset()
+	logger.Debug("now shown")
+	assertEqual(t, "DBG now shown\n", buf.String())
+}
+
+func TestCore_OtherHandler(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	zap.New(NewCore(h)).Named("store").Info("hi", zap.Int("n", 1))
+	assertEqual(t, "level=INFO msg=hi logger=store n=1\n", buf.String())
+}
+
+func TestLevel(t *testing.T) {
+	tests := map[zapcore.Level]slog.Level{
+		zapcore.DebugLevel: slog.LevelDebug,
+		zapcore.InfoLevel:  slog.LevelInfo,
+		zapcore.WarnLevel:  slog.LevelWarn,
+		zapcore.ErrorLevel: slog.LevelError,
+		zapcore.FatalLevel: slog.LevelError + 12,
+	}
+	for zl, want := range tests {
+		if got := Level(zl); got != want {
+			t.Errorf("Level(%v): want %v, got %v", zl, want, got)
+		}
+	}
+}
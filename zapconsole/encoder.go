@@ -0,0 +1,145 @@
+package zapconsole
+
+import (
+	"encoding/base64"
+	"log/slog"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// attrEncoder is a zapcore.ObjectEncoder which collects the fields added to it as
+// attrs, in order.
+type attrEncoder struct {
+	attrs []slog.Attr
+	// namespaces are the namespaces opened with OpenNamespace, which hold the
+	// attrs added after them
+	namespaces []namespace
+}
+
+type namespace struct {
+	key   string
+	attrs []slog.Attr
+}
+
+var _ zapcore.ObjectEncoder = (*attrEncoder)(nil)
+
+// add adds the attr to the innermost open namespace, if any.
+func (e *attrEncoder) add(a slog.Attr) {
+	if n := len(e.namespaces); n > 0 {
+		e.namespaces[n-1].attrs = append(e.namespaces[n-1].attrs, a)
+		return
+	}
+	e.attrs = append(e.attrs, a)
+}
+
+// result returns the attrs, with the namespaces closed, as nested groups.
+func (e *attrEncoder) result() []slog.Attr {
+	for i := len(e.namespaces) - 1; i >= 0; i-- {
+		ns := e.namespaces[i]
+		g := slog.Attr{Key: ns.key, Value: slog.GroupValue(ns.attrs...)}
+		if i > 0 {
+			e.namespaces[i-1].attrs = append(e.namespaces[i-1].attrs, g)
+		} else {
+			e.attrs = append(e.attrs, g)
+		}
+	}
+	e.namespaces = nil
+	return e.attrs
+}
+
+func (e *attrEncoder) AddArray(key string, v zapcore.ArrayMarshaler) error {
+	arr := &sliceEncoder{}
+	err := v.MarshalLogArray(arr)
+	e.add(slog.Any(key, arr.elems))
+	return err
+}
+
+func (e *attrEncoder) AddObject(key string, v zapcore.ObjectMarshaler) error {
+	obj := &attrEncoder{}
+	err := v.MarshalLogObject(obj)
+	e.add(slog.Attr{Key: key, Value: slog.GroupValue(obj.result()...)})
+	return err
+}
+
+func (e *attrEncoder) AddBinary(key string, v []byte) {
+	e.add(slog.String(key, base64.StdEncoding.EncodeToString(v)))
+}
+
+func (e *attrEncoder) AddByteString(key string, v []byte) { e.add(slog.String(key, string(v))) }
+func (e *attrEncoder) AddBool(key string, v bool)         { e.add(slog.Bool(key, v)) }
+func (e *attrEncoder) AddComplex128(key string, v complex128) {
+	e.add(slog.Any(key, v))
+}
+func (e *attrEncoder) AddComplex64(key string, v complex64)    { e.add(slog.Any(key, v)) }
+func (e *attrEncoder) AddDuration(key string, v time.Duration) { e.add(slog.Duration(key, v)) }
+func (e *attrEncoder) AddFloat64(key string, v float64)        { e.add(slog.Float64(key, v)) }
+func (e *attrEncoder) AddFloat32(key string, v float32)        { e.add(slog.Float64(key, float64(v))) }
+func (e *attrEncoder) AddInt(key string, v int)                { e.add(slog.Int(key, v)) }
+func (e *attrEncoder) AddInt64(key string, v int64)            { e.add(slog.Int64(key, v)) }
+func (e *attrEncoder) AddInt32(key string, v int32)            { e.add(slog.Int64(key, int64(v))) }
+func (e *attrEncoder) AddInt16(key string, v int16)            { e.add(slog.Int64(key, int64(v))) }
+func (e *attrEncoder) AddInt8(key string, v int8)              { e.add(slog.Int64(key, int64(v))) }
+func (e *attrEncoder) AddString(key, v string)                 { e.add(slog.String(key, v)) }
+func (e *attrEncoder) AddTime(key string, v time.Time)         { e.add(slog.Time(key, v)) }
+func (e *attrEncoder) AddUint(key string, v uint)              { e.add(slog.Uint64(key, uint64(v))) }
+func (e *attrEncoder) AddUint64(key string, v uint64)          { e.add(slog.Uint64(key, v)) }
+func (e *attrEncoder) AddUint32(key string, v uint32)          { e.add(slog.Uint64(key, uint64(v))) }
+func (e *attrEncoder) AddUint16(key string, v uint16)          { e.add(slog.Uint64(key, uint64(v))) }
+func (e *attrEncoder) AddUint8(key string, v uint8)            { e.add(slog.Uint64(key, uint64(v))) }
+func (e *attrEncoder) AddUintptr(key string, v uintptr)        { e.add(slog.Uint64(key, uint64(v))) }
+func (e *attrEncoder) AddReflected(key string, v interface{}) error {
+	e.add(slog.Any(key, v))
+	return nil
+}
+func (e *attrEncoder) OpenNamespace(key string) {
+	e.namespaces = append(e.namespaces, namespace{key: key})
+}
+
+// sliceEncoder is a zapcore.ArrayEncoder which collects the elements of an array.
+// Objects in arrays are maps.
+type sliceEncoder struct {
+	elems []any
+}
+
+var _ zapcore.ArrayEncoder = (*sliceEncoder)(nil)
+
+func (s *sliceEncoder) AppendArray(v zapcore.ArrayMarshaler) error {
+	arr := &sliceEncoder{}
+	err := v.MarshalLogArray(arr)
+	s.elems = append(s.elems, arr.elems)
+	return err
+}
+
+func (s *sliceEncoder) AppendObject(v zapcore.ObjectMarshaler) error {
+	m := zapcore.NewMapObjectEncoder()
+	err := v.MarshalLogObject(m)
+	s.elems = append(s.elems, m.Fields)
+	return err
+}
+
+func (s *sliceEncoder) AppendReflected(v interface{}) error {
+	s.elems = append(s.elems, v)
+	return nil
+}
+
+func (s *sliceEncoder) AppendBool(v bool)              { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendByteString(v []byte)      { s.elems = append(s.elems, string(v)) }
+func (s *sliceEncoder) AppendComplex128(v complex128)  { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendComplex64(v complex64)    { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendDuration(v time.Duration) { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendFloat64(v float64)        { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendFloat32(v float32)        { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendInt(v int)                { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendInt64(v int64)            { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendInt32(v int32)            { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendInt16(v int16)            { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendInt8(v int8)              { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendString(v string)          { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendTime(v time.Time)         { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendUint(v uint)              { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendUint64(v uint64)          { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendUint32(v uint32)          { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendUint16(v uint16)          { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendUint8(v uint8)            { s.elems = append(s.elems, v) }
+func (s *sliceEncoder) AppendUintptr(v uintptr)        { s.elems = append(s.elems, v) }
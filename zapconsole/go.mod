@@ -0,0 +1,10 @@
+module github.com/ansel1/console-slog/zapconsole
+
+go 1.21
+
+require (
+	github.com/ansel1/console-slog v0.7.0
+	go.uber.org/zap v1.27.0
+)
+
+require go.uber.org/multierr v1.10.0 // indirect
@@ -0,0 +1,192 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+)
+
+// NewZerologWriter returns a writer which parses the JSON records written by
+// zerolog, and re-renders them with h, so codebases migrating from zerolog to slog
+// print all their logs with the same themes and HeaderFormat:
+//
+//	h := console.NewHandler(os.Stderr, nil)
+//	zlog := zerolog.New(console.NewZerologWriter(h))
+//	slogger := slog.New(h)
+//
+// zerolog's default field names are recognized: "level", like "info" or "warn",
+// "time", either formatted as RFC 3339, or as a Unix time in seconds, milliseconds,
+// microseconds, or nanoseconds, "message", and "error", which is printed as an error.
+// The other fields are printed as attributes, in order, with objects as groups.
+// Records without a time are printed with the current time.  Lines which aren't
+// JSON objects are printed as the messages of Info records.
+//
+// Each line written is a record.  Records split across writes are printed once
+// they're complete.  Call the writer's Flush method to print the last record if it
+// wasn't terminated by a newline.  It is safe for concurrent use.
+func NewZerologWriter(h slog.Handler) *LineWriter {
+	return &LineWriter{handle: func(line []byte) error {
+		if len(bytes.TrimSpace(line)) == 0 {
+			return nil
+		}
+		rec, ok := parseZerolog(line)
+		if !ok {
+			rec = slog.NewRecord(time.Now(), slog.LevelInfo, string(line), 0)
+		}
+		ctx := context.Background()
+		if !h.Enabled(ctx, rec.Level) {
+			return nil
+		}
+		return h.Handle(ctx, rec)
+	}}
+}
+
+// zerologLevels are the levels of zerolog's level names.
+var zerologLevels = map[string]slog.Level{
+	"trace": slog.LevelDebug - 4,
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+	"fatal": slog.LevelError + 4,
+	"panic": slog.LevelError + 8,
+}
+
+// parseZerolog parses a zerolog JSON record.  It reports false if line isn't a
+// JSON object.
+func parseZerolog(line []byte) (slog.Record, bool) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	attrs, err := decodeJSONObject(dec)
+	if err != nil {
+		return slog.Record{}, false
+	}
+
+	level := slog.LevelInfo
+	var t time.Time
+	var msg string
+	rest := attrs[:0]
+	for _, a := range attrs {
+		switch {
+		case a.Key == "level" && a.Value.Kind() == slog.KindString:
+			if l, ok := zerologLevels[strings.ToLower(a.Value.String())]; ok {
+				level = l
+			}
+		case a.Key == "time" && t.IsZero():
+			t = zerologTime(a.Value)
+			if t.IsZero() {
+				rest = append(rest, a)
+			}
+		case a.Key == "message" && a.Value.Kind() == slog.KindString:
+			msg = a.Value.String()
+		case a.Key == "error" && a.Value.Kind() == slog.KindString:
+			rest = append(rest, slog.Any(a.Key, errors.New(a.Value.String())))
+		default:
+			rest = append(rest, a)
+		}
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+	rec := slog.NewRecord(t, level, msg, 0)
+	rec.AddAttrs(rest...)
+	return rec, true
+}
+
+// zerologTime returns the time of a zerolog "time" field, or the zero time if the
+// value isn't a time.
+func zerologTime(v slog.Value) time.Time {
+	switch v.Kind() {
+	case slog.KindString:
+		if t, err := time.Parse(time.RFC3339Nano, v.String()); err == nil {
+			return t
+		}
+	case slog.KindInt64:
+		// guess the unit of Unix times from their magnitude
+		n := v.Int64()
+		switch {
+		case n < 1e11:
+			return time.Unix(n, 0)
+		case n < 1e14:
+			return time.UnixMilli(n)
+		case n < 1e17:
+			return time.UnixMicro(n)
+		default:
+			return time.Unix(0, n)
+		}
+	case slog.KindFloat64:
+		sec, frac := math.Modf(v.Float64())
+		return time.Unix(int64(sec), int64(frac*1e9))
+	}
+	return time.Time{}
+}
+
+// decodeJSONObject decodes the next JSON object from dec into attrs, in order.
+// Nested objects are groups.
+func decodeJSONObject(dec *json.Decoder) ([]slog.Attr, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok != json.Delim('{') {
+		return nil, errors.New("not an object")
+	}
+	var attrs []slog.Attr
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		v, err := decodeJSONValue(dec)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, slog.Attr{Key: key, Value: v})
+	}
+	// the closing brace
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return attrs, nil
+}
+
+// decodeJSONValue decodes the next JSON value from dec.  Numbers are ints if they
+// can be, and floats otherwise, objects are groups, and arrays are []any.
+func decodeJSONValue(dec *json.Decoder) (slog.Value, error) {
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return slog.Value{}, err
+	}
+	if len(raw) > 0 && raw[0] == '{' {
+		inner := json.NewDecoder(bytes.NewReader(raw))
+		inner.UseNumber()
+		attrs, err := decodeJSONObject(inner)
+		if err != nil {
+			return slog.Value{}, err
+		}
+		return slog.GroupValue(attrs...), nil
+	}
+	var v any
+	inner := json.NewDecoder(bytes.NewReader(raw))
+	inner.UseNumber()
+	if err := inner.Decode(&v); err != nil {
+		return slog.Value{}, err
+	}
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return slog.Int64Value(n), nil
+		}
+		f, _ := v.Float64()
+		return slog.Float64Value(f), nil
+	case nil:
+		return slog.AnyValue(nil), nil
+	}
+	return slog.AnyValue(v), nil
+}
@@ -0,0 +1,89 @@
+package console
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestZerologWriter(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{
+		Level:        slog.LevelDebug,
+		NoColor:      true,
+		TimeFormat:   time.TimeOnly,
+		HeaderFormat: "%t %l %m %a",
+	})
+	w := NewZerologWriter(h)
+	// Unix times are printed in the local time zone
+	local := time.Unix(1704207845, 0).Format(time.TimeOnly)
+
+	tests := []struct {
+		name, in, want string
+	}{
+		{
+			name: "fields",
+			in:   `{"level":"info","service":"api","port":8080,"ratio":0.5,"ok":true,"time":"2024-01-02T15:04:05Z","message":"started"}` + "\n",
+			want: "15:04:05 INF started service=api port=8080 ratio=0.5 ok=true\n",
+		},
+		{
+			name: "error and group",
+			in:   `{"level":"error","error":"connection reset","req":{"method":"GET","tags":["a","b"]},"time":"2024-01-02T15:04:05Z","message":"failed"}` + "\n",
+			want: "15:04:05 ERR failed error=connection reset req.method=GET req.tags=[a b]\n",
+		},
+		{
+			name: "unix time",
+			in:   `{"level":"warn","time":1704207845,"message":"slow"}` + "\n" + `{"level":"debug","time":1704207845123,"message":"millis"}` + "\n",
+			want: local + " WRN slow\n" + local + " DBG millis\n",
+		},
+		{
+			name: "filtered level",
+			in:   `{"level":"trace","time":1704207845,"message":"hidden"}` + "\n",
+			want: "",
+		},
+		{
+			name: "fatal",
+			in:   `{"level":"fatal","time":1704207845,"message":"bye"}` + "\n",
+			want: local + " ERR+4 bye\n",
+		},
+		{
+			name: "blank lines",
+			in:   "\n  \n",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			n, err := io.WriteString(w, tt.in)
+			AssertNoError(t, err)
+			AssertEqual(t, len(tt.in), n)
+			AssertEqual(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestZerologWriter_Lines(t *testing.T) {
+	buf := bytes.Buffer{}
+	w := NewZerologWriter(NewHandler(&buf, &HandlerOptions{NoColor: true, HeaderFormat: "%l %m"}))
+
+	// records split across writes are printed once complete
+	io.WriteString(w, `{"level":"info","mess`)
+	AssertEqual(t, "", buf.String())
+	io.WriteString(w, `age":"split"}`+"\n"+`{"level":"warn","message":"second"}`+"\n")
+	AssertEqual(t, "INF split\nWRN second\n", buf.String())
+
+	// other lines are printed as messages
+	buf.Reset()
+	io.WriteString(w, "plain text\n[1,2]\n")
+	AssertEqual(t, "INF plain text\nINF [1,2]\n", buf.String())
+
+	// the last line is printed by Flush
+	buf.Reset()
+	io.WriteString(w, `{"level":"error","message":"no newline"}`)
+	AssertEqual(t, "", buf.String())
+	AssertNoError(t, w.Flush())
+	AssertEqual(t, "ERR no newline\n", buf.String())
+}